@@ -16,7 +16,7 @@ func Main() {
 
 	root := &cobra.Command{
 		Use:          "hlcut <input>",
-		Short:        "Cut highlight clips from a local MP4",
+		Short:        "Cut highlight clips from a local video or audio file",
 		Args:         cobra.ExactArgs(1),
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -32,6 +32,37 @@ func Main() {
 	root.Flags().String("out", "out", "Output directory")
 	root.Flags().Int("clips", 12, "Max clips to return")
 	root.Flags().Bool("burn-subtitles", false, "Burn karaoke subtitles into clips and write ASS files")
+	root.Flags().Bool("refresh", false, "Re-run transcription even if a cached transcript exists")
+	root.Flags().Bool("no-cache", false, "Do not read or write the transcript cache")
+	addSubtitleFormatsFlag(root)
+	addSubtitleStyleFlags(root)
+	addFrameFlags(root)
+	addTightenFlags(root)
+	addAudioFlags(root)
+	addAudiogramFlags(root)
+	addAudioTrackFlag(root)
+	addLanguageFlags(root)
+	addWhisperModelFlag(root)
+	addTranscribeChunkFlags(root)
+	addDiarizeFlag(root)
+	addTranscriptImportFlag(root)
+	addOutputFormatFlag(root)
+	addFastCutFlag(root)
+	addClipDurationFlags(root)
+	addProfileFlag(root)
+	addJobsFlag(root)
+	addRankerFlag(root)
+	addChunkFlags(root)
+
+	root.AddCommand(
+		newResumeCmd(),
+		newTranscribeCmd(),
+		newCandidatesCmd(),
+		newSelectCmd(),
+		newRenderCmd(),
+		newRerenderCmd(),
+		newModelsCmd(),
+	)
 
 	if err := root.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
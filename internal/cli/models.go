@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/pipeline"
+	"github.com/spf13/cobra"
+)
+
+func newModelsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "models",
+		Short: "List and verify whisper models",
+	}
+	cmd.AddCommand(newModelsListCmd(), newModelsVerifyCmd())
+	return cmd
+}
+
+func newModelsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "list",
+		Short:        "List the known whisper models and whether they are installed",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			for _, c := range pipeline.ListModels(modelsConfig(cmd)) {
+				pin := "unpinned"
+				if c.Want != "" {
+					pin = "pinned"
+				}
+				where := c.Path
+				if c.Status == pipeline.ModelMissing {
+					where = c.URL
+				}
+				if _, err := fmt.Fprintf(out, "%-20s %-9s %-8s %s\n", c.Name, c.Status, pin, where); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newModelsVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "verify [name|path...]",
+		Short:        "Check installed whisper models against the registry checksums",
+		Long:         "Check whisper models against the registry checksums. Without arguments every installed registry model is checked.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checks, err := pipeline.VerifyModels(modelsConfig(cmd), args)
+			if err != nil {
+				return err
+			}
+			if len(checks) == 0 {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), "no whisper models installed (run make setup)")
+				return err
+			}
+			return reportModelChecks(cmd.OutOrStdout(), checks)
+		},
+	}
+}
+
+// modelsConfig is the configuration the models commands resolve model
+// paths against.
+func modelsConfig(cmd *cobra.Command) pipeline.Config {
+	return baseConfig(newRunLogger(cmd.ErrOrStderr(), time.Now()))
+}
+
+// reportModelChecks prints one line per model and fails when a model is
+// missing or does not match its pinned checksum.
+func reportModelChecks(w io.Writer, checks []pipeline.ModelCheck) error {
+	failed := 0
+	for _, c := range checks {
+		var line string
+		switch c.Status {
+		case pipeline.ModelOK:
+			line = fmt.Sprintf("%s: ok (%s)", c.Name, c.Path)
+		case pipeline.ModelUnpinned:
+			line = fmt.Sprintf("%s: not pinned in the registry, sha256 %s (%s)", c.Name, c.SHA256, c.Path)
+		case pipeline.ModelMismatch:
+			failed++
+			line = fmt.Sprintf("%s: checksum mismatch, got %s, want %s (%s)", c.Name, c.SHA256, c.Want, c.Path)
+		default:
+			failed++
+			line = fmt.Sprintf("%s: missing %s", c.Name, c.Path)
+			if c.URL != "" {
+				line += fmt.Sprintf(" (download %s)", c.URL)
+			}
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d whisper models failed verification", failed, len(checks))
+	}
+	return nil
+}
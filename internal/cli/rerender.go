@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/forPelevin/hlcut/internal/pipeline"
+	"github.com/spf13/cobra"
+)
+
+func newRerenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "rerender <run-dir>",
+		Short:        "Re-render clips whose timing or subtitles were edited in manifest.json",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return stage(cmd, func(ctx context.Context, logf func(string, ...any)) error {
+				absRunDir, err := filepath.Abs(args[0])
+				if err != nil {
+					return err
+				}
+				cfg := baseConfig(logf)
+				if err := applyJobsFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				logf("output: %s", absRunDir)
+				res, err := pipeline.Rerender(ctx, cfg, absRunDir)
+				if err != nil {
+					return err
+				}
+				if len(res.Rerendered) > 0 {
+					logf("rerendered clips: %s", strings.Join(res.Rerendered, ", "))
+				}
+				return nil
+			})
+		},
+	}
+	addJobsFlag(cmd)
+	return cmd
+}
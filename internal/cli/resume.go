@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/pipeline"
+	"github.com/spf13/cobra"
+)
+
+func newResumeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "resume <run-dir>",
+		Short:        "Continue an interrupted run from its first incomplete stage",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return resume(cmd, args[0])
+		},
+	}
+	addJobsFlag(cmd)
+	return cmd
+}
+
+func resume(cmd *cobra.Command, runDir string) error {
+	started := time.Now()
+	logf := newRunLogger(cmd.ErrOrStderr(), started)
+
+	absRunDir, err := filepath.Abs(runDir)
+	if err != nil {
+		return err
+	}
+	st, err := pipeline.LoadRunState(absRunDir)
+	if err != nil {
+		return err
+	}
+
+	cfg := baseConfig(logf)
+	st.Apply(&cfg)
+	// A checkpointed selection is not sent to the LLM again.
+	if !pipeline.SelectionCheckpointed(absRunDir) {
+		if err := requireAPIKey(cfg); err != nil {
+			return err
+		}
+	}
+	cfg.InputPath = st.Input
+	cfg.ClipsN = st.ClipsN
+	// The stored clip count is already resolved (auto cap applied), so it must
+	// not be recomputed from the input duration.
+	cfg.ClipsNSet = true
+	cfg.RunDir = absRunDir
+	if err := applyJobsFlag(cmd, &cfg); err != nil {
+		return err
+	}
+
+	logf("resuming run")
+	logf("input: %s", st.Input)
+	logf("output: %s", absRunDir)
+	logf("requested clips: %d", st.ClipsN)
+	logf("burn subtitles: %t", st.BurnSubtitles)
+	if st.Aspect != "" {
+		logf("aspect: %s (%s)", st.Aspect, st.Reframe)
+	}
+	logf("ranker: %s", cfg.Ranker)
+
+	if err := execute(cfg, started, logf); err != nil {
+		return fmt.Errorf("resume %s: %w", absRunDir, err)
+	}
+	return nil
+}
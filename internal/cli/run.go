@@ -10,9 +10,9 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/forPelevin/hlcut/internal/domain/highlights"
 	"github.com/forPelevin/hlcut/internal/pipeline"
 	"github.com/spf13/cobra"
 )
@@ -35,10 +35,24 @@ func run(cmd *cobra.Command, input string) error {
 	if err != nil {
 		return fmt.Errorf("read burn-subtitles flag: %w", err)
 	}
+	refreshCache, err := cmd.Flags().GetBool("refresh")
+	if err != nil {
+		return fmt.Errorf("read refresh flag: %w", err)
+	}
+	noCache, err := cmd.Flags().GetBool("no-cache")
+	if err != nil {
+		return fmt.Errorf("read no-cache flag: %w", err)
+	}
 
-	apiKey := os.Getenv("OPENROUTER_API_KEY")
-	if apiKey == "" {
-		return errors.New("OPENROUTER_API_KEY is required (set it in .env)")
+	ranker, err := cmd.Flags().GetString("ranker")
+	if err != nil {
+		return fmt.Errorf("read ranker flag: %w", err)
+	}
+
+	cfg := baseConfig(logf)
+	cfg.Ranker = ranker
+	if err := requireAPIKey(cfg); err != nil {
+		return err
 	}
 
 	absIn, err := filepath.Abs(input)
@@ -49,29 +63,133 @@ func run(cmd *cobra.Command, input string) error {
 	if err != nil {
 		return err
 	}
+	cfg.InputPath = absIn
+	cfg.OutDir = outDir
+	cfg.ClipsN = clipsN
+	cfg.ClipsNSet = clipsNSet
+	cfg.BurnSubtitles = burnSubtitles
+	cfg.RefreshCache = refreshCache
+	cfg.NoCache = noCache
+	if err := applyChunkFlags(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyFrameFlags(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyJobsFlag(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applySubtitleFormatsFlag(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applySubtitleStyleFlags(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyTightenFlags(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyAudioFlags(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyAudiogramFlags(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyAudioTrackFlag(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyOutputFormatFlag(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyFastCutFlag(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyLanguageFlags(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyWhisperModelFlag(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyTranscribeChunkFlags(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyDiarizeFlag(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyTranscriptImportFlag(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyClipDurationFlags(cmd, &cfg); err != nil {
+		return err
+	}
+	if err := applyProfileFlag(cmd, &cfg); err != nil {
+		return err
+	}
 
 	logf("starting run")
 	logf("input: %s", absIn)
 	logf("output: %s", absOut)
-	minClip, maxClip := highlights.DurationBounds()
-	minClipSec := int(minClip.Seconds())
-	maxClipSec := int(maxClip.Seconds())
+	if cfg.Profile != "" {
+		logf("profile: %s", cfg.Profile)
+	}
+	durs := cfg.ClipDurations()
+	minClipSec := int(durs.Min.Seconds())
+	maxClipSec := int(durs.Max.Seconds())
 	if clipsNSet {
 		logf("requested clips: %d (%d-%ds each)", clipsN, minClipSec, maxClipSec)
 	} else {
 		logf("requested clips: auto (%d-%ds each)", minClipSec, maxClipSec)
 	}
+	if cfg.Translate {
+		logf("language: %s, translated to English", cfg.Language)
+	} else {
+		logf("language: %s", cfg.Language)
+	}
+	if cfg.TranscriptPath != "" {
+		logf("transcript: %s (imported, whisper not run)", cfg.TranscriptPath)
+	} else {
+		logf("whisper model: %s", cfg.WhisperModel)
+		if cfg.TranscribeChunk > 0 {
+			logf("chunked transcription: %s chunks, %d workers", cfg.TranscribeChunk, cfg.TranscribeWorkers)
+		}
+		if cfg.Diarize != "" {
+			logf("diarization: %s", cfg.Diarize)
+		}
+	}
 	logf("burn subtitles: %t", burnSubtitles)
+	if len(cfg.SubtitleFormats) > 0 {
+		logf("subtitle files: %s", strings.Join(cfg.SubtitleFormats, ", "))
+	}
+	if burnSubtitles || len(cfg.SubtitleFormats) > 0 {
+		logf("subtitle style: %s (%s)", cfg.SubtitleStyle, cfg.CaptionMode)
+	}
+	if cfg.Aspect != "" {
+		logf("aspect: %s (%s, %s)", cfg.Aspect, cfg.Reframe, cfg.Resolution)
+	}
+	if cfg.Tighten {
+		logf("tighten: fillers and pauses over %s", cfg.TightenPause)
+	}
+	if cfg.LoudnessLUFS != 0 {
+		logf("loudness: %g LUFS", cfg.LoudnessLUFS)
+	}
+	logf("output format: %s", cfg.OutputFormat)
+	if cfg.FastCut != "" {
+		logf("fast cut: %s", cfg.FastCut)
+	}
+	logf("ranker: %s", ranker)
+	logf("render jobs: %d", cfg.RenderJobs)
+	if cfg.LLMChunkWindow > 0 {
+		logf("chunked selection: %s windows, concurrency %d", cfg.LLMChunkWindow, cfg.LLMChunkConcurrency)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Hour)
-	defer cancel()
+	return execute(cfg, started, logf)
+}
 
-	cfg := pipeline.Config{
-		InputMP4:      absIn,
-		OutDir:        outDir,
-		ClipsN:        clipsN,
-		ClipsNSet:     clipsNSet,
-		BurnSubtitles: burnSubtitles,
+// baseConfig returns the pipeline configuration shared by every command:
+// tool locations and LLM settings from the environment. Per-run fields
+// (input, output, clip count) are filled in by the caller.
+func baseConfig(logf func(string, ...any)) pipeline.Config {
+	return pipeline.Config{
+		Ranker: pipeline.RankerOpenRouter,
 
 		FFmpegPath:  "ffmpeg",
 		FFprobePath: "ffprobe",
@@ -79,17 +197,49 @@ func run(cmd *cobra.Command, input string) error {
 		CacheDir: ".cache",
 
 		WhisperBin:   ".cache/bin/whisper.cpp",
-		WhisperModel: ".cache/models/ggml-base.bin",
+		WhisperModel: "base",
 
-		OpenRouterAPIKey:  apiKey,
+		OpenRouterAPIKey:  os.Getenv("OPENROUTER_API_KEY"),
 		OpenRouterModel:   getenvDefault("OPENROUTER_MODEL", "z-ai/glm-4.5-air:free"),
+		OpenRouterModels:  getenvCSV("OPENROUTER_MODELS", ""),
 		OpenRouterBaseURL: getenvDefault("OPENROUTER_BASE_URL", "https://openrouter.ai"),
 		OpenRouterAllowedHosts: getenvCSV(
 			"OPENROUTER_ALLOWED_HOSTS",
 			"openrouter.ai,api.openrouter.ai",
 		),
+
+		OpenAIBaseURL:           getenvDefault("OPENAI_BASE_URL", "https://api.openai.com"),
+		OpenAIAPIKey:            os.Getenv("OPENAI_API_KEY"),
+		OpenAIModel:             os.Getenv("OPENAI_MODEL"),
+		OpenAIAuthHeader:        getenvDefault("OPENAI_AUTH_HEADER", "Authorization"),
+		OpenAIResponseFormat:    getenvDefault("OPENAI_RESPONSE_FORMAT", "auto"),
+		OpenAIAllowHTTPLoopback: getenvBool("OPENAI_ALLOW_HTTP_LOOPBACK"),
+
+		LLMMaxAttempts:    getenvInt("LLM_MAX_ATTEMPTS", logf),
+		LLMRetryBaseDelay: getenvDuration("LLM_RETRY_BASE_DELAY", logf),
+		LLMRetryMaxDelay:  getenvDuration("LLM_RETRY_MAX_DELAY", logf),
+
 		Logf: logf,
 	}
+}
+
+// requireAPIKey is checked only by commands that call the LLM, so stages like
+// transcribe and render work without OpenRouter credentials. Only the
+// openrouter ranker needs a key up front; OpenAI-compatible servers may run
+// without authentication.
+func requireAPIKey(cfg pipeline.Config) error {
+	if cfg.Ranker != "" && cfg.Ranker != pipeline.RankerOpenRouter {
+		return nil
+	}
+	if cfg.OpenRouterAPIKey == "" {
+		return errors.New("OPENROUTER_API_KEY is required (set it in .env)")
+	}
+	return nil
+}
+
+func execute(cfg pipeline.Config, started time.Time, logf func(string, ...any)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Hour)
+	defer cancel()
 
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("config: %w", err)
@@ -111,6 +261,39 @@ func getenvDefault(k, def string) string {
 	return v
 }
 
+func getenvBool(k string) bool {
+	v, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(k)))
+	return err == nil && v
+}
+
+// getenvInt and getenvDuration return 0 (adapter default) for unset or
+// malformed values; malformed ones are reported so typos do not go unnoticed.
+func getenvInt(k string, logf func(string, ...any)) int {
+	raw := strings.TrimSpace(os.Getenv(k))
+	if raw == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		logf("ignoring invalid %s=%q", k, raw)
+		return 0
+	}
+	return v
+}
+
+func getenvDuration(k string, logf func(string, ...any)) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(k))
+	if raw == "" {
+		return 0
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil || v < 0 {
+		logf("ignoring invalid %s=%q (want a duration like 2s)", k, raw)
+		return 0
+	}
+	return v
+}
+
 func getenvCSV(k, def string) []string {
 	raw := strings.TrimSpace(os.Getenv(k))
 	if raw == "" {
@@ -153,6 +336,8 @@ var (
 )
 
 type runLogger struct {
+	// mu serializes lines from concurrent stages (e.g. parallel LLM requests).
+	mu          sync.Mutex
 	out         io.Writer
 	started     time.Time
 	useANSI     bool
@@ -165,6 +350,8 @@ func (l *runLogger) logf(format string, args ...any) {
 	}
 	msg := fmt.Sprintf(format, args...)
 
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	if !l.headerShown {
 		l.headerShown = true
 		l.printRaw(l.color(cBold, "┏━ 🚀 hlcut run ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
@@ -190,6 +377,12 @@ func (l *runLogger) decorate(msg string) (icon string, body string, color string
 	if msg == "starting run" {
 		return "🚀", "Starting pipeline", cBlue
 	}
+	if strings.HasPrefix(msg, "starting ") {
+		return "🚀", "Starting " + strings.TrimPrefix(msg, "starting "), cBlue
+	}
+	if msg == "resuming run" {
+		return "🔁", "Resuming pipeline", cBlue
+	}
 	if strings.HasPrefix(msg, "input: ") {
 		return "🎬", "Input  " + strings.TrimPrefix(msg, "input: "), cCyan
 	}
@@ -205,9 +398,22 @@ func (l *runLogger) decorate(msg string) (icon string, body string, color string
 	if strings.HasPrefix(msg, "cache: ") {
 		return "🗄️", "Cache  " + strings.TrimPrefix(msg, "cache: "), cBlue
 	}
+	if strings.HasPrefix(msg, "checkpoint") || strings.HasSuffix(msg, "(skipped, checkpoint)") || strings.HasSuffix(msg, "already rendered, skipping") {
+		return "⏭️", msg, cBlue
+	}
+	if strings.HasPrefix(msg, "llm: ") {
+		body := "LLM    " + strings.TrimPrefix(msg, "llm: ")
+		if strings.Contains(msg, " failed: ") {
+			return "⚠️", body, cYellow
+		}
+		return "🤖", body, cBlue
+	}
 	if strings.HasPrefix(msg, "output dirs: ") {
 		return "📂", "Dirs   " + strings.TrimPrefix(msg, "output dirs: "), cBlue
 	}
+	if strings.HasPrefix(msg, "clip ") && strings.Contains(msg, " rendered in ") {
+		return "🎞️", msg, cGreen
+	}
 	if strings.HasPrefix(msg, "manifest written") {
 		return "🧾", msg, cGreen
 	}
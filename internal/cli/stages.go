@@ -0,0 +1,851 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/framing"
+	"github.com/forPelevin/hlcut/internal/domain/subtitles"
+	"github.com/forPelevin/hlcut/internal/domain/tighten"
+	"github.com/forPelevin/hlcut/internal/pipeline"
+	"github.com/forPelevin/hlcut/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// Stage subcommands expose the steps of the root command individually. Each
+// consumes and produces JSON artifacts ("-" means stdin/stdout) so runs can
+// be scripted and selections edited by hand between stages:
+//
+//	hlcut transcribe in.mp4 -o transcript.json
+//	hlcut candidates transcript.json -o candidates.json
+//	hlcut select transcript.json --candidates candidates.json -o selection.json
+//	hlcut render in.mp4 --selection selection.json --transcript-json transcript.json
+
+const stageTimeout = 3 * time.Hour
+
+func newTranscribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "transcribe <input>",
+		Short:        "Extract audio and transcribe it into transcript JSON",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return stage(cmd, func(ctx context.Context, logf func(string, ...any)) error {
+				absIn, err := filepath.Abs(args[0])
+				if err != nil {
+					return err
+				}
+				refreshCache, err := cmd.Flags().GetBool("refresh")
+				if err != nil {
+					return fmt.Errorf("read refresh flag: %w", err)
+				}
+				noCache, err := cmd.Flags().GetBool("no-cache")
+				if err != nil {
+					return fmt.Errorf("read no-cache flag: %w", err)
+				}
+
+				cfg := baseConfig(logf)
+				cfg.InputPath = absIn
+				cfg.RefreshCache = refreshCache
+				cfg.NoCache = noCache
+				if err := applyAudioTrackFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyLanguageFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyWhisperModelFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyTranscribeChunkFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyDiarizeFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyTranscriptImportFlag(cmd, &cfg); err != nil {
+					return err
+				}
+
+				logf("input: %s", absIn)
+				tr, err := pipeline.Transcribe(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				return writeArtifact(cmd, tr)
+			})
+		},
+	}
+	addOutputFlag(cmd, "transcript")
+	cmd.Flags().Bool("refresh", false, "Re-run transcription even if a cached transcript exists")
+	cmd.Flags().Bool("no-cache", false, "Do not read or write the transcript cache")
+	addAudioTrackFlag(cmd)
+	addLanguageFlags(cmd)
+	addWhisperModelFlag(cmd)
+	addTranscribeChunkFlags(cmd)
+	addDiarizeFlag(cmd)
+	addTranscriptImportFlag(cmd)
+	return cmd
+}
+
+func newCandidatesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "candidates <transcript.json>",
+		Short:        "Build candidate highlight windows from a transcript",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return stage(cmd, func(_ context.Context, logf func(string, ...any)) error {
+				var tr types.Transcript
+				if err := readArtifact(cmd, args[0], &tr); err != nil {
+					return err
+				}
+				cfg := baseConfig(logf)
+				if err := applyClipDurationFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyProfileFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				cands, err := pipeline.Candidates(cfg, tr)
+				if err != nil {
+					return err
+				}
+				logf("built %d candidates", len(cands))
+				return writeArtifact(cmd, cands)
+			})
+		},
+	}
+	addOutputFlag(cmd, "candidates")
+	addClipDurationFlags(cmd)
+	addProfileFlag(cmd)
+	return cmd
+}
+
+func newSelectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "select <transcript.json>",
+		Short:        "Select highlight clips from candidates with the configured ranker",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return stage(cmd, func(ctx context.Context, logf func(string, ...any)) error {
+				clipsN, err := cmd.Flags().GetInt("clips")
+				if err != nil {
+					return fmt.Errorf("read clips flag: %w", err)
+				}
+				candsPath, err := cmd.Flags().GetString("candidates")
+				if err != nil {
+					return fmt.Errorf("read candidates flag: %w", err)
+				}
+
+				ranker, err := cmd.Flags().GetString("ranker")
+				if err != nil {
+					return fmt.Errorf("read ranker flag: %w", err)
+				}
+
+				cfg := baseConfig(logf)
+				cfg.ClipsN = clipsN
+				cfg.Ranker = ranker
+				if err := requireAPIKey(cfg); err != nil {
+					return err
+				}
+				if err := applyChunkFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyClipDurationFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyProfileFlag(cmd, &cfg); err != nil {
+					return err
+				}
+
+				var tr types.Transcript
+				if err := readArtifact(cmd, args[0], &tr); err != nil {
+					return err
+				}
+				var cands []types.Candidate
+				if candsPath != "" {
+					if err := readArtifact(cmd, candsPath, &cands); err != nil {
+						return err
+					}
+				} else if cands, err = pipeline.Candidates(cfg, tr); err != nil {
+					return err
+				}
+
+				clipSpecs, err := pipeline.Select(ctx, cfg, tr, cands)
+				if err != nil {
+					return err
+				}
+				return writeArtifact(cmd, clipSpecs)
+			})
+		},
+	}
+	addOutputFlag(cmd, "selection")
+	cmd.Flags().Int("clips", 12, "Max clips to return")
+	cmd.Flags().String("candidates", "", "Candidates JSON (default: built from the transcript)")
+	addRankerFlag(cmd)
+	addChunkFlags(cmd)
+	addClipDurationFlags(cmd)
+	addProfileFlag(cmd)
+	return cmd
+}
+
+func newRenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "render <input>",
+		Short:        "Render clips from a selection JSON and write a manifest",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return stage(cmd, func(ctx context.Context, logf func(string, ...any)) error {
+				absIn, err := filepath.Abs(args[0])
+				if err != nil {
+					return err
+				}
+				outDir, err := cmd.Flags().GetString("out")
+				if err != nil {
+					return fmt.Errorf("read out flag: %w", err)
+				}
+				selectionPath, err := cmd.Flags().GetString("selection")
+				if err != nil {
+					return fmt.Errorf("read selection flag: %w", err)
+				}
+				transcriptPath, err := cmd.Flags().GetString("transcript-json")
+				if err != nil {
+					return fmt.Errorf("read transcript-json flag: %w", err)
+				}
+				burnSubtitles, err := cmd.Flags().GetBool("burn-subtitles")
+				if err != nil {
+					return fmt.Errorf("read burn-subtitles flag: %w", err)
+				}
+
+				var clipSpecs []types.ClipSpec
+				if err := readArtifact(cmd, selectionPath, &clipSpecs); err != nil {
+					return err
+				}
+				var tr types.Transcript
+				if transcriptPath != "" {
+					if err := readArtifact(cmd, transcriptPath, &tr); err != nil {
+						return err
+					}
+				}
+
+				cfg := baseConfig(logf)
+				cfg.InputPath = absIn
+				cfg.OutDir = outDir
+				cfg.BurnSubtitles = burnSubtitles
+				if err := applyFrameFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyJobsFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applySubtitleFormatsFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applySubtitleStyleFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyTightenFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyAudioFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyAudiogramFlags(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyAudioTrackFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyOutputFormatFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyFastCutFlag(cmd, &cfg); err != nil {
+					return err
+				}
+				if err := applyProfileFlag(cmd, &cfg); err != nil {
+					return err
+				}
+
+				logf("input: %s", absIn)
+				m, _, err := pipeline.Render(ctx, cfg, tr, clipSpecs)
+				if err != nil {
+					return err
+				}
+				output, err := cmd.Flags().GetString("output")
+				if err != nil {
+					return fmt.Errorf("read output flag: %w", err)
+				}
+				if output == "" {
+					return nil
+				}
+				return writeArtifact(cmd, m)
+			})
+		},
+	}
+	cmd.Flags().StringP("output", "o", "", `Also write the manifest JSON to this file ("-" for stdout)`)
+	cmd.Flags().String("out", "out", "Output directory")
+	cmd.Flags().String("selection", "", "Selection JSON produced by `hlcut select`")
+	// Not --transcript: on the other commands that imports SRT/VTT/whisper files.
+	cmd.Flags().String("transcript-json", "", "Transcript JSON from `hlcut transcribe` (required with --burn-subtitles, --subtitle-formats or --tighten)")
+	cmd.Flags().Bool("burn-subtitles", false, "Burn karaoke subtitles into clips and write ASS files")
+	addSubtitleFormatsFlag(cmd)
+	addSubtitleStyleFlags(cmd)
+	addFrameFlags(cmd)
+	addTightenFlags(cmd)
+	addAudioFlags(cmd)
+	addAudiogramFlags(cmd)
+	addAudioTrackFlag(cmd)
+	addOutputFormatFlag(cmd)
+	addFastCutFlag(cmd)
+	addProfileFlag(cmd)
+	addJobsFlag(cmd)
+	_ = cmd.MarkFlagRequired("selection")
+	return cmd
+}
+
+// stage runs a stage subcommand with the shared logger and timeout.
+func stage(cmd *cobra.Command, fn func(ctx context.Context, logf func(string, ...any)) error) error {
+	started := time.Now()
+	logf := newRunLogger(cmd.ErrOrStderr(), started)
+
+	ctx, cancel := context.WithTimeout(context.Background(), stageTimeout)
+	defer cancel()
+
+	logf("starting %s", cmd.Name())
+	if err := fn(ctx, logf); err != nil {
+		logf("run failed after %s: %v", shortDuration(time.Since(started)), err)
+		return err
+	}
+	logf("run completed in %s", shortDuration(time.Since(started)))
+	return nil
+}
+
+func addChunkFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("chunk-window", 0, "Select in map-reduce mode over transcript windows of this length, e.g. 20m (0 = single LLM request)")
+	cmd.Flags().Int("chunk-concurrency", 4, "Max parallel LLM requests in chunked selection")
+}
+
+// applyChunkFlags copies the chunked-selection flags into cfg.
+func applyChunkFlags(cmd *cobra.Command, cfg *pipeline.Config) error {
+	window, err := cmd.Flags().GetDuration("chunk-window")
+	if err != nil {
+		return fmt.Errorf("read chunk-window flag: %w", err)
+	}
+	concurrency, err := cmd.Flags().GetInt("chunk-concurrency")
+	if err != nil {
+		return fmt.Errorf("read chunk-concurrency flag: %w", err)
+	}
+	cfg.LLMChunkWindow = window
+	cfg.LLMChunkConcurrency = concurrency
+	return nil
+}
+
+func addClipDurationFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("min-clip", 0, "Shortest clip, e.g. 15s (default: 20s, or the --profile's)")
+	cmd.Flags().Duration("max-clip", 0, "Longest clip, e.g. 60s (default: 3m, or the --profile's)")
+}
+
+// applyClipDurationFlags copies --min-clip and --max-clip into cfg; the
+// pipeline validates them.
+func applyClipDurationFlags(cmd *cobra.Command, cfg *pipeline.Config) error {
+	minClip, err := cmd.Flags().GetDuration("min-clip")
+	if err != nil {
+		return fmt.Errorf("read min-clip flag: %w", err)
+	}
+	maxClip, err := cmd.Flags().GetDuration("max-clip")
+	if err != nil {
+		return fmt.Errorf("read max-clip flag: %w", err)
+	}
+	cfg.MinClip, cfg.MaxClip = minClip, maxClip
+	return nil
+}
+
+func addProfileFlag(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"profile",
+		"",
+		fmt.Sprintf(
+			"Platform profile: %s; sets clip durations, aspect, caption style and size/bitrate caps unless given explicitly",
+			strings.Join(pipeline.Profiles(), ", "),
+		),
+	)
+}
+
+// applyProfileFlag fills cfg from --profile. It runs after the other apply
+// functions: settings whose flags were given explicitly are kept.
+func applyProfileFlag(cmd *cobra.Command, cfg *pipeline.Config) error {
+	name, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return fmt.Errorf("read profile flag: %w", err)
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	p, err := pipeline.LookupProfile(name)
+	if err != nil {
+		return fmt.Errorf("--profile: %w", err)
+	}
+	unset := func(flag string) bool {
+		f := cmd.Flags().Lookup(flag)
+		return f != nil && !f.Changed
+	}
+	if unset("min-clip") {
+		cfg.MinClip = p.MinClip
+	}
+	if unset("max-clip") {
+		cfg.MaxClip = p.MaxClip
+	}
+	if unset("aspect") {
+		cfg.Aspect = p.Aspect
+	}
+	if unset("subtitle-style") {
+		cfg.SubtitleStyle = p.SubtitleStyle
+	}
+	if unset("caption-mode") {
+		cfg.CaptionMode = p.CaptionMode
+	}
+	cfg.Profile = p.Name
+	cfg.MaxFileSizeMB, cfg.MaxVideoKbps = p.MaxFileSizeMB, p.MaxVideoKbps
+	return nil
+}
+
+func addSubtitleFormatsFlag(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"subtitle-formats",
+		"",
+		"Comma-separated subtitle files to write per clip: ass, srt, vtt (with or without --burn-subtitles)",
+	)
+}
+
+// applySubtitleFormatsFlag copies --subtitle-formats into cfg.
+func applySubtitleFormatsFlag(cmd *cobra.Command, cfg *pipeline.Config) error {
+	raw, err := cmd.Flags().GetString("subtitle-formats")
+	if err != nil {
+		return fmt.Errorf("read subtitle-formats flag: %w", err)
+	}
+	formats, err := subtitles.ParseFormats(strings.Split(raw, ","))
+	if err != nil {
+		return fmt.Errorf("--subtitle-formats: %w", err)
+	}
+	cfg.SubtitleFormats = formats
+	return nil
+}
+
+func addSubtitleStyleFlags(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"subtitle-style",
+		subtitles.PresetTikTok,
+		fmt.Sprintf("Subtitle look: %s, or a .ass file with a [V4+ Styles] section", strings.Join(subtitles.Presets(), ", ")),
+	)
+	cmd.Flags().String(
+		"caption-mode",
+		subtitles.CaptionKaraoke,
+		"Burned captions: karaoke (full lines with a word sweep) or pop (1-3 words at a time, key words emphasized)",
+	)
+	cmd.Flags().String("fonts-dir", "", "Directory with fonts for burned subtitles (passed to ffmpeg)")
+}
+
+// applySubtitleStyleFlags copies --subtitle-style, --caption-mode and
+// --fonts-dir into cfg.
+// Paths are made absolute so a resumed run finds them from any directory.
+func applySubtitleStyleFlags(cmd *cobra.Command, cfg *pipeline.Config) error {
+	style, err := cmd.Flags().GetString("subtitle-style")
+	if err != nil {
+		return fmt.Errorf("read subtitle-style flag: %w", err)
+	}
+	mode, err := cmd.Flags().GetString("caption-mode")
+	if err != nil {
+		return fmt.Errorf("read caption-mode flag: %w", err)
+	}
+	if mode, err = subtitles.ParseCaptionMode(mode); err != nil {
+		return fmt.Errorf("--caption-mode: %w", err)
+	}
+	fontsDir, err := cmd.Flags().GetString("fonts-dir")
+	if err != nil {
+		return fmt.Errorf("read fonts-dir flag: %w", err)
+	}
+	if subtitles.CheckPreset(style) != nil {
+		if style, err = filepath.Abs(style); err != nil {
+			return err
+		}
+	}
+	if fontsDir != "" {
+		if fontsDir, err = filepath.Abs(fontsDir); err != nil {
+			return err
+		}
+	}
+	cfg.SubtitleStyle = style
+	cfg.CaptionMode = mode
+	cfg.FontsDir = fontsDir
+	return nil
+}
+
+func addTightenFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("tighten", false, "Cut filler words (um, uh, \"you know\") and long pauses out of clips as jump cuts")
+	cmd.Flags().Duration("tighten-pause", tighten.DefaultMaxPause, "Longest pause --tighten keeps; longer ones are shortened")
+}
+
+// applyTightenFlags copies --tighten and --tighten-pause into cfg.
+func applyTightenFlags(cmd *cobra.Command, cfg *pipeline.Config) error {
+	on, err := cmd.Flags().GetBool("tighten")
+	if err != nil {
+		return fmt.Errorf("read tighten flag: %w", err)
+	}
+	pause, err := cmd.Flags().GetDuration("tighten-pause")
+	if err != nil {
+		return fmt.Errorf("read tighten-pause flag: %w", err)
+	}
+	if pause <= 0 {
+		return fmt.Errorf("--tighten-pause must be > 0")
+	}
+	cfg.Tighten = on
+	cfg.TightenPause = pause
+	return nil
+}
+
+func addAudioFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("loudnorm", false, "Normalize clip loudness with two-pass EBU R128 loudnorm")
+	cmd.Flags().Float64("loudness", -14, "Loudness target for --loudnorm in LUFS (-14 suits social platforms)")
+	cmd.Flags().Duration("audio-fade", 0, "Fade clip audio in and out over this long, e.g. 50ms (0 = no fades)")
+}
+
+// applyAudioFlags copies --loudnorm, --loudness and --audio-fade into cfg.
+func applyAudioFlags(cmd *cobra.Command, cfg *pipeline.Config) error {
+	on, err := cmd.Flags().GetBool("loudnorm")
+	if err != nil {
+		return fmt.Errorf("read loudnorm flag: %w", err)
+	}
+	target, err := cmd.Flags().GetFloat64("loudness")
+	if err != nil {
+		return fmt.Errorf("read loudness flag: %w", err)
+	}
+	fade, err := cmd.Flags().GetDuration("audio-fade")
+	if err != nil {
+		return fmt.Errorf("read audio-fade flag: %w", err)
+	}
+	cfg.LoudnessLUFS = 0
+	if on {
+		cfg.LoudnessLUFS = target
+	}
+	cfg.AudioFade = fade
+	return nil
+}
+
+func addAudiogramFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cover", "", "Cover image behind the waveform of audio-only inputs (default: a solid --background)")
+	cmd.Flags().String("background", "#101820", "Background color of audio-only clips without --cover, e.g. #101820 or navy")
+	cmd.Flags().String("episode-title", "", "Title shown on audio-only clips (default: the file's title tag or name)")
+}
+
+// applyAudiogramFlags copies --cover, --background and --episode-title into
+// cfg. The cover path is made absolute so a resumed run finds it.
+func applyAudiogramFlags(cmd *cobra.Command, cfg *pipeline.Config) error {
+	cover, err := cmd.Flags().GetString("cover")
+	if err != nil {
+		return fmt.Errorf("read cover flag: %w", err)
+	}
+	background, err := cmd.Flags().GetString("background")
+	if err != nil {
+		return fmt.Errorf("read background flag: %w", err)
+	}
+	title, err := cmd.Flags().GetString("episode-title")
+	if err != nil {
+		return fmt.Errorf("read episode-title flag: %w", err)
+	}
+	if cover != "" {
+		if cover, err = filepath.Abs(cover); err != nil {
+			return err
+		}
+	}
+	cfg.Cover = cover
+	cfg.Background = strings.TrimSpace(background)
+	cfg.EpisodeTitle = strings.TrimSpace(title)
+	return nil
+}
+
+func addFrameFlags(cmd *cobra.Command) {
+	cmd.Flags().String("aspect", "", "Reframe clips to 9:16, 1:1, 4:5 or 16:9 (default: keep the source aspect)")
+	cmd.Flags().String(
+		"reframe",
+		framing.ModeCropCenter,
+		fmt.Sprintf("How --aspect fits the source: %s, %s or %s", framing.ModeCropCenter, framing.ModeBlurPad, framing.ModeLetterbox),
+	)
+	cmd.Flags().String("resolution", "1080p", "Short side of reframed clips: 720p, 1080p, 1440p, 2160p or pixels")
+}
+
+// applyFrameFlags copies the reframing flags into cfg.
+func applyFrameFlags(cmd *cobra.Command, cfg *pipeline.Config) error {
+	aspect, err := cmd.Flags().GetString("aspect")
+	if err != nil {
+		return fmt.Errorf("read aspect flag: %w", err)
+	}
+	reframe, err := cmd.Flags().GetString("reframe")
+	if err != nil {
+		return fmt.Errorf("read reframe flag: %w", err)
+	}
+	resolution, err := cmd.Flags().GetString("resolution")
+	if err != nil {
+		return fmt.Errorf("read resolution flag: %w", err)
+	}
+	cfg.Aspect = aspect
+	cfg.Reframe = reframe
+	cfg.Resolution = resolution
+	return nil
+}
+
+// defaultRenderJobs leaves most cores to libx264, which already encodes each
+// clip with several threads; parallel renders mainly fill the gaps of its
+// single-threaded decode and filter steps.
+func defaultRenderJobs() int {
+	return max(1, runtime.NumCPU()/4)
+}
+
+func addAudioTrackFlag(cmd *cobra.Command) {
+	cmd.Flags().Int("audio-track", -1, "Audio track to use, 0-based among the audio streams (default: the track flagged default)")
+}
+
+// applyAudioTrackFlag copies --audio-track into cfg; -1 selects automatically.
+func applyAudioTrackFlag(cmd *cobra.Command, cfg *pipeline.Config) error {
+	track, err := cmd.Flags().GetInt("audio-track")
+	if err != nil {
+		return fmt.Errorf("read audio-track flag: %w", err)
+	}
+	if track < -1 {
+		return fmt.Errorf("--audio-track must be >= 0, got %d", track)
+	}
+	cfg.AudioTrack = track
+	return nil
+}
+
+func addLanguageFlags(cmd *cobra.Command) {
+	cmd.Flags().String("language", "auto", "Spoken language as a whisper code (en, es, de, ...) or auto to detect it")
+	cmd.Flags().Bool("translate", false, "Translate the speech to English while transcribing")
+}
+
+// applyLanguageFlags copies --language and --translate into cfg; the
+// pipeline validates the language.
+func applyLanguageFlags(cmd *cobra.Command, cfg *pipeline.Config) error {
+	lang, err := cmd.Flags().GetString("language")
+	if err != nil {
+		return fmt.Errorf("read language flag: %w", err)
+	}
+	translate, err := cmd.Flags().GetBool("translate")
+	if err != nil {
+		return fmt.Errorf("read translate flag: %w", err)
+	}
+	cfg.Language = strings.ToLower(strings.TrimSpace(lang))
+	cfg.Translate = translate
+	return nil
+}
+
+func addWhisperModelFlag(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"whisper-model",
+		"base",
+		"Whisper model: a name from `hlcut models list` (looked up in .cache/models) or the path of a ggml .bin file",
+	)
+}
+
+// applyWhisperModelFlag copies --whisper-model into cfg; the pipeline
+// resolves and validates it.
+func applyWhisperModelFlag(cmd *cobra.Command, cfg *pipeline.Config) error {
+	model, err := cmd.Flags().GetString("whisper-model")
+	if err != nil {
+		return fmt.Errorf("read whisper-model flag: %w", err)
+	}
+	cfg.WhisperModel = strings.TrimSpace(model)
+	return nil
+}
+
+func addTranscribeChunkFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("transcribe-chunk", 0, "Transcribe in parallel chunks of about this length cut at silences, e.g. 10m (0 = one whisper process)")
+	cmd.Flags().Int("transcribe-workers", 2, "Max parallel whisper processes in chunked transcription")
+	cmd.Flags().Int("whisper-threads", 0, "Threads per whisper process (0 = whisper's default, or the CPUs split between workers)")
+}
+
+// applyTranscribeChunkFlags copies the chunked-transcription flags into
+// cfg; the pipeline validates them.
+func applyTranscribeChunkFlags(cmd *cobra.Command, cfg *pipeline.Config) error {
+	chunk, err := cmd.Flags().GetDuration("transcribe-chunk")
+	if err != nil {
+		return fmt.Errorf("read transcribe-chunk flag: %w", err)
+	}
+	workers, err := cmd.Flags().GetInt("transcribe-workers")
+	if err != nil {
+		return fmt.Errorf("read transcribe-workers flag: %w", err)
+	}
+	threads, err := cmd.Flags().GetInt("whisper-threads")
+	if err != nil {
+		return fmt.Errorf("read whisper-threads flag: %w", err)
+	}
+	cfg.TranscribeChunk = chunk
+	cfg.TranscribeWorkers = workers
+	cfg.WhisperThreads = threads
+	return nil
+}
+
+func addDiarizeFlag(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"diarize",
+		"",
+		"Label speakers: tdrz (with a tinydiarize model such as small.en-tdrz) or stereo (one speaker per audio channel)",
+	)
+}
+
+// applyDiarizeFlag copies --diarize into cfg; the pipeline validates it.
+func applyDiarizeFlag(cmd *cobra.Command, cfg *pipeline.Config) error {
+	mode, err := cmd.Flags().GetString("diarize")
+	if err != nil {
+		return fmt.Errorf("read diarize flag: %w", err)
+	}
+	cfg.Diarize = strings.ToLower(strings.TrimSpace(mode))
+	return nil
+}
+
+func addTranscriptImportFlag(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"transcript",
+		"",
+		"Use an existing transcript (.srt, .vtt, whisper.cpp or OpenAI verbose .json, .jsonl) instead of running whisper",
+	)
+}
+
+// applyTranscriptImportFlag copies --transcript into cfg as an absolute path.
+func applyTranscriptImportFlag(cmd *cobra.Command, cfg *pipeline.Config) error {
+	path, err := cmd.Flags().GetString("transcript")
+	if err != nil {
+		return fmt.Errorf("read transcript flag: %w", err)
+	}
+	if path == "" {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	cfg.TranscriptPath = abs
+	return nil
+}
+
+func addOutputFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"output-format",
+		"mp4",
+		"Clip format: mp4 (h264/aac), webm (vp9/opus) or mov (ProRes 422 HQ/PCM, for editing)",
+	)
+}
+
+// applyOutputFormatFlag copies --output-format into cfg; the pipeline
+// validates it.
+func applyOutputFormatFlag(cmd *cobra.Command, cfg *pipeline.Config) error {
+	format, err := cmd.Flags().GetString("output-format")
+	if err != nil {
+		return fmt.Errorf("read output-format flag: %w", err)
+	}
+	cfg.OutputFormat = strings.ToLower(strings.TrimSpace(format))
+	return nil
+}
+
+func addFastCutFlag(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"fast-cut",
+		"",
+		"Stream-copy clips without burned subtitles instead of encoding them: copy starts at the keyframe before "+
+			"each clip, smart re-encodes up to the first keyframe (--fast-cut=smart)",
+	)
+	cmd.Flags().Lookup("fast-cut").NoOptDefVal = "copy"
+}
+
+// applyFastCutFlag copies --fast-cut into cfg; the pipeline validates it.
+func applyFastCutFlag(cmd *cobra.Command, cfg *pipeline.Config) error {
+	mode, err := cmd.Flags().GetString("fast-cut")
+	if err != nil {
+		return fmt.Errorf("read fast-cut flag: %w", err)
+	}
+	cfg.FastCut = strings.ToLower(strings.TrimSpace(mode))
+	return nil
+}
+
+func addJobsFlag(cmd *cobra.Command) {
+	cmd.Flags().Int("jobs", defaultRenderJobs(), "Max clips rendered in parallel")
+}
+
+// applyJobsFlag copies --jobs into cfg.
+func applyJobsFlag(cmd *cobra.Command, cfg *pipeline.Config) error {
+	jobs, err := cmd.Flags().GetInt("jobs")
+	if err != nil {
+		return fmt.Errorf("read jobs flag: %w", err)
+	}
+	if jobs < 1 {
+		return fmt.Errorf("--jobs must be >= 1, got %d", jobs)
+	}
+	cfg.RenderJobs = jobs
+	return nil
+}
+
+func addRankerFlag(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"ranker",
+		pipeline.RankerOpenRouter,
+		fmt.Sprintf(
+			"Clip ranker: %s (LLM), %s (OpenAI-compatible server, see OPENAI_* env) or %s (offline, no API key)",
+			pipeline.RankerOpenRouter,
+			pipeline.RankerOpenAI,
+			pipeline.RankerHeuristic,
+		),
+	)
+}
+
+func addOutputFlag(cmd *cobra.Command, what string) {
+	cmd.Flags().StringP("output", "o", "-", fmt.Sprintf(`Write %s JSON to this file ("-" for stdout)`, what))
+}
+
+func readArtifact(cmd *cobra.Command, path string, v any) error {
+	var (
+		b   []byte
+		err error
+	)
+	if path == "-" {
+		b, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeArtifact(cmd *cobra.Command, v any) error {
+	path, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return fmt.Errorf("read output flag: %w", err)
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", path, err)
+	}
+	b = append(b, '\n')
+	if path == "-" {
+		_, err := cmd.OutOrStdout().Write(b)
+		return err
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return err
+	}
+	return nil
+}
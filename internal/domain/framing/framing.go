@@ -0,0 +1,96 @@
+package framing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Modes for fitting the source into a frame of another aspect ratio.
+const (
+	// ModeCropCenter fills the frame and crops the overflow around the center.
+	ModeCropCenter = "crop-center"
+	// ModeBlurPad fits the whole source and fills the bars with a blurred,
+	// cropped copy of it.
+	ModeBlurPad = "blur-pad"
+	// ModeLetterbox fits the whole source on black bars.
+	ModeLetterbox = "letterbox"
+)
+
+// DefaultResolution is the short side of the output frame when none is given.
+const DefaultResolution = 1080
+
+// aspects lists the supported aspect ratios as width:height.
+var aspects = map[string][2]int{
+	"9:16": {9, 16},
+	"1:1":  {1, 1},
+	"4:5":  {4, 5},
+	"16:9": {16, 9},
+}
+
+// resolutions are the presets accepted for the short side of the frame.
+var resolutions = map[string]int{
+	"720p":  720,
+	"1080p": 1080,
+	"1440p": 1440,
+	"2160p": 2160,
+}
+
+// Resolve builds the output frame for an aspect ratio, fit mode and short-side
+// size in pixels. An empty aspect (or "source") keeps the source geometry; an
+// empty mode means crop-center and a size <= 0 means DefaultResolution.
+func Resolve(aspect, mode string, size int) (types.Frame, error) {
+	aspect = strings.TrimSpace(aspect)
+	if aspect == "" || aspect == "source" {
+		return types.Frame{}, nil
+	}
+	ratio, ok := aspects[aspect]
+	if !ok {
+		return types.Frame{}, fmt.Errorf("unknown aspect %q (want 9:16, 1:1, 4:5 or 16:9)", aspect)
+	}
+	switch mode {
+	case "":
+		mode = ModeCropCenter
+	case ModeCropCenter, ModeBlurPad, ModeLetterbox:
+	default:
+		return types.Frame{}, fmt.Errorf(
+			"unknown reframe mode %q (want %s, %s or %s)",
+			mode, ModeCropCenter, ModeBlurPad, ModeLetterbox,
+		)
+	}
+	if size <= 0 {
+		size = DefaultResolution
+	}
+
+	w, h := ratio[0], ratio[1]
+	f := types.Frame{Aspect: aspect, Mode: mode}
+	if w <= h {
+		f.Width, f.Height = size, even(size*h/w)
+	} else {
+		f.Width, f.Height = even(size*w/h), size
+	}
+	return f, nil
+}
+
+// ParseResolution reads a resolution preset (720p, 1080p, 1440p, 2160p) or a
+// plain pixel count for the short side of the frame. Empty means
+// DefaultResolution.
+func ParseResolution(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return DefaultResolution, nil
+	}
+	if v, ok := resolutions[s]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 240 || v > 4320 || v%2 != 0 {
+		return 0, fmt.Errorf("invalid resolution %q (want 720p, 1080p, 1440p, 2160p or an even pixel count)", s)
+	}
+	return v, nil
+}
+
+// even rounds down to an even pixel count, as required by yuv420p encoders.
+func even(n int) int { return n &^ 1 }
@@ -0,0 +1,71 @@
+package framing
+
+import (
+	"testing"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func TestResolve_Presets(t *testing.T) {
+	tests := []struct {
+		aspect string
+		size   int
+		w, h   int
+	}{
+		{"9:16", 0, 1080, 1920},
+		{"1:1", 1080, 1080, 1080},
+		{"4:5", 1080, 1080, 1350},
+		{"16:9", 1080, 1920, 1080},
+		{"9:16", 720, 720, 1280},
+		{"4:5", 1440, 1440, 1800},
+	}
+	for _, tt := range tests {
+		f, err := Resolve(tt.aspect, "", tt.size)
+		if err != nil {
+			t.Fatalf("Resolve(%s, %d): %v", tt.aspect, tt.size, err)
+		}
+		if f.Width != tt.w || f.Height != tt.h || f.Mode != ModeCropCenter {
+			t.Fatalf("Resolve(%s, %d) = %+v, want %dx%d crop-center", tt.aspect, tt.size, f, tt.w, tt.h)
+		}
+		if f.Width%2 != 0 || f.Height%2 != 0 {
+			t.Fatalf("odd dimensions: %+v", f)
+		}
+	}
+}
+
+func TestResolve_SourceAndErrors(t *testing.T) {
+	for _, aspect := range []string{"", "source"} {
+		f, err := Resolve(aspect, ModeBlurPad, 1080)
+		if err != nil || f != (types.Frame{}) {
+			t.Fatalf("Resolve(%q) = %+v, %v; want source geometry", aspect, f, err)
+		}
+	}
+	if _, err := Resolve("3:2", "", 0); err == nil {
+		t.Fatalf("expected unknown aspect error")
+	}
+	if _, err := Resolve("9:16", "stretch", 0); err == nil {
+		t.Fatalf("expected unknown mode error")
+	}
+}
+
+func TestParseResolution(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"", 1080, false},
+		{"720p", 720, false},
+		{"1440P", 1440, false},
+		{"960", 960, false},
+		{"961", 0, true},
+		{"4k", 0, true},
+		{"100", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseResolution(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Fatalf("ParseResolution(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+}
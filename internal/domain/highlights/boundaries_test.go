@@ -0,0 +1,72 @@
+package highlights
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeClip_SnapsToPunctuationNearTail(t *testing.T) {
+	timing := Timing{
+		words: []timedWord{
+			{Start: 53 * time.Second, End: 54 * time.Second, Text: "almost"},
+			{Start: 54 * time.Second, End: 55 * time.Second, Text: "there"},
+			{Start: 55 * time.Second, End: 56 * time.Second, Text: "finished."},
+			{Start: 56 * time.Second, End: 57 * time.Second, Text: "next"},
+		},
+	}
+
+	_, en, ok := NormalizeClip(0, 60*time.Second, 20*time.Second, 60*time.Second, timing)
+	if !ok {
+		t.Fatalf("expected normalized clip")
+	}
+	if en != 56*time.Second {
+		t.Fatalf("expected clip end to snap to punctuation at 56s, got %v", en)
+	}
+}
+
+func TestNormalizeClip_PrefersComprehensiveSentenceEnd(t *testing.T) {
+	timing := Timing{
+		words: []timedWord{
+			{Start: 53 * time.Second, End: 54 * time.Second, Text: "What"},
+			{Start: 54 * time.Second, End: 55 * time.Second, Text: "is"},
+			{Start: 55 * time.Second, End: 56 * time.Second, Text: "going"},
+			{Start: 56 * time.Second, End: 57 * time.Second, Text: "on?"},
+			{Start: 57 * time.Second, End: 57*time.Second + 200*time.Millisecond, Text: "I"},
+			{Start: 57*time.Second + 200*time.Millisecond, End: 58 * time.Second, Text: "am"},
+			{Start: 58 * time.Second, End: 59 * time.Second, Text: "out."},
+		},
+	}
+
+	_, en, ok := NormalizeClip(0, 58*time.Second, 20*time.Second, 60*time.Second, timing)
+	if !ok {
+		t.Fatalf("expected normalized clip")
+	}
+	if en != 59*time.Second {
+		t.Fatalf("expected clip end to prefer full-resolution sentence at 59s, got %v", en)
+	}
+}
+
+func TestNormalizeClip_AvoidsQuestionTailWhenContinuationStartsImmediately(t *testing.T) {
+	timing := Timing{
+		words: []timedWord{
+			{Start: 73 * time.Second, End: 74 * time.Second, Text: "there's"},
+			{Start: 74 * time.Second, End: 75 * time.Second, Text: "more!"},
+			{Start: 75 * time.Second, End: 76 * time.Second, Text: "what"},
+			{Start: 76 * time.Second, End: 77 * time.Second, Text: "is"},
+			{Start: 77 * time.Second, End: 78 * time.Second, Text: "going"},
+			{Start: 78 * time.Second, End: 79 * time.Second, Text: "on?"},
+			{Start: 79 * time.Second, End: 79*time.Second + 200*time.Millisecond, Text: "i"},
+			{Start: 79*time.Second + 200*time.Millisecond, End: 80 * time.Second, Text: "was"},
+		},
+	}
+
+	// start=20s, max=60s => hard upper bound is 80s, so "on?" is the tail candidate;
+	// the logic should back off to "more!" to avoid abrupt unresolved question ending.
+	_, en, ok := NormalizeClip(20*time.Second, 80*time.Second, 20*time.Second, 60*time.Second, timing)
+	if !ok {
+		t.Fatalf("expected normalized clip")
+	}
+	if en != 75*time.Second {
+		t.Fatalf("expected clip end to back off to 75s for smoother closure, got %v", en)
+	}
+}
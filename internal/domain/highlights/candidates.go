@@ -12,17 +12,19 @@ const (
 	defaultMaxClip = 3 * time.Minute
 )
 
-// DurationBounds returns internal duration limits for generated clips.
-func DurationBounds() (time.Duration, time.Duration) {
-	return defaultMinClip, defaultMaxClip
+// DefaultDurations returns the clip length bounds used when neither flags
+// nor a platform profile set them.
+func DefaultDurations() types.ClipDurations {
+	return types.ClipDurations{Min: defaultMinClip, Max: defaultMaxClip}
 }
 
-// BuildCandidates creates many candidate windows from the transcript.
+// BuildCandidates creates many candidate windows from the transcript, each
+// within the duration bounds d.
 // MVP strategy:
 //   - Prefer word-timestamp-driven windows when available (more granular than segments).
 //   - Fall back to segment windows.
-func BuildCandidates(tr types.Transcript) []types.Candidate {
-	minClip, maxClip := DurationBounds()
+func BuildCandidates(tr types.Transcript, d types.ClipDurations) []types.Candidate {
+	minClip, maxClip := d.Min, d.Max
 
 	segs := tr.Segments
 	if len(segs) == 0 {
@@ -45,6 +47,7 @@ func BuildCandidates(tr types.Transcript) []types.Candidate {
 	for i := 0; i < len(segs); i++ {
 		start := dur(segs[i].Start)
 		var parts []string
+		var spoken []timedWord
 		for j := i; j < len(segs); j++ {
 			end := dur(segs[j].End)
 			win := end - start
@@ -54,24 +57,51 @@ func BuildCandidates(tr types.Transcript) []types.Candidate {
 			if win < minClip {
 				continue
 			}
-			if strings.TrimSpace(segs[j].Text) != "" {
-				parts = append(parts, strings.TrimSpace(segs[j].Text))
+			if t := strings.TrimSpace(segs[j].Text); t != "" {
+				parts = append(parts, t)
+				spoken = append(spoken, timedWord{Text: t, Speaker: segs[j].Speaker})
 			}
 			text := strings.TrimSpace(strings.Join(parts, " "))
 			if text == "" {
 				continue
 			}
 			info, hook := Score(text)
-			out = append(out, types.Candidate{Start: start, End: end, Text: text, InfoScore: info, HookScore: hook})
+			out = append(out, types.Candidate{
+				Start:     start,
+				End:       end,
+				Text:      text,
+				Turns:     turnsOf(spoken),
+				InfoScore: info,
+				HookScore: hook,
+			})
 		}
 	}
 	return out
 }
 
 type timedWord struct {
-	Start time.Duration
-	End   time.Duration
-	Text  string
+	Start   time.Duration
+	End     time.Duration
+	Text    string
+	Speaker string
+}
+
+// turnsOf groups words into speaker turns; nil when no word has a speaker.
+func turnsOf(words []timedWord) []types.Turn {
+	var out []types.Turn
+	labeled := false
+	for _, w := range words {
+		labeled = labeled || w.Speaker != ""
+		if n := len(out); n > 0 && out[n-1].Speaker == w.Speaker {
+			out[n-1].Text += " " + w.Text
+			continue
+		}
+		out = append(out, types.Turn{Speaker: w.Speaker, Text: w.Text})
+	}
+	if !labeled {
+		return nil
+	}
+	return out
 }
 
 func collectAllWords(tr types.Transcript) []timedWord {
@@ -87,7 +117,7 @@ func collectAllWords(tr types.Transcript) []timedWord {
 			if text == "" {
 				continue
 			}
-			out = append(out, timedWord{Start: ws, End: we, Text: text})
+			out = append(out, timedWord{Start: ws, End: we, Text: text, Speaker: types.WordSpeaker(s, w)})
 		}
 	}
 	return out
@@ -148,7 +178,14 @@ func buildFromWords(words []timedWord, minClip, maxClip time.Duration) []types.C
 				continue
 			}
 			info, hook := Score(text)
-			out = append(out, types.Candidate{Start: start, End: end, Text: text, InfoScore: info, HookScore: hook})
+			out = append(out, types.Candidate{
+				Start:     start,
+				End:       end,
+				Text:      text,
+				Turns:     turnsOf(words[i : j+1]),
+				InfoScore: info,
+				HookScore: hook,
+			})
 			if len(out) >= maxCandidates {
 				return out
 			}
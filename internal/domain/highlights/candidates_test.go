@@ -11,20 +11,27 @@ import (
 func TestBuildCandidates_RespectsMaxClip(t *testing.T) {
 	tr := types.Transcript{Segments: []types.Segment{
 		{Start: 0, End: 40, Text: "A"},
-		{Start: 40, End: 90, Text: "B"},
+		{Start: 40, End: 50, Text: "B"},
+		{Start: 50, End: 90, Text: "C"},
 	}}
-	min, max := DurationBounds()
-	cands := BuildCandidates(tr)
-	if len(cands) == 0 {
-		t.Fatalf("expected candidates")
-	}
-	for _, c := range cands {
-		if c.End-c.Start < min {
-			t.Fatalf("candidate under min: %v", c.End-c.Start)
-		}
-		if c.End-c.Start > max {
-			t.Fatalf("candidate exceeds max: %v", c.End-c.Start)
-		}
+	for _, d := range []types.ClipDurations{
+		DefaultDurations(),
+		{Min: 5 * time.Second, Max: 45 * time.Second},
+	} {
+		t.Run(fmt.Sprintf("%s-%s", d.Min, d.Max), func(t *testing.T) {
+			cands := BuildCandidates(tr, d)
+			if len(cands) == 0 {
+				t.Fatalf("expected candidates")
+			}
+			for _, c := range cands {
+				if c.End-c.Start < d.Min {
+					t.Fatalf("candidate under min: %v", c.End-c.Start)
+				}
+				if c.End-c.Start > d.Max {
+					t.Fatalf("candidate exceeds max: %v", c.End-c.Start)
+				}
+			}
+		})
 	}
 }
 
@@ -47,7 +54,7 @@ func TestBuildCandidates_CoversLateTranscriptParts(t *testing.T) {
 		},
 	}
 
-	cands := BuildCandidates(tr)
+	cands := BuildCandidates(tr, DefaultDurations())
 	if len(cands) == 0 {
 		t.Fatalf("expected candidates")
 	}
@@ -63,3 +70,40 @@ func TestBuildCandidates_CoversLateTranscriptParts(t *testing.T) {
 		t.Fatalf("expected candidates from later timeline, got %d candidates", len(cands))
 	}
 }
+
+func TestBuildCandidates_SpeakerTurns(t *testing.T) {
+	d := types.ClipDurations{Min: time.Second, Max: 10 * time.Second}
+	diarized := types.Transcript{Segments: []types.Segment{
+		{Start: 0, End: 2, Text: "So why now?", Speaker: "S1", Words: []types.Word{
+			{Start: 0, End: 0.5, Word: "So"}, {Start: 0.5, End: 1, Word: "why"}, {Start: 1, End: 2, Word: "now?"},
+		}},
+		{Start: 2, End: 4, Text: "Rates fell.", Speaker: "S2", Words: []types.Word{
+			{Start: 2, End: 3, Word: "Rates"}, {Start: 3, End: 4, Word: "fell."},
+		}},
+	}}
+	segmentsOnly := types.Transcript{Segments: []types.Segment{
+		{Start: 0, End: 2, Text: "So why now?", Speaker: "S1"},
+		{Start: 2, End: 4, Text: "Rates fell.", Speaker: "S2"},
+	}}
+	want := []types.Turn{{Speaker: "S1", Text: "So why now?"}, {Speaker: "S2", Text: "Rates fell."}}
+	for name, tr := range map[string]types.Transcript{"words": diarized, "segments": segmentsOnly} {
+		var found bool
+		for _, c := range BuildCandidates(tr, d) {
+			if c.Start == 0 && c.End == 4*time.Second {
+				found = true
+				if fmt.Sprint(c.Turns) != fmt.Sprint(want) {
+					t.Fatalf("%s: turns = %+v, want %+v", name, c.Turns, want)
+				}
+			}
+		}
+		if !found {
+			t.Fatalf("%s: expected a candidate spanning both speakers", name)
+		}
+	}
+
+	for _, c := range BuildCandidates(types.Transcript{Segments: []types.Segment{{Start: 0, End: 4, Text: "No labels here."}}}, d) {
+		if c.Turns != nil {
+			t.Fatalf("expected no turns without speakers, got %+v", c.Turns)
+		}
+	}
+}
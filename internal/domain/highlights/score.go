@@ -3,6 +3,7 @@ package highlights
 import (
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 var (
@@ -47,3 +48,13 @@ func clamp(x, a, b float64) float64 {
 	}
 	return x
 }
+
+// Emphasized reports whether a single transcript word is one of the tokens
+// Score rewards: a number or a hook word. Surrounding punctuation is ignored.
+func Emphasized(word string) bool {
+	w := strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	if w == "" {
+		return false
+	}
+	return reNum.MatchString(w) || reHook.MatchString(w)
+}
@@ -29,3 +29,19 @@ func TestScore_Table(t *testing.T) {
 		})
 	}
 }
+
+func TestEmphasized(t *testing.T) {
+	for word, want := range map[string]bool{
+		"42":         true,
+		"3.5,":       true,
+		"Never!":     true,
+		"important.": true,
+		"keys":       false,
+		"hello":      false,
+		"...":        false,
+	} {
+		if got := Emphasized(word); got != want {
+			t.Errorf("Emphasized(%q) = %t, want %t", word, got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package highlights
+
+import (
+	"sort"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// minClipGap keeps selected clips apart so they read as distinct scenes.
+const minClipGap = 2 * time.Second
+
+// Pick is a candidate chosen by SelectBest together with its normalized range.
+type Pick struct {
+	Candidate types.Candidate
+	Start     time.Duration
+	End       time.Duration
+}
+
+// SelectBest picks up to clipsN non-overlapping clips, highest combined
+// info+hook score first. Each range is normalized with NormalizeClip; picks are
+// returned in score order.
+func SelectBest(
+	cands []types.Candidate,
+	clipsN int,
+	minClip, maxClip time.Duration,
+	timing Timing,
+) []Pick {
+	if clipsN <= 0 {
+		return nil
+	}
+
+	best := make([]types.Candidate, len(cands))
+	copy(best, cands)
+	sort.SliceStable(best, func(i, j int) bool {
+		s1 := best[i].InfoScore + best[i].HookScore
+		s2 := best[j].InfoScore + best[j].HookScore
+		if s1 == s2 {
+			return best[i].Start < best[j].Start
+		}
+		return s1 > s2
+	})
+
+	out := make([]Pick, 0, clipsN)
+	for _, c := range best {
+		if len(out) >= clipsN {
+			break
+		}
+		st, en, ok := NormalizeClip(c.Start, c.End, minClip, maxClip, timing)
+		if !ok {
+			continue
+		}
+		if overlapsPick(out, st, en) {
+			continue
+		}
+		out = append(out, Pick{Candidate: c, Start: st, End: en})
+	}
+	return out
+}
+
+func overlapsPick(existing []Pick, st, en time.Duration) bool {
+	for _, e := range existing {
+		if st < e.End+minClipGap && en > e.Start-minClipGap {
+			return true
+		}
+	}
+	return false
+}
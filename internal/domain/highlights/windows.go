@@ -0,0 +1,54 @@
+package highlights
+
+import (
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Window is a contiguous span of the transcript used for chunked selection.
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+	// Transcript holds the segments of the window, with original timestamps.
+	Transcript types.Transcript
+}
+
+// Contains reports whether t falls inside the window.
+func (w Window) Contains(t time.Duration) bool {
+	return t >= w.Start && t < w.End
+}
+
+// SplitWindows cuts the transcript into windows of roughly size, breaking only
+// at segment boundaries so no sentence is split. A trailing window shorter
+// than half of size is merged into the previous one.
+func SplitWindows(tr types.Transcript, size time.Duration) []Window {
+	if len(tr.Segments) == 0 || size <= 0 {
+		return nil
+	}
+	var out []Window
+	cur := Window{Start: dur(tr.Segments[0].Start)}
+	for _, s := range tr.Segments {
+		st := dur(s.Start)
+		if len(cur.Transcript.Segments) > 0 && st-cur.Start >= size {
+			out = append(out, cur)
+			cur = Window{Start: st}
+		}
+		cur.Transcript.Segments = append(cur.Transcript.Segments, s)
+		if en := dur(s.End); en > cur.End {
+			cur.End = en
+		}
+	}
+	if len(out) > 0 && cur.End-cur.Start < size/2 {
+		last := &out[len(out)-1]
+		last.Transcript.Segments = append(last.Transcript.Segments, cur.Transcript.Segments...)
+		last.End = cur.End
+	} else {
+		out = append(out, cur)
+	}
+	// Windows tile the timeline: each ends where the next begins.
+	for i := 0; i+1 < len(out); i++ {
+		out[i].End = out[i+1].Start
+	}
+	return out
+}
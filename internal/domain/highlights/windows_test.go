@@ -0,0 +1,44 @@
+package highlights
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func TestSplitWindows_BreaksAtSegmentsAndMergesShortTail(t *testing.T) {
+	var tr types.Transcript
+	for i := 0; i < 10; i++ {
+		tr.Segments = append(tr.Segments, types.Segment{Start: float64(i * 60), End: float64(i*60 + 60), Text: "x"})
+	}
+
+	ws := SplitWindows(tr, 3*time.Minute)
+	if len(ws) != 3 {
+		t.Fatalf("expected 3 windows, got %d", len(ws))
+	}
+	want := [][2]time.Duration{
+		{0, 3 * time.Minute},
+		{3 * time.Minute, 6 * time.Minute},
+		{6 * time.Minute, 10 * time.Minute},
+	}
+	total := 0
+	for i, w := range ws {
+		if w.Start != want[i][0] || w.End != want[i][1] {
+			t.Fatalf("window %d = [%v, %v), want [%v, %v)", i, w.Start, w.End, want[i][0], want[i][1])
+		}
+		total += len(w.Transcript.Segments)
+	}
+	if total != len(tr.Segments) {
+		t.Fatalf("windows hold %d segments, want %d", total, len(tr.Segments))
+	}
+	if !ws[1].Contains(3*time.Minute) || ws[0].Contains(3*time.Minute) {
+		t.Fatalf("window bounds should be half-open")
+	}
+}
+
+func TestSplitWindows_Empty(t *testing.T) {
+	if ws := SplitWindows(types.Transcript{}, time.Minute); ws != nil {
+		t.Fatalf("expected no windows, got %v", ws)
+	}
+}
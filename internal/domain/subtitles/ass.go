@@ -8,29 +8,80 @@ import (
 	"github.com/forPelevin/hlcut/internal/types"
 )
 
+// Options describe the video the subtitles are burned into and how they look.
+type Options struct {
+	// Width and Height of the output frame; zero means 1920x1080.
+	Width  int
+	Height int
+	// Preset names a built-in style (see Presets); empty means tiktok. It is
+	// ignored when Sheet is set.
+	Preset string
+	// Sheet is a style file loaded with ParseStyleSheet.
+	Sheet *StyleSheet
+	// Title is shown as a banner for the whole clip when the style has a
+	// Title style, as the top-title preset does.
+	Title string
+	// Mode is CaptionKaraoke (default) or CaptionPop.
+	Mode string
+	// Keywords are emphasized in pop mode besides numbers and hook words,
+	// e.g. the keywords the LLM picked for the clip.
+	Keywords []string
+}
+
+// RenderTikTokASS renders karaoke subtitles for a 1920x1080 frame.
 func RenderTikTokASS(tr types.Transcript, start, end time.Duration) (string, error) {
+	return RenderASS(tr, start, end, Options{})
+}
+
+// RenderASS renders karaoke subtitles laid out for the output frame in opts.
+func RenderASS(tr types.Transcript, start, end time.Duration, opts Options) (string, error) {
+	ss, err := resolveStyles(opts)
+	if err != nil {
+		return "", err
+	}
+	ss.addSpeakers(tr.Speakers())
+	mode, err := ParseCaptionMode(opts.Mode)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(assHeader(ss))
+	b.WriteString("\n[Events]\n")
+	b.WriteString("Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	if ss.title != nil && strings.TrimSpace(opts.Title) != "" {
+		fmt.Fprintf(&b, "Dialogue: 1,%s,%s,%s,,0,0,0,,%s\n", assTime(0), assTime(end-start), ss.title.Name, sanitizeASS(opts.Title))
+	}
 	words := collectWords(tr, start, end)
 	if len(words) == 0 {
 		// Fallback keeps subtitle rendering robust when ASR has segment text but
 		// no usable per-word timestamps.
 		text := collectSegmentText(tr, start, end)
-		return renderASSPlain(text, end-start), nil
+		writeASSPlain(&b, ss.caption.Name, text, end-start)
+		return b.String(), nil
+	}
+	if mode == CaptionPop {
+		writeASSPop(&b, ss, groupWords(words), newEmphasis(opts.Keywords))
+		return b.String(), nil
 	}
 	// Karaoke mode is preferred for readability and pacing in short-form clips.
-	lines := packWords(words)
-	return renderASSKaraoke(lines), nil
+	lines := packWords(words, ss.charBudget())
+	writeASSKaraoke(&b, ss, lines)
+	return b.String(), nil
 }
 
 type wword struct {
-	Start time.Duration
-	End   time.Duration
-	Text  string
+	Start   time.Duration
+	End     time.Duration
+	Text    string
+	Speaker string
 }
 
+// line is one caption event; its words share one speaker.
 type line struct {
-	Start time.Duration
-	End   time.Duration
-	Words []wword
+	Start   time.Duration
+	End     time.Duration
+	Words   []wword
+	Speaker string
 }
 
 func collectWords(tr types.Transcript, start, end time.Duration) []wword {
@@ -54,7 +105,8 @@ func collectWords(tr types.Transcript, start, end time.Duration) []wword {
 			}
 			// Event times are normalized to clip-local offsets because renderer
 			// operates on per-clip subtitle files, not full-timeline subtitles.
-			out = append(out, wword{Start: ws - start, End: we - start, Text: sanitizeASS(text)})
+			// Text stays raw; each format escapes it when writing.
+			out = append(out, wword{Start: ws - start, End: we - start, Text: text, Speaker: types.WordSpeaker(s, w)})
 		}
 	}
 	return out
@@ -75,12 +127,11 @@ func collectSegmentText(tr types.Transcript, start, end time.Duration) string {
 	return strings.Join(parts, " ")
 }
 
-func packWords(words []wword) []line {
+func packWords(words []wword, charBudget int) []line {
 	var out []line
-	cur := line{Start: words[0].Start}
+	cur := line{Start: words[0].Start, Speaker: words[0].Speaker}
 	// Hard budgets trade exact transcript grouping for consistently readable
 	// subtitle chunks on vertical-video layouts.
-	charBudget := 42
 	wordBudget := 9
 	curLen := 0
 	for i, w := range words {
@@ -90,10 +141,10 @@ func packWords(words []wword) []line {
 			nextLen++
 		}
 		nextLen += wl
-		if len(cur.Words) >= wordBudget || nextLen > charBudget {
+		if len(cur.Words) >= wordBudget || nextLen > charBudget || w.Speaker != cur.Speaker {
 			cur.End = cur.Words[len(cur.Words)-1].End
 			out = append(out, cur)
-			cur = line{Start: w.Start}
+			cur = line{Start: w.Start, Speaker: w.Speaker}
 			curLen = 0
 		}
 		cur.Words = append(cur.Words, w)
@@ -109,54 +160,91 @@ func packWords(words []wword) []line {
 	return out
 }
 
-func renderASSKaraoke(lines []line) string {
-	var b strings.Builder
-	b.WriteString(assHeader())
-	b.WriteString("\n[Events]\n")
-	b.WriteString("Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+func writeASSKaraoke(b *strings.Builder, ss styleSet, lines []line) {
 	for _, ln := range lines {
 		b.WriteString("Dialogue: 0,")
 		b.WriteString(assTime(ln.Start))
 		b.WriteString(",")
 		b.WriteString(assTime(ln.End))
-		b.WriteString(",TikTok,,0,0,0,,")
+		b.WriteString("," + ss.captionFor(ln.Speaker).Name + ",,0,0,0,,")
 		for _, w := range ln.Words {
 			durCS := int((w.End - w.Start) / (10 * time.Millisecond))
 			if durCS < 1 {
 				durCS = 1
 			}
-			b.WriteString(fmt.Sprintf("{\\k%d}%s ", durCS, w.Text))
+			b.WriteString(fmt.Sprintf("{\\k%d}%s ", durCS, sanitizeASS(w.Text)))
 		}
 		b.WriteString("\n")
 	}
-	return b.String()
 }
 
-func renderASSPlain(text string, dur time.Duration) string {
-	var b strings.Builder
-	b.WriteString(assHeader())
-	b.WriteString("\n[Events]\n")
-	b.WriteString("Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+func writeASSPlain(b *strings.Builder, style, text string, dur time.Duration) {
 	b.WriteString("Dialogue: 0,0:00:00.00,")
 	b.WriteString(assTime(dur))
-	b.WriteString(",TikTok,,0,0,0,,")
+	b.WriteString("," + style + ",,0,0,0,,")
 	b.WriteString(sanitizeASS(text))
 	b.WriteString("\n")
-	return b.String()
 }
 
-func assHeader() string {
-	return strings.TrimSpace(`
+// layout is the reference subtitle geometry for one output frame, which the
+// built-in presets derive their sizes from.
+type layout struct {
+	width, height    int
+	fontSize         int
+	outline, shadow  int
+	marginH, marginV int
+}
+
+// newLayout scales the 1920x1080 reference style to the output frame: text is
+// sized from the short side, so it stays readable on vertical video, and
+// tall frames lift captions clear of the platform UI at the bottom.
+func newLayout(opts Options) layout {
+	w, h := opts.Width, opts.Height
+	if w <= 0 || h <= 0 {
+		w, h = 1920, 1080
+	}
+	l := layout{width: w, height: h}
+	l.fontSize = max(1, (min(w, h)*78+540)/1080)
+	l.outline = max(1, (l.fontSize+6)/13)
+	l.shadow = max(1, (l.fontSize+19)/39)
+	switch {
+	case w >= h:
+		l.marginH = w / 24
+		l.marginV = h * 85 / 1080
+	case 2*h >= 3*w: // 9:16 and similar
+		l.marginH = w / 16
+		l.marginV = h / 5
+	default: // 4:5 and similar
+		l.marginH = w / 16
+		l.marginV = h / 10
+	}
+	return l
+}
+
+// tall reports whether the frame is portrait, where platform UI covers the
+// top and bottom of the video.
+func (l layout) tall() bool { return 2*l.height >= 3*l.width }
+
+func assHeader(ss styleSet) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, strings.TrimSpace(`
 [Script Info]
 ScriptType: v4.00+
-PlayResX: 1920
-PlayResY: 1080
+PlayResX: %d
+PlayResY: %d
 ScaledBorderAndShadow: yes
 
 [V4+ Styles]
 Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
-Style: TikTok, Inter, 78, &H00FFFFFF, &H00FFD200, &H00000000, &H64000000, 1,0,0,0,100,100,0,0,1,6,2,2, 80,80,85,1
-`)
+`), ss.width, ss.height)
+	b.WriteString("\n" + ss.caption.line())
+	for _, sp := range ss.speakers {
+		b.WriteString("\n" + sp.style.line())
+	}
+	if ss.title != nil {
+		b.WriteString("\n" + ss.title.line())
+	}
+	return b.String()
 }
 
 func assTime(d time.Duration) string {
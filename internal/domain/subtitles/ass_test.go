@@ -2,6 +2,7 @@ package subtitles
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -55,3 +56,68 @@ func TestRenderTikTokASS_DoesNotDropTailWords(t *testing.T) {
 		t.Fatalf("expected multiple dialogue lines instead of truncation, got:\n%s", ass)
 	}
 }
+
+func TestRenderASS_FollowsOutputGeometry(t *testing.T) {
+	words := make([]types.Word, 0, 12)
+	for i := 0; i < 12; i++ {
+		st := float64(i) * 0.4
+		words = append(words, types.Word{Start: st, End: st + 0.35, Word: fmt.Sprintf("word%02d", i)})
+	}
+	tr := types.Transcript{Segments: []types.Segment{{Start: 0, End: 5, Words: words}}}
+
+	wide, err := RenderTikTokASS(tr, 0, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tall, err := RenderASS(tr, 0, 5*time.Second, Options{Width: 1080, Height: 1920})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(wide, "PlayResX: 1920\nPlayResY: 1080") || !strings.Contains(wide, "80,80,85,1") {
+		t.Fatalf("expected reference 1920x1080 layout, got:\n%s", wide)
+	}
+	if !strings.Contains(tall, "PlayResX: 1080\nPlayResY: 1920") {
+		t.Fatalf("expected PlayRes to follow the frame, got:\n%s", tall)
+	}
+	if !strings.Contains(tall, "67,67,384,1") {
+		t.Fatalf("expected vertical margins, got:\n%s", tall)
+	}
+	// The narrower frame fits fewer words per line.
+	if strings.Count(tall, "Dialogue:") <= strings.Count(wide, "Dialogue:") {
+		t.Fatalf("expected shorter lines on a vertical frame:\n%s", tall)
+	}
+}
+
+func TestRenderASS_SpeakerStyles(t *testing.T) {
+	tr := types.Transcript{Segments: []types.Segment{
+		{Start: 0, End: 1, Speaker: "S1", Words: []types.Word{{Start: 0, End: 0.4, Word: "So"}, {Start: 0.4, End: 0.9, Word: "why?"}}},
+		{Start: 1, End: 2, Speaker: "S2", Words: []types.Word{{Start: 1, End: 1.5, Word: "Because"}, {Start: 1.5, End: 2, Word: "money."}}},
+	}}
+	for _, mode := range []string{CaptionKaraoke, CaptionPop} {
+		ass, err := RenderASS(tr, 0, 2*time.Second, Options{Mode: mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(ass, "Style: TikTok-S2, Inter, 78, &H0090EE90,") {
+			t.Fatalf("%s: expected a style for the second speaker, got:\n%s", mode, ass)
+		}
+		var styles []string
+		for _, ln := range strings.Split(ass, "\n") {
+			if strings.HasPrefix(ln, "Dialogue:") {
+				styles = append(styles, strings.Split(ln, ",")[3])
+			}
+		}
+		if want := []string{"TikTok", "TikTok-S2"}; !slices.Equal(styles, want) {
+			t.Fatalf("%s: dialogue styles = %v, want %v", mode, styles, want)
+		}
+	}
+
+	single, err := RenderTikTokASS(types.Transcript{Segments: tr.Segments[:1]}, 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(single, "TikTok-S") {
+		t.Fatalf("a single speaker should keep the caption style, got:\n%s", single)
+	}
+}
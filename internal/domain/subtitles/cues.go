@@ -0,0 +1,96 @@
+package subtitles
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Soft-caption formats share the ASS line packing, so sidecars show the same
+// lines as burned captions; only the karaoke timing is dropped.
+
+// RenderSRT renders the clip's captions as SubRip cues.
+func RenderSRT(tr types.Transcript, start, end time.Duration, opts Options) (string, error) {
+	var b strings.Builder
+	for i, c := range collectCues(tr, start, end, opts) {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, srtTime(c.Start), srtTime(c.End), c.Text)
+	}
+	return b.String(), nil
+}
+
+// RenderVTT renders the clip's captions as WebVTT cues.
+func RenderVTT(tr types.Transcript, start, end time.Duration, opts Options) (string, error) {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, c := range collectCues(tr, start, end, opts) {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", vttTime(c.Start), vttTime(c.End), sanitizeVTT(c.Text))
+	}
+	return b.String(), nil
+}
+
+type cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// collectCues packs timed words into lines like the ASS renderer. Without
+// word timestamps it falls back to one cue per segment.
+func collectCues(tr types.Transcript, start, end time.Duration, opts Options) []cue {
+	words := collectWords(tr, start, end)
+	if len(words) == 0 {
+		return segmentCues(tr, start, end)
+	}
+	budget := 42
+	if ss, err := resolveStyles(opts); err == nil {
+		budget = ss.charBudget()
+	}
+	var out []cue
+	for _, ln := range packWords(words, budget) {
+		parts := make([]string, len(ln.Words))
+		for i, w := range ln.Words {
+			parts[i] = w.Text
+		}
+		out = append(out, cue{Start: ln.Start, End: ln.End, Text: strings.Join(parts, " ")})
+	}
+	return out
+}
+
+func segmentCues(tr types.Transcript, start, end time.Duration) []cue {
+	var out []cue
+	for _, s := range tr.Segments {
+		ss, se := dur(s.Start), dur(s.End)
+		text := strings.TrimSpace(s.Text)
+		if se <= start || ss >= end || text == "" {
+			continue
+		}
+		out = append(out, cue{Start: max(ss, start) - start, End: min(se, end) - start, Text: text})
+	}
+	return out
+}
+
+func srtTime(d time.Duration) string {
+	h, m, s, ms := splitTime(d)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", h, m, s, ms)
+}
+
+func vttTime(d time.Duration) string {
+	h, m, s, ms := splitTime(d)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, ms)
+}
+
+func splitTime(d time.Duration) (h, m, s, ms int) {
+	if d < 0 {
+		d = 0
+	}
+	total := int(d / time.Millisecond)
+	return total / 3600000, total / 60000 % 60, total / 1000 % 60, total % 1000
+}
+
+// sanitizeVTT escapes the characters WebVTT cue text reserves for markup; this
+// also keeps a literal "-->" from ending the cue.
+func sanitizeVTT(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,65 @@
+package subtitles
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func TestRenderSRTAndVTT_ShareLinePacking(t *testing.T) {
+	tr := types.Transcript{Segments: []types.Segment{
+		{Start: 10, End: 13, Words: []types.Word{
+			{Start: 10.0, End: 10.5, Word: "Fish"},
+			{Start: 10.5, End: 11.0, Word: "&"},
+			{Start: 11.0, End: 12.25, Word: "<chips>"},
+		}},
+	}}
+
+	srt, err := RenderSRT(tr, 10*time.Second, 13*time.Second, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSRT := "1\n00:00:00,000 --> 00:00:02,250\nFish & <chips>\n\n"
+	if srt != wantSRT {
+		t.Fatalf("unexpected SRT:\n%q\nwant\n%q", srt, wantSRT)
+	}
+
+	vtt, err := RenderVTT(tr, 10*time.Second, 13*time.Second, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantVTT := "WEBVTT\n\n00:00:00.000 --> 00:00:02.250\nFish &amp; &lt;chips&gt;\n\n"
+	if vtt != wantVTT {
+		t.Fatalf("unexpected VTT:\n%q\nwant\n%q", vtt, wantVTT)
+	}
+}
+
+func TestRenderSRT_FallsBackToSegments(t *testing.T) {
+	tr := types.Transcript{Segments: []types.Segment{
+		{Start: 0, End: 4, Text: " first "},
+		{Start: 4, End: 9, Text: "second"},
+	}}
+	srt, err := RenderSRT(tr, 2*time.Second, 6*time.Second, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(srt, "1\n00:00:00,000 --> 00:00:02,000\nfirst\n") ||
+		!strings.Contains(srt, "2\n00:00:02,000 --> 00:00:04,000\nsecond\n") {
+		t.Fatalf("expected one cue per segment clipped to the range, got:\n%s", srt)
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	got, err := ParseFormats([]string{" SRT", "webvtt", "srt", "", "ass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "srt,vtt,ass" {
+		t.Fatalf("unexpected formats: %v", got)
+	}
+	if _, err := ParseFormats([]string{"sub"}); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
@@ -0,0 +1,53 @@
+package subtitles
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Subtitle file formats, named by their file extension.
+const (
+	FormatASS = "ass"
+	FormatSRT = "srt"
+	FormatVTT = "vtt"
+)
+
+// ParseFormats normalizes a list of format names (case-insensitive, "webvtt"
+// accepted for vtt), dropping duplicates and keeping the order given.
+func ParseFormats(names []string) ([]string, error) {
+	var out []string
+	for _, n := range names {
+		f := strings.ToLower(strings.TrimSpace(n))
+		switch f {
+		case "":
+			continue
+		case "webvtt":
+			f = FormatVTT
+		case FormatASS, FormatSRT, FormatVTT:
+		default:
+			return nil, fmt.Errorf("unknown subtitle format %q (want %s, %s or %s)", n, FormatASS, FormatSRT, FormatVTT)
+		}
+		if !slices.Contains(out, f) {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// Render renders the clip's subtitles in the given format.
+func Render(format string, tr types.Transcript, start, end time.Duration, opts Options) (string, error) {
+	switch format {
+	case FormatASS:
+		return RenderASS(tr, start, end, opts)
+	case FormatSRT:
+		return RenderSRT(tr, start, end, opts)
+	case FormatVTT:
+		return RenderVTT(tr, start, end, opts)
+	default:
+		return "", fmt.Errorf("unknown subtitle format %q", format)
+	}
+}
@@ -0,0 +1,147 @@
+package subtitles
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+)
+
+// Caption modes of the burned ASS captions.
+const (
+	// CaptionKaraoke shows full packed lines with a \k sweep per word.
+	CaptionKaraoke = "karaoke"
+	// CaptionPop shows one to three words at a time, popping in, with
+	// emphasized words enlarged and colored.
+	CaptionPop = "pop"
+)
+
+// ParseCaptionMode normalizes a caption mode name; empty means karaoke.
+func ParseCaptionMode(s string) (string, error) {
+	switch m := strings.ToLower(strings.TrimSpace(s)); m {
+	case "", CaptionKaraoke:
+		return CaptionKaraoke, nil
+	case CaptionPop:
+		return CaptionPop, nil
+	default:
+		return "", fmt.Errorf("unknown caption mode %q (want %s or %s)", s, CaptionKaraoke, CaptionPop)
+	}
+}
+
+const (
+	popMaxWords = 3
+	popMaxChars = 18
+	// popMaxGap ends a group at a pause, so words are not shown early.
+	popMaxGap = 400 * time.Millisecond
+	// popHold keeps a group on screen into a short pause instead of
+	// flashing an empty frame before the next one.
+	popHold = 300 * time.Millisecond
+	// popFallbackAccent is gold, used when the style has no distinct
+	// secondary color.
+	popFallbackAccent = "&H0000D7FF"
+)
+
+// groupWords splits words into the short groups of pop mode: at most three
+// words and popMaxChars characters, broken after punctuation, at pauses and
+// where the speaker changes.
+func groupWords(words []wword) []line {
+	var out []line
+	var cur line
+	chars := 0
+	for i, w := range words {
+		n := len([]rune(w.Text))
+		if len(cur.Words) > 0 && chars+1+n > popMaxChars {
+			out = append(out, cur)
+			cur, chars = line{}, 0
+		}
+		if len(cur.Words) == 0 {
+			cur.Start, cur.Speaker = w.Start, w.Speaker
+		} else {
+			chars++
+		}
+		cur.Words = append(cur.Words, w)
+		cur.End = w.End
+		chars += n
+
+		last := i == len(words)-1
+		if last || len(cur.Words) >= popMaxWords || endsPhrase(w.Text) ||
+			words[i+1].Start-w.End > popMaxGap || words[i+1].Speaker != w.Speaker {
+			out = append(out, cur)
+			cur, chars = line{}, 0
+		}
+	}
+	// Hold each group until the next one unless the pause is long.
+	for i := 0; i+1 < len(out); i++ {
+		if next := out[i+1].Start; next-out[i].End <= popHold {
+			out[i].End = next
+		}
+	}
+	return out
+}
+
+func endsPhrase(word string) bool {
+	r := []rune(word)
+	return strings.ContainsRune(".,!?;:", r[len(r)-1])
+}
+
+// emphasis decides which words pop mode enlarges: numbers and hook words (as
+// scored by highlights.Score) and the clip's keywords.
+type emphasis map[string]bool
+
+func newEmphasis(keywords []string) emphasis {
+	e := emphasis{}
+	for _, k := range keywords {
+		for _, w := range strings.Fields(k) {
+			// Short words of keyword phrases ("the", "of") are not worth it.
+			if w = normWord(w); len([]rune(w)) >= 3 {
+				e[w] = true
+			}
+		}
+	}
+	return e
+}
+
+func (e emphasis) has(word string) bool {
+	return highlights.Emphasized(word) || e[normWord(word)]
+}
+
+func normWord(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }))
+}
+
+// writeASSPop writes one Dialogue per word group. Every word scales in from
+// 80%; emphasized words grow to 125% in the accent color.
+func writeASSPop(b *strings.Builder, ss styleSet, groups []line, emph emphasis) {
+	for _, g := range groups {
+		st := ss.captionFor(g.Speaker)
+		accent := st.SecondaryColour
+		if accent == st.PrimaryColour {
+			accent = popFallbackAccent
+		}
+		fmt.Fprintf(b, "Dialogue: 0,%s,%s,%s,,0,0,0,,", assTime(g.Start), assTime(g.End), st.Name)
+		for i, w := range g.Words {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			if emph.has(w.Text) {
+				fmt.Fprintf(b, `{\1c%s\fscx90\fscy90\t(0,120,\fscx125\fscy125)}%s{\1c%s}`,
+					inlineColor(accent), sanitizeASS(w.Text), inlineColor(st.PrimaryColour))
+				continue
+			}
+			fmt.Fprintf(b, `{\fscx80\fscy80\t(0,100,\fscx100\fscy100)}%s`, sanitizeASS(w.Text))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// inlineColor turns a style color (&HAABBGGRR) into the &HBBGGRR& form of
+// the \1c override tag.
+func inlineColor(c string) string {
+	hex := strings.TrimPrefix(c, "&H")
+	if len(hex) > 6 {
+		hex = hex[len(hex)-6:]
+	}
+	return "&H" + hex + "&"
+}
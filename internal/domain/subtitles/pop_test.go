@@ -0,0 +1,66 @@
+package subtitles
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func TestRenderASS_PopGroupsAndEmphasis(t *testing.T) {
+	tr := types.Transcript{Segments: []types.Segment{
+		{Start: 0, End: 6, Words: []types.Word{
+			{Start: 0.0, End: 0.3, Word: "The"},
+			{Start: 0.3, End: 0.7, Word: "secret"},
+			{Start: 0.7, End: 1.0, Word: "is"},
+			{Start: 1.0, End: 1.4, Word: "compound"},
+			{Start: 1.4, End: 1.9, Word: "interest."},
+			{Start: 2.0, End: 2.4, Word: "It"},
+			{Start: 2.4, End: 2.8, Word: "took"},
+			{Start: 4.0, End: 4.5, Word: "10"},
+			{Start: 4.5, End: 5.0, Word: "years"},
+		}},
+	}}
+	ass, err := RenderASS(tr, 0, 6*time.Second, Options{Mode: CaptionPop, Keywords: []string{"Compound interest"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, ln := range strings.Split(ass, "\n") {
+		if strings.HasPrefix(ln, "Dialogue:") {
+			got = append(got, ln)
+		}
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected 4 word groups, got %d:\n%s", len(got), ass)
+	}
+	pop := `{\fscx80\fscy80\t(0,100,\fscx100\fscy100)}`
+	emph := func(w string) string {
+		return `{\1c&HFFD200&\fscx90\fscy90\t(0,120,\fscx125\fscy125)}` + w + `{\1c&HFFFFFF&}`
+	}
+	want := []string{
+		"Dialogue: 0,0:00:00.00,0:00:01.00,TikTok,,0,0,0,," + pop + "The " + emph("secret") + " " + pop + "is",
+		"Dialogue: 0,0:00:01.00,0:00:02.00,TikTok,,0,0,0,," + emph("compound") + " " + emph("interest."),
+		// The pause before "10" is too long to hold "It took" over it.
+		"Dialogue: 0,0:00:02.00,0:00:02.80,TikTok,,0,0,0,," + pop + "It " + pop + "took",
+		"Dialogue: 0,0:00:04.00,0:00:05.00,TikTok,,0,0,0,," + emph("10") + " " + pop + "years",
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("group %d:\n got %s\nwant %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseCaptionMode(t *testing.T) {
+	if m, err := ParseCaptionMode(""); err != nil || m != CaptionKaraoke {
+		t.Fatalf("empty mode: %q, %v", m, err)
+	}
+	if m, err := ParseCaptionMode(" POP "); err != nil || m != CaptionPop {
+		t.Fatalf("pop mode: %q, %v", m, err)
+	}
+	if _, err := ParseCaptionMode("typewriter"); err == nil {
+		t.Fatal("expected unknown mode error")
+	}
+}
@@ -0,0 +1,464 @@
+package subtitles
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Style is one entry of an ASS [V4+ Styles] section. Colors are normalized to
+// &HAABBGGRR (alpha first, 00 is opaque).
+type Style struct {
+	Name            string
+	Fontname        string
+	Fontsize        float64
+	PrimaryColour   string
+	SecondaryColour string
+	OutlineColour   string
+	BackColour      string
+	Bold            int
+	Italic          int
+	Underline       int
+	StrikeOut       int
+	ScaleX          float64
+	ScaleY          float64
+	Spacing         float64
+	Angle           float64
+	// BorderStyle is 1 for outline and shadow, 3 for an opaque box.
+	BorderStyle int
+	Outline     float64
+	Shadow      float64
+	// Alignment uses numpad positions: 2 is bottom center, 8 top center.
+	Alignment int
+	MarginL   int
+	MarginR   int
+	MarginV   int
+	Encoding  int
+}
+
+func (s Style) line() string {
+	return fmt.Sprintf(
+		"Style: %s, %s, %s, %s, %s, %s, %s, %d,%d,%d,%d,%s,%s,%s,%s,%d,%s,%s,%d, %d,%d,%d,%d",
+		s.Name, s.Fontname, num(s.Fontsize),
+		s.PrimaryColour, s.SecondaryColour, s.OutlineColour, s.BackColour,
+		s.Bold, s.Italic, s.Underline, s.StrikeOut,
+		num(s.ScaleX), num(s.ScaleY), num(s.Spacing), num(s.Angle),
+		s.BorderStyle, num(s.Outline), num(s.Shadow), s.Alignment,
+		s.MarginL, s.MarginR, s.MarginV, s.Encoding,
+	)
+}
+
+// Built-in style presets.
+const (
+	PresetTikTok   = "tiktok"
+	PresetMinimal  = "minimal"
+	PresetBoxed    = "boxed"
+	PresetTopTitle = "top-title"
+)
+
+// Presets lists the built-in style presets.
+func Presets() []string {
+	return []string{PresetTikTok, PresetMinimal, PresetBoxed, PresetTopTitle}
+}
+
+// CheckPreset reports whether name is a built-in preset; empty is tiktok.
+func CheckPreset(name string) error {
+	if name == "" || slices.Contains(Presets(), name) {
+		return nil
+	}
+	return fmt.Errorf("unknown subtitle style %q (want %s or a .ass style file)", name, strings.Join(Presets(), ", "))
+}
+
+// styleSet is what one subtitle file is rendered with: the caption style, an
+// optional Title style for a banner over the whole clip and, in diarized
+// transcripts, a caption style per additional speaker.
+type styleSet struct {
+	width, height int
+	caption       Style
+	title         *Style
+	speakers      []speakerStyle
+}
+
+type speakerStyle struct {
+	speaker string
+	style   Style
+}
+
+// speakerColours tell the second and later speakers apart: light green,
+// pink, orange and light blue, repeating from the sixth speaker on.
+var speakerColours = []string{"&H0090EE90", "&H00CBC0FF", "&H0000A5FF", "&H00E6D8AD"}
+
+// addSpeakers gives every speaker after the first a copy of the caption
+// style in its own text color. The first speaker keeps the caption style,
+// so a single-speaker clip looks as it did without diarization.
+func (ss *styleSet) addSpeakers(speakers []string) {
+	if len(speakers) < 2 {
+		return
+	}
+	for i, sp := range speakers[1:] {
+		st := ss.caption
+		// Style names end at a comma.
+		st.Name = ss.caption.Name + "-" + strings.ReplaceAll(sp, ",", " ")
+		colour := speakerColours[i%len(speakerColours)]
+		if st.SecondaryColour == st.PrimaryColour {
+			// Styles without a karaoke sweep keep it that way.
+			st.SecondaryColour = colour
+		}
+		st.PrimaryColour = colour
+		ss.speakers = append(ss.speakers, speakerStyle{speaker: sp, style: st})
+	}
+}
+
+// captionFor is the caption style of speaker.
+func (ss styleSet) captionFor(speaker string) Style {
+	for _, sp := range ss.speakers {
+		if sp.speaker == speaker {
+			return sp.style
+		}
+	}
+	return ss.caption
+}
+
+// charBudget is how many characters fit on one caption line. A bold sans
+// glyph averages about 0.53em; at the reference geometry this gives the
+// 42-character lines the packer has always used.
+func (ss styleSet) charBudget() int {
+	st := ss.caption
+	usable := float64(ss.width - st.MarginL - st.MarginR)
+	em := st.Fontsize * st.ScaleX / 100
+	if usable <= 0 || em <= 0 {
+		return 12
+	}
+	return max(12, int(usable*100/(53*em)))
+}
+
+func resolveStyles(opts Options) (styleSet, error) {
+	lay := newLayout(opts)
+	if opts.Sheet != nil {
+		return opts.Sheet.scaled(lay.width, lay.height), nil
+	}
+	if err := CheckPreset(opts.Preset); err != nil {
+		return styleSet{}, err
+	}
+	ss := styleSet{width: lay.width, height: lay.height, caption: tiktokStyle(lay)}
+	switch opts.Preset {
+	case PresetMinimal:
+		st := &ss.caption
+		st.Name = "Minimal"
+		st.Fontsize = math.Round(float64(lay.fontSize) * 0.85)
+		st.SecondaryColour = st.PrimaryColour
+		st.BackColour = "&H00000000"
+		st.Bold = 0
+		st.Outline = float64(max(1, lay.fontSize/26))
+		st.Shadow = 0
+	case PresetBoxed:
+		st := &ss.caption
+		st.Name = "Boxed"
+		st.SecondaryColour = "&H0000D7FF"
+		// With BorderStyle 3 the outline color fills the box and the outline
+		// width pads it.
+		st.OutlineColour = "&H60000000"
+		st.BackColour = "&H60000000"
+		st.BorderStyle = 3
+		st.Outline = float64(max(2, lay.fontSize/5))
+		st.Shadow = 0
+	case PresetTopTitle:
+		title := tiktokStyle(lay)
+		title.Name = "Title"
+		title.Fontsize = math.Round(float64(lay.fontSize) * 0.8)
+		title.SecondaryColour = title.PrimaryColour
+		title.OutlineColour = "&H60000000"
+		title.BackColour = "&H60000000"
+		title.BorderStyle = 3
+		title.Outline = float64(max(2, lay.fontSize/5))
+		title.Shadow = 0
+		title.Alignment = 8
+		title.MarginV = lay.height * 6 / 100
+		if lay.tall() {
+			title.MarginV = lay.height * 12 / 100
+		}
+		ss.title = &title
+	}
+	return ss, nil
+}
+
+// tiktokStyle is the default look: bold white Inter with a karaoke sweep,
+// outlined, bottom center.
+func tiktokStyle(l layout) Style {
+	return Style{
+		Name:            "TikTok",
+		Fontname:        "Inter",
+		Fontsize:        float64(l.fontSize),
+		PrimaryColour:   "&H00FFFFFF",
+		SecondaryColour: "&H00FFD200",
+		OutlineColour:   "&H00000000",
+		BackColour:      "&H64000000",
+		Bold:            1,
+		ScaleX:          100,
+		ScaleY:          100,
+		BorderStyle:     1,
+		Outline:         float64(l.outline),
+		Shadow:          float64(l.shadow),
+		Alignment:       2,
+		MarginL:         l.marginH,
+		MarginR:         l.marginH,
+		MarginV:         l.marginV,
+		Encoding:        1,
+	}
+}
+
+// StyleSheet is a caption style loaded from an .ass file. The first style is
+// used for captions; a style named Title, if any, adds a title banner.
+type StyleSheet struct {
+	Caption Style
+	Title   *Style
+	// PlayResX and PlayResY are the frame the sizes were designed for; when
+	// set, sizes are scaled to the output frame, otherwise used as-is.
+	PlayResX int
+	PlayResY int
+}
+
+func (s StyleSheet) scaled(w, h int) styleSet {
+	ss := styleSet{width: w, height: h, caption: s.Caption}
+	if s.Title != nil {
+		t := *s.Title
+		ss.title = &t
+	}
+	if s.PlayResX <= 0 || s.PlayResY <= 0 {
+		return ss
+	}
+	fx := float64(w) / float64(s.PlayResX)
+	fy := float64(h) / float64(s.PlayResY)
+	scale := func(st *Style) {
+		st.Fontsize = math.Round(st.Fontsize * fy)
+		st.Spacing = math.Round(st.Spacing * fx)
+		st.Outline = math.Round(st.Outline * fy)
+		st.Shadow = math.Round(st.Shadow * fy)
+		st.MarginL = int(math.Round(float64(st.MarginL) * fx))
+		st.MarginR = int(math.Round(float64(st.MarginR) * fx))
+		st.MarginV = int(math.Round(float64(st.MarginV) * fy))
+	}
+	scale(&ss.caption)
+	if ss.title != nil {
+		scale(ss.title)
+	}
+	return ss
+}
+
+// styleFields are the columns of a V4+ Styles Format line.
+var styleFields = []string{
+	"Name", "Fontname", "Fontsize", "PrimaryColour", "SecondaryColour", "OutlineColour", "BackColour",
+	"Bold", "Italic", "Underline", "StrikeOut", "ScaleX", "ScaleY", "Spacing", "Angle",
+	"BorderStyle", "Outline", "Shadow", "Alignment", "MarginL", "MarginR", "MarginV", "Encoding",
+}
+
+var colorRE = regexp.MustCompile(`^&[Hh]([0-9A-Fa-f]{1,8})&?$`)
+
+// ParseStyleSheet reads the [V4+ Styles] section (and PlayResX/PlayResY from
+// [Script Info]) of an .ass file. Every style is validated; errors name the
+// line and field.
+func ParseStyleSheet(text string) (StyleSheet, error) {
+	var (
+		sheet   StyleSheet
+		section string
+		format  []string
+		styles  []Style
+	)
+	for i, raw := range strings.Split(strings.TrimPrefix(text, "\ufeff"), "\n") {
+		ln := strings.TrimSpace(raw)
+		lineErr := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", i+1, fmt.Sprintf(format, args...))
+		}
+		if ln == "" || strings.HasPrefix(ln, ";") {
+			continue
+		}
+		if strings.HasPrefix(ln, "[") {
+			section = strings.ToLower(ln)
+			continue
+		}
+		key, value, ok := strings.Cut(ln, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch section {
+		case "[script info]":
+			switch key {
+			case "PlayResX", "PlayResY":
+				v, err := strconv.Atoi(value)
+				if err != nil || v <= 0 {
+					return StyleSheet{}, lineErr("invalid %s %q", key, value)
+				}
+				if key == "PlayResX" {
+					sheet.PlayResX = v
+				} else {
+					sheet.PlayResY = v
+				}
+			}
+		case "[v4+ styles]":
+			switch key {
+			case "Format":
+				f, err := parseStyleFormat(value)
+				if err != nil {
+					return StyleSheet{}, lineErr("%v", err)
+				}
+				format = f
+			case "Style":
+				if format == nil {
+					return StyleSheet{}, lineErr("Style before Format")
+				}
+				st, err := parseStyle(format, value)
+				if err != nil {
+					return StyleSheet{}, lineErr("style: %v", err)
+				}
+				if slices.ContainsFunc(styles, func(o Style) bool { return o.Name == st.Name }) {
+					return StyleSheet{}, lineErr("duplicate style %q", st.Name)
+				}
+				styles = append(styles, st)
+			}
+		}
+	}
+	for _, st := range styles {
+		if strings.EqualFold(st.Name, "Title") {
+			t := st
+			sheet.Title = &t
+		} else if sheet.Caption.Name == "" {
+			sheet.Caption = st
+		}
+	}
+	if sheet.Caption.Name == "" {
+		return StyleSheet{}, fmt.Errorf("no caption style in [V4+ Styles]")
+	}
+	return sheet, nil
+}
+
+func parseStyleFormat(value string) ([]string, error) {
+	var out []string
+	for _, f := range strings.Split(value, ",") {
+		f = strings.TrimSpace(f)
+		if !slices.Contains(styleFields, f) {
+			return nil, fmt.Errorf("unknown style field %q", f)
+		}
+		if slices.Contains(out, f) {
+			return nil, fmt.Errorf("duplicate style field %q", f)
+		}
+		out = append(out, f)
+	}
+	for _, f := range styleFields {
+		if !slices.Contains(out, f) {
+			return nil, fmt.Errorf("style field %s is missing from Format", f)
+		}
+	}
+	return out, nil
+}
+
+func parseStyle(format []string, value string) (Style, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != len(format) {
+		return Style{}, fmt.Errorf("%d fields, Format has %d", len(parts), len(format))
+	}
+	var st Style
+	for i, field := range format {
+		v := strings.TrimSpace(parts[i])
+		if err := st.set(field, v); err != nil {
+			return Style{}, fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return st, nil
+}
+
+func (st *Style) set(field, v string) error {
+	var err error
+	switch field {
+	case "Name":
+		if v == "" {
+			return fmt.Errorf("empty name")
+		}
+		st.Name = v
+	case "Fontname":
+		if v == "" {
+			return fmt.Errorf("empty font name")
+		}
+		st.Fontname = v
+	case "Fontsize":
+		st.Fontsize, err = parseNumber(v, 1, 1000)
+	case "PrimaryColour":
+		st.PrimaryColour, err = parseColor(v)
+	case "SecondaryColour":
+		st.SecondaryColour, err = parseColor(v)
+	case "OutlineColour":
+		st.OutlineColour, err = parseColor(v)
+	case "BackColour":
+		st.BackColour, err = parseColor(v)
+	case "Bold":
+		st.Bold, err = parseInt(v, -1, 1000)
+	case "Italic":
+		st.Italic, err = parseInt(v, -1, 1)
+	case "Underline":
+		st.Underline, err = parseInt(v, -1, 1)
+	case "StrikeOut":
+		st.StrikeOut, err = parseInt(v, -1, 1)
+	case "ScaleX":
+		st.ScaleX, err = parseNumber(v, 1, 1000)
+	case "ScaleY":
+		st.ScaleY, err = parseNumber(v, 1, 1000)
+	case "Spacing":
+		st.Spacing, err = parseNumber(v, -1000, 1000)
+	case "Angle":
+		st.Angle, err = parseNumber(v, -360, 360)
+	case "BorderStyle":
+		st.BorderStyle, err = parseInt(v, 1, 4)
+		if err == nil && st.BorderStyle == 2 {
+			err = fmt.Errorf("want 1 (outline) or 3 (box), got 2")
+		}
+	case "Outline":
+		st.Outline, err = parseNumber(v, 0, 100)
+	case "Shadow":
+		st.Shadow, err = parseNumber(v, 0, 100)
+	case "Alignment":
+		st.Alignment, err = parseInt(v, 1, 9)
+	case "MarginL":
+		st.MarginL, err = parseInt(v, 0, 10000)
+	case "MarginR":
+		st.MarginR, err = parseInt(v, 0, 10000)
+	case "MarginV":
+		st.MarginV, err = parseInt(v, 0, 10000)
+	case "Encoding":
+		st.Encoding, err = parseInt(v, 0, 255)
+	}
+	return err
+}
+
+// parseColor accepts &HBBGGRR and &HAABBGGRR (with an optional trailing &)
+// and returns the &HAABBGGRR form.
+func parseColor(v string) (string, error) {
+	m := colorRE.FindStringSubmatch(v)
+	if m == nil {
+		return "", fmt.Errorf("invalid color %q (want &HAABBGGRR)", v)
+	}
+	n, _ := strconv.ParseUint(m[1], 16, 32)
+	return fmt.Sprintf("&H%08X", n), nil
+}
+
+func parseInt(v string, lo, hi int) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("invalid value %q (want an integer in %d..%d)", v, lo, hi)
+	}
+	return n, nil
+}
+
+func parseNumber(v string, lo, hi float64) (float64, error) {
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(n) || n < lo || n > hi {
+		return 0, fmt.Errorf("invalid value %q (want a number in %s..%s)", v, num(lo), num(hi))
+	}
+	return n, nil
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,105 @@
+package subtitles
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+const testStyleFormat = "Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding"
+
+func TestRenderASS_Presets(t *testing.T) {
+	tr := types.Transcript{Segments: []types.Segment{
+		{Start: 0, End: 2, Words: []types.Word{{Start: 0, End: 1, Word: "hi"}, {Start: 1, End: 2, Word: "there"}}},
+	}}
+	tests := []struct {
+		preset string
+		want   []string
+	}{
+		{"", []string{"Style: TikTok, Inter, 78, &H00FFFFFF, &H00FFD200, &H00000000, &H64000000, 1,0,0,0,100,100,0,0,1,6,2,2, 80,80,85,1", ",TikTok,,"}},
+		{PresetMinimal, []string{"Style: Minimal, Inter, 66, &H00FFFFFF, &H00FFFFFF,", ", 0,0,0,0,100,100,0,0,1,3,0,2,", ",Minimal,,"}},
+		{PresetBoxed, []string{"Style: Boxed,", ",3,15,0,2,", ",Boxed,,"}},
+		{PresetTopTitle, []string{"Style: TikTok,", "Style: Title,", ",3,15,0,8, 80,80,64,1", "Dialogue: 1,0:00:00.00,0:00:02.00,Title,,0,0,0,,Big (news)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			got, err := RenderASS(tr, 0, 2*time.Second, Options{Preset: tt.preset, Title: "Big {news}"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Fatalf("expected %q in:\n%s", w, got)
+				}
+			}
+			if tt.preset != PresetTopTitle && strings.Contains(got, "Title") {
+				t.Fatalf("only top-title should render a title:\n%s", got)
+			}
+		})
+	}
+
+	if _, err := RenderASS(tr, 0, 2*time.Second, Options{Preset: "neon"}); err == nil {
+		t.Fatal("expected unknown preset error")
+	}
+}
+
+func TestParseStyleSheet_ScalesToFrame(t *testing.T) {
+	text := "[Script Info]\nPlayResX: 1080\nPlayResY: 1920\n\n[V4+ Styles]\n" + testStyleFormat + "\n" +
+		"Style: Title, Arial, 60, &H00FFFFFF, &H00FFFFFF, &H00000000, &H00000000, 1,0,0,0,100,100,0,0,3,10,0,8, 40,40,200,1\n" +
+		"Style: Pop, Arial, 100, &HFFFFFF, &H00FFFF&, &H000000, &H80000000, -1,0,0,0,100,100,0,0,1,4,1,2, 60,60,400,1\n"
+	sheet, err := ParseStyleSheet(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sheet.Caption.Name != "Pop" || sheet.Title == nil || sheet.Title.Name != "Title" {
+		t.Fatalf("unexpected styles: %+v", sheet)
+	}
+	if sheet.Caption.PrimaryColour != "&H00FFFFFF" || sheet.Caption.SecondaryColour != "&H0000FFFF" {
+		t.Fatalf("colors not normalized: %+v", sheet.Caption)
+	}
+
+	got, err := RenderASS(types.Transcript{}, 0, time.Second, Options{Width: 540, Height: 960, Sheet: &sheet, Title: "T"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{
+		"PlayResX: 540\nPlayResY: 960",
+		"Style: Pop, Arial, 50, &H00FFFFFF, &H0000FFFF, &H00000000, &H80000000, -1,0,0,0,100,100,0,0,1,2,1,2, 30,30,200,1",
+		"Style: Title, Arial, 30,",
+		",Title,,0,0,0,,T",
+	} {
+		if !strings.Contains(got, w) {
+			t.Fatalf("expected %q in:\n%s", w, got)
+		}
+	}
+}
+
+func TestParseStyleSheet_Errors(t *testing.T) {
+	style := "Style: A, Arial, 60, &H00FFFFFF, &H00FFFFFF, &H00000000, &H00000000, 0,0,0,0,100,100,0,0,1,2,0,2, 10,10,10,1"
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no styles", "[V4+ Styles]\n" + testStyleFormat + "\n", "no caption style"},
+		{"style before format", "[V4+ Styles]\n" + style, "line 2: Style before Format"},
+		{"unknown field", "[V4+ Styles]\nFormat: Name, Glow\n", "unknown style field \"Glow\""},
+		{"missing field", "[V4+ Styles]\nFormat: Name, Fontname\n", "Fontsize is missing"},
+		{"bad color", "[V4+ Styles]\n" + testStyleFormat + "\n" + strings.Replace(style, "&H00FFFFFF", "#FFFFFF", 1), "line 3: style: PrimaryColour: invalid color"},
+		{"bad alignment", "[V4+ Styles]\n" + testStyleFormat + "\n" + strings.Replace(style, ",2, 10", ",12, 10", 1), "Alignment"},
+		{"bad size", "[V4+ Styles]\n" + testStyleFormat + "\n" + strings.Replace(style, "Arial, 60", "Arial, 0", 1), "Fontsize"},
+		{"field count", "[V4+ Styles]\n" + testStyleFormat + "\nStyle: A, Arial", "2 fields, Format has 23"},
+		{"duplicate", "[V4+ Styles]\n" + testStyleFormat + "\n" + style + "\n" + style, "line 4: duplicate style \"A\""},
+		{"bad playres", "[Script Info]\nPlayResY: tall\n", "line 2: invalid PlayResY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseStyleSheet(tt.text)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
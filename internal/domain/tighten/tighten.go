@@ -0,0 +1,211 @@
+// Package tighten turns a clip into jump cuts: filler words and long pauses
+// are dropped using the transcript's word timestamps, and the transcript is
+// re-timed to the edited clip so subtitles stay in sync.
+package tighten
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// DefaultMaxPause is the longest pause kept untouched when none is given.
+const DefaultMaxPause = 600 * time.Millisecond
+
+const (
+	// cutPad is the silence left on each side of a cut, so words are not
+	// clipped where ASR timestamps are a little early or late.
+	cutPad = 80 * time.Millisecond
+	// minCut skips cuts too short to notice; each cut costs a seam.
+	minCut = 50 * time.Millisecond
+)
+
+// fillers are dropped wherever they occur. "you know" is handled separately
+// since it is only a filler when set off by commas.
+var fillers = map[string]bool{
+	"um": true, "umm": true, "uh": true, "uhh": true, "uhm": true,
+	"er": true, "erm": true, "ah": true, "hmm": true, "mm": true, "mhm": true,
+}
+
+// KeepRanges returns the parts of [start, end) to keep, in source time:
+// filler words are cut out and pauses longer than maxPause (<= 0 means
+// DefaultMaxPause) are shortened. Without word timestamps the whole range is
+// kept.
+func KeepRanges(tr types.Transcript, start, end time.Duration, maxPause time.Duration) []types.Range {
+	if maxPause <= 0 {
+		maxPause = DefaultMaxPause
+	}
+	whole := []types.Range{{Start: start, End: end}}
+	words := collectWords(tr, start, end)
+	if !hasSpeech(words) {
+		return whole
+	}
+
+	var cuts []types.Range
+	prevEnd, prevEdge, filler := start, true, false
+	for _, w := range words {
+		if w.filler {
+			filler = true
+			continue
+		}
+		if c, ok := cut(prevEnd, w.start, prevEdge, false, filler, maxPause); ok {
+			cuts = append(cuts, c)
+		}
+		prevEnd, prevEdge, filler = max(prevEnd, w.end), false, false
+	}
+	if c, ok := cut(prevEnd, end, prevEdge, true, filler, maxPause); ok {
+		cuts = append(cuts, c)
+	}
+
+	var keep []types.Range
+	at := start
+	for _, c := range cuts {
+		if c.Start > at {
+			keep = append(keep, types.Range{Start: at, End: c.Start})
+		}
+		at = c.End
+	}
+	if at < end {
+		keep = append(keep, types.Range{Start: at, End: end})
+	}
+	if len(keep) == 0 {
+		return whole
+	}
+	return keep
+}
+
+// cut returns the part of the gap between two kept words to drop. Padding is
+// only left next to words; silence at the clip edges is dead air.
+func cut(from, to time.Duration, fromEdge, toEdge, filler bool, maxPause time.Duration) (types.Range, bool) {
+	gap := to - from
+	if gap <= 0 || (!filler && gap <= maxPause) {
+		return types.Range{}, false
+	}
+	pad := min(cutPad, gap/4)
+	if !fromEdge {
+		from += pad
+	}
+	if !toEdge {
+		to -= pad
+	}
+	if to-from < minCut {
+		return types.Range{}, false
+	}
+	return types.Range{Start: from, End: to}, true
+}
+
+// Duration is the length of the edited clip.
+func Duration(keep []types.Range) time.Duration {
+	var d time.Duration
+	for _, r := range keep {
+		d += r.End - r.Start
+	}
+	return d
+}
+
+// Retime maps the transcript onto the edited clip: times become offsets from
+// the start of the first kept range, and words outside the kept ranges are
+// dropped.
+func Retime(tr types.Transcript, keep []types.Range) types.Transcript {
+	var out types.Transcript
+	for _, s := range tr.Segments {
+		ss, se, ok := mapRange(keep, sec(s.Start), sec(s.End))
+		if !ok {
+			continue
+		}
+		seg := types.Segment{Start: ss.Seconds(), End: se.Seconds(), Text: s.Text}
+		for _, w := range s.Words {
+			ws, we, ok := mapRange(keep, sec(w.Start), sec(w.End))
+			if !ok {
+				continue
+			}
+			seg.Words = append(seg.Words, types.Word{Start: ws.Seconds(), End: we.Seconds(), Word: w.Word})
+		}
+		if len(s.Words) > 0 && len(seg.Words) == 0 {
+			// Only cut words (e.g. fillers) were in this segment.
+			continue
+		}
+		out.Segments = append(out.Segments, seg)
+	}
+	return out
+}
+
+// mapRange maps [a, b) to the edited timeline: a moves to the first kept
+// moment at or after it, b to the last one before it. ok is false when the
+// range lies entirely in cuts.
+func mapRange(keep []types.Range, a, b time.Duration) (time.Duration, time.Duration, bool) {
+	var (
+		offset     time.Duration
+		s, e       time.Duration
+		overlapped bool
+	)
+	for _, r := range keep {
+		if a < r.End && b > r.Start {
+			if !overlapped {
+				s = offset + max(a, r.Start) - r.Start
+				overlapped = true
+			}
+			e = offset + min(b, r.End) - r.Start
+		}
+		offset += r.End - r.Start
+	}
+	return s, e, overlapped
+}
+
+type word struct {
+	start, end time.Duration
+	filler     bool
+}
+
+func collectWords(tr types.Transcript, start, end time.Duration) []word {
+	var raw []types.Word
+	for _, s := range tr.Segments {
+		raw = append(raw, s.Words...)
+	}
+	var out []word
+	for i, w := range raw {
+		ws, we := sec(w.Start), sec(w.End)
+		if we <= start || ws >= end || strings.TrimSpace(w.Word) == "" {
+			continue
+		}
+		out = append(out, word{start: max(ws, start), end: min(we, end), filler: isFiller(raw, i)})
+	}
+	return out
+}
+
+// isFiller reports whether raw[i] is a filler word, or part of a "you know"
+// set off by commas ("it's, you know, fine").
+func isFiller(raw []types.Word, i int) bool {
+	w := norm(raw[i].Word)
+	if fillers[w] {
+		return true
+	}
+	var you, know int
+	switch {
+	case w == "you" && i+1 < len(raw) && norm(raw[i+1].Word) == "know":
+		you, know = i, i+1
+	case w == "know" && i > 0 && norm(raw[i-1].Word) == "you":
+		you, know = i-1, i
+	default:
+		return false
+	}
+	return strings.HasSuffix(strings.TrimSpace(raw[know].Word), ",") ||
+		(you > 0 && strings.HasSuffix(strings.TrimSpace(raw[you-1].Word), ","))
+}
+
+func hasSpeech(words []word) bool {
+	for _, w := range words {
+		if !w.filler {
+			return true
+		}
+	}
+	return false
+}
+
+func norm(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) }))
+}
+
+func sec(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
@@ -0,0 +1,74 @@
+package tighten
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func ms(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+
+func TestKeepRanges_DropsFillersAndLongPauses(t *testing.T) {
+	tr := types.Transcript{Segments: []types.Segment{{Start: 10, End: 20, Words: []types.Word{
+		{Start: 11.0, End: 11.4, Word: " So"},
+		{Start: 11.5, End: 11.8, Word: " um,"},
+		{Start: 11.9, End: 12.3, Word: " this"},
+		{Start: 12.4, End: 12.8, Word: " works,"},
+		{Start: 12.9, End: 13.1, Word: " you"},
+		{Start: 13.1, End: 13.3, Word: " know,"},
+		{Start: 13.4, End: 13.8, Word: " great."},
+		{Start: 15.8, End: 16.2, Word: " Next"},
+	}}}}
+
+	got := KeepRanges(tr, 10*time.Second, 17*time.Second, 0)
+	want := []types.Range{
+		// Dead air at the clip edges is cut too, keeping the pad by the words.
+		{Start: ms(10920), End: ms(11480)},
+		{Start: ms(11820), End: ms(12880)},
+		{Start: ms(13320), End: ms(13880)},
+		{Start: ms(15720), End: ms(16280)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("KeepRanges = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("range %d = %v, want %v (all: %v)", i, got[i], want[i], got)
+		}
+	}
+	if d := Duration(got); d != ms(560+1060+560+560) {
+		t.Fatalf("Duration = %s", d)
+	}
+
+	// "you know" without commas is speech, and short pauses stay.
+	plain := types.Transcript{Segments: []types.Segment{{Start: 0, End: 2, Words: []types.Word{
+		{Start: 0.0, End: 0.4, Word: "do"},
+		{Start: 0.5, End: 0.8, Word: "you"},
+		{Start: 0.8, End: 1.1, Word: "know"},
+		{Start: 1.2, End: 2.0, Word: "why?"},
+	}}}}
+	if got := KeepRanges(plain, 0, 2*time.Second, 0); len(got) != 1 || got[0] != (types.Range{End: 2 * time.Second}) {
+		t.Fatalf("expected the clip to stay whole, got %v", got)
+	}
+}
+
+func TestRetime_ShiftsWordsOntoEditedTimeline(t *testing.T) {
+	tr := types.Transcript{Segments: []types.Segment{
+		{Start: 10, End: 12, Text: "one two", Words: []types.Word{{Start: 10.0, End: 10.5, Word: "one"}, {Start: 11.5, End: 12.0, Word: "two"}}},
+		{Start: 12, End: 12.5, Text: "um", Words: []types.Word{{Start: 12.0, End: 12.5, Word: "um"}}},
+	}}
+	keep := []types.Range{{Start: ms(10000), End: ms(10600)}, {Start: ms(11400), End: ms(12000)}}
+
+	got := Retime(tr, keep)
+	if len(got.Segments) != 1 {
+		t.Fatalf("expected the filler-only segment to be dropped, got %+v", got.Segments)
+	}
+	seg := got.Segments[0]
+	if seg.Start != 0 || seg.End != 1.2 || len(seg.Words) != 2 {
+		t.Fatalf("unexpected segment: %+v", seg)
+	}
+	if w := seg.Words[1]; w.Start != 0.7 || w.End != 1.2 {
+		t.Fatalf("unexpected retimed word: %+v", w)
+	}
+}
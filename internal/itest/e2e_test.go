@@ -101,7 +101,7 @@ func TestE2E(t *testing.T) {
 		if err != nil {
 			t.Fatalf("probe sample duration: %v", err)
 		}
-		minClip, _ := highlights.DurationBounds()
+		minClip := highlights.DefaultDurations().Min
 		if sampleDur+0.2 < minClip.Seconds() {
 			// No clip can be produced when input is shorter than internal min clip duration.
 			return
@@ -114,7 +114,7 @@ func TestRobustness_InvalidInputMedia(t *testing.T) {
 				"OPENROUTER_API_KEY": "dummy",
 			},
 			wantContains: []string{
-				"ffmpeg extract audio:",
+				"is a directory, not a media file",
 			},
 		},
 		{
@@ -124,7 +124,7 @@ func TestRobustness_InvalidInputMedia(t *testing.T) {
 				"OPENROUTER_API_KEY": "dummy",
 			},
 			wantContains: []string{
-				"ffmpeg extract audio:",
+				"is not a readable media file",
 			},
 		},
 		{
@@ -218,12 +218,53 @@ func TestRobustness_SecurityEnvHardening(t *testing.T) {
 				"OPENROUTER_ALLOWED_HOSTS": " proxy.internal ",
 			},
 			wantContains: []string{
-				"ffmpeg extract audio:",
+				"is a directory, not a media file",
 			},
 			wantNotContains: []string{
 				"invalid OPENROUTER_BASE_URL",
 			},
 		},
+		{
+			name: "openai ranker rejects http without loopback opt-in",
+			args: staticArgs(sample, "--ranker", "openai"),
+			env: map[string]string{
+				"OPENROUTER_API_KEY": "",
+				"OPENAI_BASE_URL":    "http://127.0.0.1:8080",
+				"OPENAI_MODEL":       "local",
+			},
+			wantContains: []string{
+				"OPENAI_ALLOW_HTTP_LOOPBACK",
+			},
+		},
+		{
+			name: "openai ranker rejects http to remote host",
+			args: staticArgs(sample, "--ranker", "openai"),
+			env: map[string]string{
+				"OPENAI_BASE_URL":            "http://10.0.0.5:8080",
+				"OPENAI_MODEL":               "local",
+				"OPENAI_ALLOW_HTTP_LOOPBACK": "1",
+			},
+			wantContains: []string{
+				"plain http is only allowed for loopback hosts",
+			},
+		},
+		{
+			name: "openai ranker allows http loopback then fail later",
+			args: staticArgs(filepath.Join(repoRoot, "internal", "itest", "testdata"), "--ranker", "openai"),
+			env: map[string]string{
+				"OPENROUTER_API_KEY":         "",
+				"OPENAI_BASE_URL":            "http://127.0.0.1:8080/v1",
+				"OPENAI_MODEL":               "local",
+				"OPENAI_ALLOW_HTTP_LOOPBACK": "1",
+			},
+			wantContains: []string{
+				"is a directory, not a media file",
+			},
+			wantNotContains: []string{
+				"invalid OPENAI_BASE_URL",
+				"OPENROUTER_API_KEY is required",
+			},
+		},
 	}
 
 	runRobustCases(t, repoRoot, cases)
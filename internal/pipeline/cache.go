@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// transcriptCacheVersion is mixed into every cache key so that changes to the
+// transcript shape or the ASR invocation invalidate previously cached entries.
+const transcriptCacheVersion = "v1"
+
+type digestEntry struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime_ns"`
+	SHA256  string `json:"sha256"`
+}
+
+// fileDigest returns the sha256 of the file content. Digests are remembered in
+// indexDir keyed by path, so unchanged files (same size and mtime) are not
+// re-hashed on later runs; content remains the source of truth.
+func fileDigest(path, indexDir string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("digest %s: not a regular file", abs)
+	}
+
+	indexPath := ""
+	if indexDir != "" {
+		indexPath = filepath.Join(indexDir, hash(abs)+".json")
+		if b, err := os.ReadFile(indexPath); err == nil {
+			var e digestEntry
+			if json.Unmarshal(b, &e) == nil &&
+				e.Path == abs &&
+				e.Size == info.Size() &&
+				e.ModTime == info.ModTime().UnixNano() &&
+				e.SHA256 != "" {
+				return e.SHA256, nil
+			}
+		}
+	}
+
+	sum, err := sha256File(abs)
+	if err != nil {
+		return "", err
+	}
+
+	if indexPath != "" {
+		// The index is only an accelerator; failing to persist it must not fail the run.
+		if err := os.MkdirAll(indexDir, 0o755); err == nil {
+			b, err := json.Marshal(digestEntry{
+				Path:    abs,
+				Size:    info.Size(),
+				ModTime: info.ModTime().UnixNano(),
+				SHA256:  sum,
+			})
+			if err == nil {
+				_ = os.WriteFile(indexPath, b, 0o644)
+			}
+		}
+	}
+	return sum, nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// transcriptCacheKey derives the cache directory name for an input from its
+// content digest and the identity of everything that shapes the transcript
+// (ASR engine, model, options).
+func transcriptCacheKey(inputDigest string, asrIdentity ...string) string {
+	parts := append([]string{transcriptCacheVersion, inputDigest}, asrIdentity...)
+	return hash(strings.Join(parts, "|"))
+}
+
+// modelIdentity identifies a whisper model by content when the file is
+// readable, falling back to its path so a missing model still yields a stable
+// key (whisper.cpp reports the real error later). A registry model whose
+// content does not match its pinned checksum is logged.
+func modelIdentity(m whisperModel, indexDir string, logf func(string, ...any)) string {
+	sum, err := fileDigest(m.path, indexDir)
+	if err != nil {
+		return "path:" + m.path
+	}
+	if r, ok := m.registry(); ok && r.SHA256 != "" && r.SHA256 != sum {
+		logf("warning: whisper model %s does not match its registry checksum; check it with `hlcut models verify`", r.Name)
+	}
+	return "sha256:" + sum
+}
@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileDigest_ContentAddressed(t *testing.T) {
+	tmp := t.TempDir()
+	index := filepath.Join(tmp, "digests")
+	a := filepath.Join(tmp, "a.mp4")
+	b := filepath.Join(tmp, "renamed copy.mp4")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("same bytes"), 0o644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+	}
+
+	da, err := fileDigest(a, index)
+	if err != nil {
+		t.Fatalf("digest a: %v", err)
+	}
+	db, err := fileDigest(b, index)
+	if err != nil {
+		t.Fatalf("digest b: %v", err)
+	}
+	if da != db {
+		t.Fatalf("expected identical content to share a digest: %s != %s", da, db)
+	}
+
+	// Same size, new mtime: the fast path must not hide a content change.
+	if err := os.WriteFile(a, []byte("diff bytes"), 0o644); err != nil {
+		t.Fatalf("rewrite fixture: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(a, later, later); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	da2, err := fileDigest(a, index)
+	if err != nil {
+		t.Fatalf("digest a after edit: %v", err)
+	}
+	if da2 == da {
+		t.Fatalf("expected digest to change after content edit")
+	}
+}
+
+func TestFileDigest_RejectsDirectory(t *testing.T) {
+	if _, err := fileDigest(t.TempDir(), ""); err == nil {
+		t.Fatalf("expected error for directory input")
+	}
+}
+
+func TestTranscriptCacheKey_DependsOnModel(t *testing.T) {
+	base := transcriptCacheKey("abc", "asr=whisper.cpp", "model=sha256:1")
+	if base != transcriptCacheKey("abc", "asr=whisper.cpp", "model=sha256:1") {
+		t.Fatalf("expected stable cache key")
+	}
+	if base == transcriptCacheKey("abc", "asr=whisper.cpp", "model=sha256:2") {
+		t.Fatalf("expected model identity to change the cache key")
+	}
+	if base == transcriptCacheKey("abd", "asr=whisper.cpp", "model=sha256:1") {
+		t.Fatalf("expected input digest to change the cache key")
+	}
+}
+
+func TestCacheJobID_DependsOnLanguage(t *testing.T) {
+	tmp := t.TempDir()
+	in := filepath.Join(tmp, "in.mp4")
+	model := filepath.Join(tmp, "model.bin")
+	for _, p := range []string{in, model} {
+		if err := os.WriteFile(p, []byte(p), 0o644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+	}
+	id := func(lang string, translate bool) string {
+		cfg := Config{InputPath: in, WhisperModel: model, Language: lang, Translate: translate}
+		return cacheJobID(cfg, tmp, func(string, ...any) {})
+	}
+	if id("", false) != id("auto", false) {
+		t.Fatalf("expected empty and auto language to share a cache entry")
+	}
+	seen := map[string]bool{}
+	for _, k := range []string{id("auto", false), id("es", false), id("de", false), id("es", true)} {
+		if seen[k] {
+			t.Fatalf("expected distinct cache keys per language and translation")
+		}
+		seen[k] = true
+	}
+}
+
+func TestCacheJobID_DependsOnModelName(t *testing.T) {
+	tmp := t.TempDir()
+	in := filepath.Join(tmp, "in.mp4")
+	if err := os.WriteFile(in, []byte("in"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	// Identical bytes under two registry names must not share a transcript.
+	for _, name := range []string{"base", "small"} {
+		if err := os.WriteFile(filepath.Join(tmp, "ggml-"+name+".bin"), []byte("model"), 0o644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+	}
+	id := func(model string) string {
+		cfg := Config{InputPath: in, WhisperModel: model, ModelsDir: tmp}
+		return cacheJobID(cfg, tmp, func(string, ...any) {})
+	}
+	if id("base") != id(filepath.Join(tmp, "ggml-base.bin")) {
+		t.Fatalf("expected a model name and its path to share a cache entry")
+	}
+	if id("base") == id("small") {
+		t.Fatalf("expected distinct cache keys per model name")
+	}
+}
+
+func TestCacheJobID_DependsOnTranscribeChunk(t *testing.T) {
+	tmp := t.TempDir()
+	in := filepath.Join(tmp, "in.mp4")
+	model := filepath.Join(tmp, "model.bin")
+	for _, p := range []string{in, model} {
+		if err := os.WriteFile(p, []byte(p), 0o644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+	}
+	id := func(chunk time.Duration, workers int) string {
+		cfg := Config{InputPath: in, WhisperModel: model, TranscribeChunk: chunk, TranscribeWorkers: workers}
+		return cacheJobID(cfg, tmp, func(string, ...any) {})
+	}
+	if id(0, 0) == id(10*time.Minute, 0) || id(10*time.Minute, 0) == id(20*time.Minute, 0) {
+		t.Fatalf("expected distinct cache keys per chunk length")
+	}
+	if id(10*time.Minute, 2) != id(10*time.Minute, 4) {
+		t.Fatalf("expected the worker count to keep the cache key")
+	}
+}
+
+func TestCacheJobID_DependsOnDiarize(t *testing.T) {
+	tmp := t.TempDir()
+	in := filepath.Join(tmp, "in.mp4")
+	model := filepath.Join(tmp, "model.bin")
+	for _, p := range []string{in, model} {
+		if err := os.WriteFile(p, []byte(p), 0o644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+	}
+	id := func(diarize string) string {
+		return cacheJobID(Config{InputPath: in, WhisperModel: model, Diarize: diarize}, tmp, func(string, ...any) {})
+	}
+	if id("") == id("stereo") || id("stereo") == id("tdrz") {
+		t.Fatalf("expected distinct cache keys per diarization mode")
+	}
+}
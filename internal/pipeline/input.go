@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/forPelevin/hlcut/internal/ports"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// probeInput reads the input's streams and checks that hlcut can work with
+// them, so a bad input fails up front with a readable error rather than an
+// ffmpeg or whisper log minutes later. The audio track is resolved from
+// c.AudioTrack (negative picks the default track).
+func (c Config) probeInput(ctx context.Context, v ports.VideoTool) (types.InputInfo, error) {
+	st, err := os.Stat(c.InputPath)
+	if err != nil {
+		return types.InputInfo{}, fmt.Errorf("stat input: %w", err)
+	}
+	if st.IsDir() {
+		return types.InputInfo{}, fmt.Errorf("input %s is a directory, not a media file", c.InputPath)
+	}
+	info, err := v.Probe(ctx, c.InputPath)
+	if err != nil {
+		return types.InputInfo{}, fmt.Errorf("input %s is not a readable media file: %w", c.InputPath, err)
+	}
+	track, err := checkMedia(info, c.AudioTrack)
+	if err != nil {
+		return types.InputInfo{}, fmt.Errorf("input %s: %w", c.InputPath, err)
+	}
+	c.logger()("input media: %s", describeMedia(info, track))
+	return types.InputInfo{MediaInfo: info, AudioTrack: track}, nil
+}
+
+// checkMedia validates the probed streams and returns the audio track to use.
+func checkMedia(info types.MediaInfo, track int) (int, error) {
+	tracks := info.AudioTracks()
+	_, hasVideo := info.Video()
+	switch {
+	case len(tracks) == 0 && hasVideo:
+		return 0, errors.New("no audio track to transcribe")
+	case len(tracks) == 0:
+		return 0, errors.New("no audio or video streams")
+	case track >= len(tracks):
+		return 0, fmt.Errorf("audio track %d does not exist (tracks: %s)", track, describeTracks(tracks))
+	case track < 0:
+		track = info.DefaultAudioTrack()
+	}
+	if info.Duration() <= 0 {
+		return 0, errors.New("duration is unknown; the file may be truncated")
+	}
+	if a := tracks[track]; a.Codec == "" || a.Codec == "none" {
+		return 0, fmt.Errorf("audio track %d (stream %d) has an unsupported codec", track, a.Index)
+	}
+	if v, ok := info.Video(); ok && (v.Codec == "" || v.Width <= 0 || v.Height <= 0) {
+		return 0, fmt.Errorf("video stream %d has an unsupported codec or no frame size", v.Index)
+	}
+	return track, nil
+}
+
+// describeMedia summarizes the input for the log, e.g.
+// "mov,mp4 12m30s, video h264 1920x1080 29.97fps, audio track 0/2 (eng aac 2ch)".
+func describeMedia(info types.MediaInfo, track int) string {
+	parts := []string{strings.TrimSpace(info.Format + " " + formatDuration(info.Duration()))}
+	if v, ok := info.Video(); ok {
+		s := fmt.Sprintf("video %s %dx%d %gfps", v.Codec, v.Width, v.Height, v.FPS)
+		if v.VFR {
+			s += " (variable frame rate)"
+		}
+		if v.Rotation != 0 {
+			s += fmt.Sprintf(" rotated %d°", v.Rotation)
+		}
+		parts = append(parts, s)
+	} else {
+		parts = append(parts, "audio only")
+	}
+	tracks := info.AudioTracks()
+	parts = append(parts, fmt.Sprintf("audio track %d/%d (%s)", track, len(tracks), describeTrack(tracks[track])))
+	return strings.Join(parts, ", ")
+}
+
+func describeTracks(tracks []types.MediaStream) string {
+	out := make([]string, len(tracks))
+	for i, t := range tracks {
+		out[i] = fmt.Sprintf("%d: %s", i, describeTrack(t))
+	}
+	return strings.Join(out, ", ")
+}
+
+func describeTrack(t types.MediaStream) string {
+	var parts []string
+	if t.Language != "" && t.Language != "und" {
+		parts = append(parts, t.Language)
+	}
+	parts = append(parts, t.Codec)
+	if t.Channels > 0 {
+		parts = append(parts, fmt.Sprintf("%dch", t.Channels))
+	}
+	if t.Default {
+		parts = append(parts, "default")
+	}
+	return strings.Join(parts, " ")
+}
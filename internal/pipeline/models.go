@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"os"
+
+	"github.com/forPelevin/hlcut/internal/ports/adapters/whispercpp"
+)
+
+// Model check results.
+const (
+	ModelOK        = "ok"
+	ModelMismatch  = "mismatch"
+	ModelUnpinned  = "unpinned"
+	ModelMissing   = "missing"
+	ModelInstalled = "installed"
+)
+
+// ModelCheck is the state of one whisper model file.
+type ModelCheck struct {
+	Name string
+	Path string
+	URL  string
+	// Status is ModelOK, ModelMismatch, ModelUnpinned (installed, but the
+	// registry has no checksum to compare with) or ModelMissing after a
+	// verify, and ModelInstalled or ModelMissing after a list.
+	Status string
+	// SHA256 is the file's checksum (empty unless hashed); Want is the
+	// registry's.
+	SHA256 string
+	Want   string
+}
+
+// ListModels reports every registry model as installed or missing in the
+// models directory, without hashing.
+func ListModels(cfg Config) []ModelCheck {
+	var out []ModelCheck
+	for _, m := range whispercpp.Models() {
+		c := registryCheck(cfg, m)
+		if fileExists(c.Path) {
+			c.Status = ModelInstalled
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// VerifyModels hashes the given models (registry names or paths) and
+// compares them with the registry; with no names it verifies every
+// installed registry model.
+func VerifyModels(cfg Config, names []string) ([]ModelCheck, error) {
+	var checks []ModelCheck
+	if len(names) == 0 {
+		for _, c := range ListModels(cfg) {
+			if c.Status != ModelMissing {
+				checks = append(checks, c)
+			}
+		}
+	}
+	for _, name := range names {
+		cfg.WhisperModel = name
+		wm, err := cfg.whisperModel()
+		if err != nil {
+			return nil, err
+		}
+		c := ModelCheck{Name: wm.name, Path: wm.path, Status: ModelMissing}
+		if m, ok := wm.registry(); ok {
+			c.URL, c.Want = m.URL(), m.SHA256
+		}
+		checks = append(checks, c)
+	}
+	for i, c := range checks {
+		checks[i] = verifyModel(c)
+	}
+	return checks, nil
+}
+
+func registryCheck(cfg Config, m whispercpp.Model) ModelCheck {
+	cfg.WhisperModel = m.Name
+	wm, _ := cfg.whisperModel()
+	return ModelCheck{Name: m.Name, Path: wm.path, URL: m.URL(), Status: ModelMissing, Want: m.SHA256}
+}
+
+func verifyModel(c ModelCheck) ModelCheck {
+	if !fileExists(c.Path) {
+		c.Status = ModelMissing
+		return c
+	}
+	sum, err := sha256File(c.Path)
+	if err != nil {
+		c.Status = ModelMissing
+		return c
+	}
+	c.SHA256 = sum
+	switch {
+	case c.Want == "":
+		c.Status = ModelUnpinned
+	case c.Want == sum:
+		c.Status = ModelOK
+	default:
+		c.Status = ModelMismatch
+	}
+	return c
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/forPelevin/hlcut/internal/ports/adapters/whispercpp"
+)
+
+func TestVerifyModel(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ggml-test.bin")
+	content := []byte("not really a model")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("write model: %v", err)
+	}
+	sum := sha256.Sum256(content)
+	want := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name       string
+		check      ModelCheck
+		wantStatus string
+		wantSum    string
+	}{
+		{name: "ok", check: ModelCheck{Path: path, Want: want}, wantStatus: ModelOK, wantSum: want},
+		{name: "mismatch", check: ModelCheck{Path: path, Want: "00" + want[2:]}, wantStatus: ModelMismatch, wantSum: want},
+		{name: "unpinned", check: ModelCheck{Path: path}, wantStatus: ModelUnpinned, wantSum: want},
+		{name: "missing", check: ModelCheck{Path: filepath.Join(dir, "absent.bin"), Want: want}, wantStatus: ModelMissing},
+		{name: "directory", check: ModelCheck{Path: dir, Want: want}, wantStatus: ModelMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := verifyModel(tt.check)
+			if got.Status != tt.wantStatus || got.SHA256 != tt.wantSum {
+				t.Fatalf("verifyModel() = %s %q, want %s %q", got.Status, got.SHA256, tt.wantStatus, tt.wantSum)
+			}
+		})
+	}
+}
+
+func TestVerifyModels(t *testing.T) {
+	dir := t.TempDir()
+	base, ok := whispercpp.LookupModel("base")
+	if !ok || base.SHA256 == "" {
+		t.Fatalf("expected base to be pinned in the registry")
+	}
+	if err := os.WriteFile(filepath.Join(dir, base.File()), []byte("corrupt"), 0o644); err != nil {
+		t.Fatalf("write model: %v", err)
+	}
+	other := filepath.Join(dir, "custom.bin")
+	if err := os.WriteFile(other, []byte("custom"), 0o644); err != nil {
+		t.Fatalf("write model: %v", err)
+	}
+	cfg := Config{ModelsDir: dir}
+
+	// Without names only installed registry models are checked.
+	checks, err := VerifyModels(cfg, nil)
+	if err != nil {
+		t.Fatalf("verify installed: %v", err)
+	}
+	if len(checks) != 1 || checks[0].Name != "base" || checks[0].Status != ModelMismatch || checks[0].Want != base.SHA256 {
+		t.Fatalf("unexpected installed checks: %+v", checks)
+	}
+
+	checks, err = VerifyModels(cfg, []string{other, "small"})
+	if err != nil {
+		t.Fatalf("verify named: %v", err)
+	}
+	if len(checks) != 2 || checks[0].Status != ModelUnpinned || checks[1].Status != ModelMissing || checks[1].URL == "" {
+		t.Fatalf("unexpected named checks: %+v", checks)
+	}
+
+	if _, err := VerifyModels(cfg, []string{"no-such-model"}); err == nil {
+		t.Fatalf("expected an error for an unknown model name")
+	}
+}
@@ -9,121 +9,727 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/forPelevin/hlcut/internal/domain/framing"
+	"github.com/forPelevin/hlcut/internal/domain/subtitles"
 	"github.com/forPelevin/hlcut/internal/ports"
+	"github.com/forPelevin/hlcut/internal/ports/adapters/chatcompletion"
 	"github.com/forPelevin/hlcut/internal/ports/adapters/ffmpeg"
+	"github.com/forPelevin/hlcut/internal/ports/adapters/heuristic"
+	"github.com/forPelevin/hlcut/internal/ports/adapters/openai"
 	"github.com/forPelevin/hlcut/internal/ports/adapters/openrouter"
+	"github.com/forPelevin/hlcut/internal/ports/adapters/transcriptfile"
 	"github.com/forPelevin/hlcut/internal/ports/adapters/whispercpp"
+	"github.com/forPelevin/hlcut/internal/types"
 	"github.com/forPelevin/hlcut/internal/usecase"
 )
 
 type Config struct {
-	InputMP4 string
-	OutDir   string
-	ClipsN   int
+	InputPath string
+	OutDir    string
+	ClipsN    int
 	// ClipsNSet indicates whether --clips was explicitly provided by the user.
-	ClipsNSet     bool
+	ClipsNSet bool
+	// MinClip and MaxClip bound clip durations; zero takes the default
+	// (highlights.DefaultDurations).
+	MinClip time.Duration
+	MaxClip time.Duration
+	// Profile names the platform profile the settings came from, if any.
+	Profile string
+	// MaxFileSizeMB and MaxVideoKbps cap the video bitrate of encoded clips
+	// so the longest clip fits the size; 0 means no limit.
+	MaxFileSizeMB int
+	MaxVideoKbps  int
 	BurnSubtitles bool
-	Logf          func(format string, args ...any)
+	// SubtitleFormats are the subtitle files written per clip (ass, srt,
+	// vtt), independent of burning.
+	SubtitleFormats []string
+	// SubtitleStyle is a style preset (tiktok, minimal, boxed, top-title) or
+	// the path of an .ass file whose [V4+ Styles] section is used.
+	SubtitleStyle string
+	// CaptionMode is karaoke (full lines) or pop (a few words at a time).
+	CaptionMode string
+	// FontsDir is passed to ffmpeg for the fonts of burned subtitles.
+	FontsDir string
+	Logf     func(format string, args ...any)
+
+	// Aspect reframes clips to 9:16, 1:1, 4:5 or 16:9; empty keeps the source
+	// geometry. Reframe is the fit mode (crop-center, blur-pad, letterbox)
+	// and Resolution the short side of the frame (e.g. 1080p).
+	Aspect     string
+	Reframe    string
+	Resolution string
+	// Tighten cuts filler words and pauses longer than TightenPause out of
+	// clips (jump cuts); TightenPause 0 uses tighten.DefaultMaxPause.
+	Tighten      bool
+	TightenPause time.Duration
+	// LoudnessLUFS normalizes clip audio to this integrated loudness with
+	// two-pass loudnorm (0 = off); AudioFade fades clip audio in and out.
+	LoudnessLUFS float64
+	AudioFade    time.Duration
+	// Audio-only inputs are rendered as audiograms: a waveform over Cover
+	// (an image) or the Background color, with EpisodeTitle at the top
+	// (default: the input's title tag, then its file name).
+	Cover        string
+	Background   string
+	EpisodeTitle string
+	// AudioTrack is the input's audio stream to transcribe and render, as
+	// in ffmpeg's "0:a:N"; negative picks the track flagged as default.
+	AudioTrack int
+	// OutputFormat is the clip container and codec profile: mp4 (h264/aac,
+	// default), webm (vp9/opus) or mov (ProRes/PCM for editing).
+	OutputFormat string
+	// FastCut stream-copies clips instead of encoding them: "copy" starts
+	// each clip at the keyframe before it, "smart" re-encodes up to the
+	// first keyframe to keep the exact start. Empty encodes every clip.
+	FastCut string
+	// RenderJobs bounds concurrent clip renders; <= 1 renders one by one.
+	RenderJobs int
 
 	// CacheDir is the base directory for local artifacts (audio, transcripts, etc.).
 	// If empty, defaults to ".cache".
 	CacheDir string
+	// RefreshCache ignores a cached transcript and re-runs transcription,
+	// replacing the cached entry.
+	RefreshCache bool
+	// NoCache disables reading and writing the transcript cache.
+	NoCache bool
+	// RunDir, when set, continues an existing run directory (see LoadRunState)
+	// instead of creating a new one; completed stages are not redone.
+	RunDir string
 
 	FFmpegPath  string
 	FFprobePath string
 
-	WhisperBin   string
+	WhisperBin string
+	// WhisperModel is a registry name (base, small, large-v3, ...; see
+	// whispercpp.Models) looked up in ModelsDir, or the path of a model file.
 	WhisperModel string
+	// ModelsDir holds the registry models; empty means CacheDir/models.
+	ModelsDir string
+	// Language is the spoken language passed to whisper (en, es, ...);
+	// empty or "auto" detects it. Translate transcribes into English.
+	Language  string
+	Translate bool
+	// TranscribeChunk, when set, transcribes the audio in chunks of about
+	// this length cut at silences, TranscribeWorkers at a time; chunks are
+	// cached as they finish. WhisperThreads is whisper's -t per process
+	// (0 = whisper's default, or the CPUs split between workers).
+	TranscribeChunk   time.Duration
+	TranscribeWorkers int
+	WhisperThreads    int
+	// TranscriptPath imports an existing transcript (SRT, WebVTT,
+	// whisper.cpp or verbose JSON, JSONL) instead of running whisper.
+	TranscriptPath string
+	// Diarize labels speakers: whispercpp.DiarizeTinydiarize (needs a tdrz
+	// model), whispercpp.DiarizeStereo (one speaker per channel) or empty.
+	Diarize string
+
+	// Ranker selects how clips are chosen from candidates: RankerOpenRouter
+	// (default), RankerOpenAI for any OpenAI-compatible server, or
+	// RankerHeuristic, which needs no network or API key.
+	Ranker string
 
-	OpenRouterAPIKey       string
-	OpenRouterModel        string
+	OpenRouterAPIKey string
+	OpenRouterModel  string
+	// OpenRouterModels, when set, replaces OpenRouterModel with an ordered
+	// chain of models; later ones are fallbacks for a failing model.
+	OpenRouterModels       []string
 	OpenRouterBaseURL      string
 	OpenRouterAllowedHosts []string
+
+	OpenAIBaseURL    string
+	OpenAIAPIKey     string
+	OpenAIModel      string
+	OpenAIAuthHeader string
+	// OpenAIResponseFormat is auto, json_schema, json_object or none.
+	OpenAIResponseFormat string
+	// OpenAIAllowHTTPLoopback permits plain http to loopback hosts.
+	OpenAIAllowHTTPLoopback bool
+
+	// LLM retry policy per model; zero values use the adapter defaults.
+	LLMMaxAttempts    int
+	LLMRetryBaseDelay time.Duration
+	LLMRetryMaxDelay  time.Duration
+
+	// LLMChunkWindow enables map-reduce selection over transcript windows of
+	// this length (0 = single request); LLMChunkConcurrency bounds parallel
+	// window requests.
+	LLMChunkWindow      time.Duration
+	LLMChunkConcurrency int
 }
 
+// Clip rankers accepted in Config.Ranker.
+const (
+	RankerOpenRouter = "openrouter"
+	RankerOpenAI     = "openai"
+	RankerHeuristic  = "heuristic"
+)
+
 const (
 	autoClipWindow   = 5 * time.Minute
 	autoClipMaxLimit = 80
 )
 
 func (c Config) Validate() error {
-	if c.InputMP4 == "" {
+	if err := c.validateInput(); err != nil {
+		return err
+	}
+	if c.ClipsN <= 0 {
+		return fmt.Errorf("clips must be > 0")
+	}
+	if err := c.validateDurations(); err != nil {
+		return err
+	}
+	if err := c.validateASR(); err != nil {
+		return err
+	}
+	if _, err := c.frame(); err != nil {
+		return err
+	}
+	if _, err := subtitles.ParseFormats(c.SubtitleFormats); err != nil {
+		return err
+	}
+	if _, err := c.captionOptions(); err != nil {
+		return err
+	}
+	if c.TightenPause < 0 {
+		return errors.New("tighten pause must be >= 0")
+	}
+	if err := c.validateAudio(); err != nil {
+		return err
+	}
+	if err := c.validateAudiogram(); err != nil {
+		return err
+	}
+	if err := ffmpeg.CheckFormat(c.OutputFormat); err != nil {
+		return err
+	}
+	if err := c.validateFastCut(); err != nil {
+		return err
+	}
+	return c.validateLLM()
+}
+
+// validateFastCut checks the fast cut mode. Copied streams keep their codecs,
+// which webm cannot hold for typical h264/aac sources.
+func (c Config) validateFastCut() error {
+	switch c.FastCut {
+	case "":
+		return nil
+	case types.FastCutCopy, types.FastCutSmart:
+	default:
+		return fmt.Errorf("unknown fast cut mode %q (want %s or %s)", c.FastCut, types.FastCutCopy, types.FastCutSmart)
+	}
+	if c.OutputFormat == ffmpeg.FormatWebM {
+		return fmt.Errorf("fast cut needs mp4 or mov output, got %s", c.OutputFormat)
+	}
+	return nil
+}
+
+// captionOptions resolves SubtitleStyle and CaptionMode and checks FontsDir.
+// A style ending in .ass or containing a path separator is read as a style
+// file.
+func (c Config) captionOptions() (subtitles.Options, error) {
+	mode, err := subtitles.ParseCaptionMode(c.CaptionMode)
+	if err != nil {
+		return subtitles.Options{}, err
+	}
+	if c.FontsDir != "" {
+		st, err := os.Stat(c.FontsDir)
+		if err != nil {
+			return subtitles.Options{}, fmt.Errorf("fonts dir: %w", err)
+		}
+		if !st.IsDir() {
+			return subtitles.Options{}, fmt.Errorf("fonts dir %s is not a directory", c.FontsDir)
+		}
+	}
+	style := strings.TrimSpace(c.SubtitleStyle)
+	if !isStyleFile(style) {
+		if err := subtitles.CheckPreset(style); err != nil {
+			return subtitles.Options{}, err
+		}
+		return subtitles.Options{Preset: style, Mode: mode}, nil
+	}
+	b, err := os.ReadFile(style)
+	if err != nil {
+		return subtitles.Options{}, fmt.Errorf("subtitle style: %w", err)
+	}
+	sheet, err := subtitles.ParseStyleSheet(string(b))
+	if err != nil {
+		return subtitles.Options{}, fmt.Errorf("subtitle style %s: %w", style, err)
+	}
+	return subtitles.Options{Sheet: &sheet, Mode: mode}, nil
+}
+
+func isStyleFile(style string) bool {
+	return strings.EqualFold(filepath.Ext(style), ".ass") || strings.ContainsRune(style, os.PathSeparator) ||
+		strings.ContainsRune(style, '/')
+}
+
+// frame resolves the output geometry of rendered clips.
+func (c Config) frame() (types.Frame, error) {
+	size, err := framing.ParseResolution(c.Resolution)
+	if err != nil {
+		return types.Frame{}, err
+	}
+	return framing.Resolve(c.Aspect, c.Reframe, size)
+}
+
+func (c Config) validateAudio() error {
+	if c.LoudnessLUFS != 0 && (c.LoudnessLUFS < -70 || c.LoudnessLUFS > -5) {
+		return fmt.Errorf("loudness target %g LUFS is out of range (want -70..-5)", c.LoudnessLUFS)
+	}
+	if c.AudioFade < 0 || c.AudioFade > 2*time.Second {
+		return fmt.Errorf("audio fade %s is out of range (want 0..2s)", c.AudioFade)
+	}
+	return nil
+}
+
+func (c Config) audio() types.AudioOptions {
+	return types.AudioOptions{LoudnessLUFS: c.LoudnessLUFS, Fade: c.AudioFade}
+}
+
+var reColor = regexp.MustCompile(`^((#|0x)?[0-9A-Fa-f]{6}([0-9A-Fa-f]{2})?|[A-Za-z]+)$`)
+
+func (c Config) validateAudiogram() error {
+	if c.Cover != "" {
+		st, err := os.Stat(c.Cover)
+		if err != nil {
+			return fmt.Errorf("cover: %w", err)
+		}
+		if !st.Mode().IsRegular() {
+			return fmt.Errorf("cover %s is not a file", c.Cover)
+		}
+	}
+	if c.Background != "" && !reColor.MatchString(c.Background) {
+		return fmt.Errorf("invalid background color %q (want #RRGGBB or a color name)", c.Background)
+	}
+	return nil
+}
+
+// audiogram returns the audiogram settings for audio-only inputs (nil for
+// video) and the frame to render them in: the configured one, or a square
+// frame when none was asked for. Video inputs keep frame unchanged.
+func (c Config) audiogram(info types.MediaInfo, frame types.Frame) (*types.Audiogram, types.Frame, error) {
+	if _, ok := info.Video(); ok {
+		return nil, frame, nil
+	}
+	frame, err := c.audiogramFrame(frame)
+	if err != nil {
+		return nil, types.Frame{}, err
+	}
+	title := strings.TrimSpace(c.EpisodeTitle)
+	if title == "" {
+		title = info.Title
+	}
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(c.InputPath), filepath.Ext(c.InputPath))
+	}
+	ag := &types.Audiogram{Cover: c.Cover, Background: c.Background, Title: title}
+	c.logger()("audio-only input: rendering audiograms (%dx%d, title %q)", frame.Width, frame.Height, title)
+	return ag, frame, nil
+}
+
+// audiogramFrame is the frame of audiogram clips; without an aspect they
+// are square.
+func (c Config) audiogramFrame(frame types.Frame) (types.Frame, error) {
+	if frame.Reframes() {
+		return frame, nil
+	}
+	size, err := framing.ParseResolution(c.Resolution)
+	if err != nil {
+		return types.Frame{}, err
+	}
+	return framing.Resolve("1:1", "", size)
+}
+
+func (c Config) validateInput() error {
+	if c.InputPath == "" {
 		return errors.New("input is empty")
 	}
-	if _, err := os.Stat(c.InputMP4); err != nil {
+	if _, err := os.Stat(c.InputPath); err != nil {
 		return fmt.Errorf("stat input: %w", err)
 	}
-	if c.ClipsN <= 0 {
-		return fmt.Errorf("clips must be > 0")
+	return nil
+}
+
+func (c Config) validateASR() error {
+	lang := c.language()
+	if lang != whispercpp.LanguageAuto && !reLanguage.MatchString(lang) {
+		return fmt.Errorf("language must be auto or a whisper language code like en or es, got %q", c.Language)
 	}
-	if c.WhisperModel == "" {
-		return fmt.Errorf("whisper model path is required")
+	if c.TranscriptPath != "" {
+		if c.Translate {
+			return errors.New("translate does not apply to an imported transcript")
+		}
+		if c.Diarize != "" {
+			return errors.New("diarize does not apply to an imported transcript; speakers come from the file")
+		}
+		if _, err := transcriptfile.Detect(c.TranscriptPath); err != nil {
+			return err
+		}
+		if _, err := os.Stat(c.TranscriptPath); err != nil {
+			return fmt.Errorf("stat transcript: %w", err)
+		}
+		return nil
 	}
-	return openrouter.ValidateBaseURL(
-		c.OpenRouterBaseURL,
-		c.OpenRouterAllowedHosts,
-	)
+	m, err := c.whisperModel()
+	if err != nil {
+		return err
+	}
+	if r, ok := m.registry(); ok && r.EnglishOnly() && (c.Translate || (lang != whispercpp.LanguageAuto && lang != "en")) {
+		return fmt.Errorf("whisper model %s is English-only; use a multilingual model for --language %s or --translate", r.Name, lang)
+	}
+	switch c.Diarize {
+	case "", whispercpp.DiarizeStereo:
+	case whispercpp.DiarizeTinydiarize:
+		if r, ok := m.registry(); ok && !r.Tinydiarize() {
+			return fmt.Errorf("diarize tdrz needs a tinydiarize model such as small.en-tdrz, got %s", r.Name)
+		}
+	default:
+		return fmt.Errorf("unknown diarize mode %q (want %s or %s)", c.Diarize, whispercpp.DiarizeTinydiarize, whispercpp.DiarizeStereo)
+	}
+	if c.TranscribeChunk != 0 && c.TranscribeChunk < minTranscribeChunk {
+		return fmt.Errorf("transcribe chunk must be 0 or at least %s", minTranscribeChunk)
+	}
+	if c.TranscribeWorkers < 0 || c.WhisperThreads < 0 {
+		return errors.New("transcribe workers and whisper threads must be >= 0")
+	}
+	return nil
 }
 
-func Run(ctx context.Context, cfg Config) error {
-	logf := cfg.Logf
-	if logf == nil {
-		logf = func(string, ...any) {}
+// stereoAudio reports whether whisper gets both audio channels, which its
+// stereo diarization compares.
+func (c Config) stereoAudio() bool {
+	return c.Diarize == whispercpp.DiarizeStereo && c.TranscriptPath == ""
+}
+
+// minTranscribeChunk keeps chunks long enough for whisper's 30s window and
+// for a silence to cut at.
+const minTranscribeChunk = time.Minute
+
+// whisperModel is a resolved WhisperModel: its name (the registry name, also
+// for a path to a registry file, or else the file name) and its path.
+type whisperModel struct {
+	name, path string
+}
+
+// registry returns the registry entry of a model chosen by name.
+func (m whisperModel) registry() (whispercpp.Model, bool) {
+	return whispercpp.LookupModel(m.name)
+}
+
+// whisperModel resolves WhisperModel against the registry.
+func (c Config) whisperModel() (whisperModel, error) {
+	switch {
+	case c.WhisperModel == "":
+		return whisperModel{}, errors.New("whisper model is required")
+	case whispercpp.IsModelPath(c.WhisperModel):
+		name := filepath.Base(c.WhisperModel)
+		if r, ok := whispercpp.ModelForFile(c.WhisperModel); ok {
+			name = r.Name
+		}
+		return whisperModel{name: name, path: c.WhisperModel}, nil
+	}
+	r, ok := whispercpp.LookupModel(c.WhisperModel)
+	if !ok {
+		return whisperModel{}, whispercpp.UnknownModelError(c.WhisperModel)
+	}
+	return whisperModel{name: r.Name, path: filepath.Join(c.modelsDir(), r.File())}, nil
+}
+
+func (c Config) modelsDir() string {
+	if c.ModelsDir != "" {
+		return c.ModelsDir
+	}
+	base := c.CacheDir
+	if base == "" {
+		base = ".cache"
+	}
+	return filepath.Join(base, "models")
+}
+
+// reLanguage matches whisper's language codes (en, es, haw, yue, ...).
+var reLanguage = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// language is the whisper language; empty means auto.
+func (c Config) language() string {
+	if c.Language == "" {
+		return whispercpp.LanguageAuto
 	}
+	return c.Language
+}
+
+func (c Config) validateLLM() error {
+	if maxClip := c.ClipDurations().Max; c.LLMChunkWindow != 0 && c.LLMChunkWindow < maxClip {
+		return fmt.Errorf("chunk window must be 0 or at least %s", maxClip)
+	}
+	switch c.Ranker {
+	case "", RankerOpenRouter:
+		if c.LLMMaxAttempts < 0 {
+			return errors.New("LLM max attempts must be >= 0")
+		}
+		return openrouter.ValidateBaseURL(
+			c.OpenRouterBaseURL,
+			c.OpenRouterAllowedHosts,
+		)
+	case RankerOpenAI:
+		if c.LLMMaxAttempts < 0 {
+			return errors.New("LLM max attempts must be >= 0")
+		}
+		if err := openai.ValidateBaseURL(c.OpenAIBaseURL, c.OpenAIAllowHTTPLoopback); err != nil {
+			return err
+		}
+		if _, err := chatcompletion.ParseResponseFormat(c.OpenAIResponseFormat); err != nil {
+			return fmt.Errorf("OPENAI_RESPONSE_FORMAT: %w", err)
+		}
+		if c.OpenAIModel == "" {
+			return errors.New("OPENAI_MODEL is required for the openai ranker")
+		}
+		return nil
+	case RankerHeuristic:
+		return nil
+	default:
+		return fmt.Errorf(
+			"unknown ranker %q (want %s, %s or %s)",
+			c.Ranker, RankerOpenRouter, RankerOpenAI, RankerHeuristic,
+		)
+	}
+}
 
-	// adapters
+func Run(ctx context.Context, cfg Config) error {
+	logf := cfg.logger()
 	v := ffmpeg.New(cfg.FFmpegPath, cfg.FFprobePath)
-	asr := whispercpp.New(cfg.WhisperBin, cfg.WhisperModel)
-	llm := openrouter.New(cfg.OpenRouterAPIKey, cfg.OpenRouterModel, cfg.OpenRouterBaseURL)
+	uc := usecase.New(newDeps(cfg, v))
+
+	src, err := cfg.probeInput(ctx, v)
+	if err != nil {
+		return err
+	}
+	cfg.AudioTrack = src.AudioTrack
 
 	clipsN := cfg.ClipsN
 	if !cfg.ClipsNSet {
-		videoDur, err := v.ProbeDuration(ctx, cfg.InputMP4)
-		if err != nil {
-			logf("duration probe failed, keeping clip cap %d: %v", clipsN, err)
-		} else {
-			clipsN = autoClipCount(clipsN, videoDur)
-			if clipsN != cfg.ClipsN {
-				logf("auto clips cap from duration: %s => %d", formatDuration(videoDur), clipsN)
-			}
+		clipsN = autoClipCount(clipsN, src.Duration())
+		if clipsN != cfg.ClipsN {
+			logf("auto clips cap from duration: %s => %d", formatDuration(src.Duration()), clipsN)
+		}
+	}
+
+	frame, err := cfg.frame()
+	if err != nil {
+		return err
+	}
+	formats, err := subtitles.ParseFormats(cfg.SubtitleFormats)
+	if err != nil {
+		return err
+	}
+	captions, err := cfg.captionOptions()
+	if err != nil {
+		return err
+	}
+	ag, frame, err := cfg.audiogram(src.MediaInfo, frame)
+	if err != nil {
+		return err
+	}
+	var model whisperModel
+	if cfg.TranscriptPath == "" {
+		if model, err = cfg.whisperModel(); err != nil {
+			return err
+		}
+	}
+	if tracks := src.AudioTracks(); cfg.stereoAudio() && src.AudioTrack < len(tracks) && tracks[src.AudioTrack].Channels == 1 {
+		logf("warning: diarize stereo needs one speaker per channel, but the audio is mono; no speakers will be labeled")
+	}
+
+	logf("preparing workspace")
+	cacheDir, transcriptCache, err := prepareCache(cfg)
+	if err != nil {
+		return err
+	}
+
+	runOutDir := cfg.RunDir
+	if runOutDir == "" {
+		runOutDir = newRunOutDir(cfg)
+		if err := saveRunState(runOutDir, cfg.runState(clipsN, formats, frame)); err != nil {
+			return err
 		}
 	}
+	if err := prepareRunOutDir(cfg, runOutDir); err != nil {
+		return err
+	}
+
+	res, err := uc.Run(ctx, usecase.Input{
+		InputPath:       cfg.InputPath,
+		ClipsN:          clipsN,
+		Durations:       cfg.ClipDurations(),
+		BurnSubtitles:   cfg.BurnSubtitles,
+		SubtitleFormats: formats,
+		Captions:        captions,
+		FontsDir:        cfg.FontsDir,
+		Frame:           frame,
+		Tighten:         cfg.Tighten,
+		TightenPause:    cfg.TightenPause,
+		Audio:           cfg.audio(),
+		AudioTrack:      cfg.AudioTrack,
+		StereoAudio:     cfg.stereoAudio(),
+		Format:          cfg.OutputFormat,
+		FastCut:         cfg.FastCut,
+		MaxVideoKbps:    cfg.videoKbpsCap(),
+		MaxFileSizeMB:   cfg.MaxFileSizeMB,
+		Source:          &src,
+		WhisperModel:    model.name,
+		Audiogram:       ag,
+		Jobs:            cfg.RenderJobs,
+		CacheDir:        cacheDir,
+		OutDir:          runOutDir,
+		Logf:            logf,
+
+		TranscriptCache:   transcriptCache,
+		RefreshTranscript: cfg.RefreshCache,
+		CheckpointDir:     filepath.Join(runOutDir, checkpointsDir),
+	})
+	if err != nil {
+		return err
+	}
+	return writeManifest(runOutDir, res.Manifest, logf)
+}
+
+func (c Config) logger() func(string, ...any) {
+	if c.Logf == nil {
+		return func(string, ...any) {}
+	}
+	return c.Logf
+}
 
-	deps := usecase.Deps{
+func newDeps(cfg Config, v *ffmpeg.Adapter) usecase.Deps {
+	return usecase.Deps{
 		Video: v,
-		ASR:   asr,
-		LLM:   llm,
+		ASR:   newASR(cfg),
+		LLM:   newRanker(cfg),
+	}
+}
+
+func newASR(cfg Config) ports.ASR {
+	if cfg.TranscriptPath != "" {
+		// Only a language given explicitly fills in the imported one.
+		lang := cfg.language()
+		if lang == whispercpp.LanguageAuto {
+			lang = ""
+		}
+		return transcriptfile.New(cfg.TranscriptPath, lang)
+	}
+	// Stages that transcribe validate the model first; the others do not use it.
+	model, _ := cfg.whisperModel()
+	return whispercpp.New(whispercpp.Config{
+		Bin:       cfg.WhisperBin,
+		Model:     model.path,
+		Language:  cfg.Language,
+		Translate: cfg.Translate,
+		Diarize:   cfg.Diarize,
+		Threads:   cfg.WhisperThreads,
+		Chunking: whispercpp.Chunking{
+			Length:  cfg.TranscribeChunk,
+			Workers: cfg.TranscribeWorkers,
+			// Chunks are a partial transcript cache and follow its flags.
+			Refresh: cfg.RefreshCache || cfg.NoCache,
+		},
+		FFmpeg: cfg.FFmpegPath,
+		Logf:   cfg.logger(),
+	})
+}
+
+func newRanker(cfg Config) ports.LLMRanker {
+	switch cfg.Ranker {
+	case RankerHeuristic:
+		return heuristic.New()
+	case RankerOpenAI:
+		// Validated in validateLLM; an invalid value falls back to auto.
+		format, _ := chatcompletion.ParseResponseFormat(cfg.OpenAIResponseFormat)
+		return openai.New(openai.Config{
+			BaseURL:    cfg.OpenAIBaseURL,
+			APIKey:     cfg.OpenAIAPIKey,
+			Model:      cfg.OpenAIModel,
+			AuthHeader: cfg.OpenAIAuthHeader,
+			Format:     format,
+			Retry:      cfg.retryPolicy(),
+			Chunking:   cfg.chunking(),
+			Logf:       cfg.logger(),
+		})
+	default:
+		models := cfg.OpenRouterModels
+		if len(models) == 0 {
+			models = []string{cfg.OpenRouterModel}
+		}
+		return openrouter.New(openrouter.Config{
+			APIKey:   cfg.OpenRouterAPIKey,
+			BaseURL:  cfg.OpenRouterBaseURL,
+			Models:   models,
+			Retry:    cfg.retryPolicy(),
+			Chunking: cfg.chunking(),
+			Logf:     cfg.logger(),
+		})
 	}
+}
 
-	uc := usecase.New(deps)
+func (c Config) chunking() chatcompletion.Chunking {
+	return chatcompletion.Chunking{Window: c.LLMChunkWindow, Concurrency: c.LLMChunkConcurrency}
+}
 
-	jobID := hash(cfg.InputMP4)
+func (c Config) retryPolicy() chatcompletion.RetryPolicy {
+	return chatcompletion.RetryPolicy{
+		MaxAttempts: c.LLMMaxAttempts,
+		BaseDelay:   c.LLMRetryBaseDelay,
+		MaxDelay:    c.LLMRetryMaxDelay,
+	}
+}
+
+// prepareCache creates the per-input cache directory and returns it along
+// with the transcript cache path ("" when caching is disabled).
+func prepareCache(cfg Config) (string, string, error) {
+	logf := cfg.logger()
 	baseCache := cfg.CacheDir
 	if baseCache == "" {
 		baseCache = ".cache"
 	}
+	jobID := cacheJobID(cfg, baseCache, logf)
 	cacheDir := filepath.Join(baseCache, "runs", jobID)
-	logf("preparing workspace")
 	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
-		return err
+		return "", "", err
 	}
 	logf("cache: %s", cacheDir)
+	if cfg.TranscriptPath != "" {
+		// Parsing is cheap, and the file may be edited between runs.
+		return cacheDir, "", nil
+	}
+	if cfg.NoCache {
+		logf("cache: transcript cache disabled")
+		return cacheDir, "", nil
+	}
+	return cacheDir, filepath.Join(cacheDir, "transcript.json"), nil
+}
 
+func newRunOutDir(cfg Config) string {
 	outDir := cfg.OutDir
 	if outDir == "" {
 		outDir = "out"
 	}
-	runOutDir := buildRunOutDir(outDir, cfg.InputMP4, time.Now().UTC())
+	return buildRunOutDir(outDir, cfg.InputPath, time.Now().UTC())
+}
+
+func prepareRunOutDir(cfg Config, runOutDir string) error {
+	logf := cfg.logger()
 	clipsDir := filepath.Join(runOutDir, "clips")
 	subtitlesDir := filepath.Join(runOutDir, "subtitles")
 	if err := os.MkdirAll(clipsDir, 0o755); err != nil {
 		return err
 	}
-	if cfg.BurnSubtitles {
+	if cfg.BurnSubtitles || len(cfg.SubtitleFormats) > 0 {
 		if err := os.MkdirAll(subtitlesDir, 0o755); err != nil {
 			return err
 		}
@@ -133,20 +739,11 @@ func Run(ctx context.Context, cfg Config) error {
 		logf("output run dir: %s", runOutDir)
 		logf("output dirs: %s", clipsDir)
 	}
+	return nil
+}
 
-	res, err := uc.Run(ctx, usecase.Input{
-		InputMP4:      cfg.InputMP4,
-		ClipsN:        clipsN,
-		BurnSubtitles: cfg.BurnSubtitles,
-		CacheDir:      cacheDir,
-		OutDir:        runOutDir,
-		Logf:          logf,
-	})
-	if err != nil {
-		return err
-	}
-
-	b, err := json.MarshalIndent(res.Manifest, "", "  ")
+func writeManifest(runOutDir string, m types.Manifest, logf func(string, ...any)) error {
+	b, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal manifest: %w", err)
 	}
@@ -154,18 +751,59 @@ func Run(ctx context.Context, cfg Config) error {
 	if err := os.WriteFile(manifestPath, b, 0o644); err != nil {
 		return err
 	}
-	logf("manifest written (%d clips): %s", len(res.Manifest.Clips), manifestPath)
+	logf("manifest written (%d clips): %s", len(m.Clips), manifestPath)
 	return nil
 }
 
-func buildRunOutDir(outRoot, inputMP4 string, now time.Time) string {
-	name := strings.TrimSuffix(filepath.Base(inputMP4), filepath.Ext(inputMP4))
+// cacheJobID keys the per-input cache directory on input content and whisper
+// model identity, so renamed or re-copied inputs still hit the cache while an
+// edited file or a different model does not. Inputs that cannot be hashed fall
+// back to a path-derived key and fail later with the real media error.
+func cacheJobID(cfg Config, baseCache string, logf func(string, ...any)) string {
+	indexDir := filepath.Join(baseCache, "digests")
+	inputDigest, err := fileDigest(cfg.InputPath, indexDir)
+	if err != nil {
+		logf("cache: input digest unavailable, using path key: %v", err)
+		return hash(cfg.InputPath)
+	}
+	model, _ := cfg.whisperModel()
+	identity := []string{"asr=whisper.cpp", "model=" + modelIdentity(model, indexDir, logf)}
+	if model.name != whispercpp.DefaultModel {
+		// The default model keeps the key of caches written before model
+		// selection.
+		identity = append(identity, "model_name="+model.name)
+	}
+	if cfg.AudioTrack > 0 {
+		// Track 0 keeps the key of caches written before track selection.
+		identity = append(identity, fmt.Sprintf("audio=%d", cfg.AudioTrack))
+	}
+	// Auto-detection keeps the key of caches written before language
+	// selection.
+	if lang := cfg.language(); lang != whispercpp.LanguageAuto {
+		identity = append(identity, "lang="+lang)
+	}
+	if cfg.Translate {
+		identity = append(identity, "translate")
+	}
+	if cfg.TranscribeChunk > 0 {
+		// Chunk boundaries shift segmentation slightly, so chunked and
+		// whole-file transcripts are cached apart.
+		identity = append(identity, "chunk="+cfg.TranscribeChunk.String())
+	}
+	if cfg.Diarize != "" {
+		identity = append(identity, "diarize="+cfg.Diarize)
+	}
+	return transcriptCacheKey(inputDigest, identity...)
+}
+
+func buildRunOutDir(outRoot, input string, now time.Time) string {
+	name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
 	name = normalizePathSegment(name)
 	if name == "" {
 		name = "input"
 	}
 	ts := now.UTC().UnixMilli()
-	runSeed := fmt.Sprintf("%s|%d", inputMP4, now.UTC().UnixNano())
+	runSeed := fmt.Sprintf("%s|%d", input, now.UTC().UnixNano())
 	suffix := hash(runSeed)[:6]
 	return filepath.Join(outRoot, fmt.Sprintf("%013d-%s-%s", ts, name, suffix))
 }
@@ -222,3 +860,5 @@ func formatDuration(d time.Duration) string {
 var _ ports.VideoTool = (*ffmpeg.Adapter)(nil)
 var _ ports.ASR = (*whispercpp.Adapter)(nil)
 var _ ports.LLMRanker = (*openrouter.Adapter)(nil)
+var _ ports.LLMRanker = (*openai.Adapter)(nil)
+var _ ports.LLMRanker = (*heuristic.Adapter)(nil)
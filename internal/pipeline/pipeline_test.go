@@ -2,11 +2,14 @@ package pipeline
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
 )
 
 func TestBuildRunOutDir(t *testing.T) {
@@ -112,3 +115,377 @@ func TestAutoClipCount(t *testing.T) {
 		})
 	}
 }
+
+func TestRunStateRoundTrip(t *testing.T) {
+	runDir := t.TempDir()
+	if _, err := LoadRunState(runDir); err == nil {
+		t.Fatalf("expected error for run dir without state")
+	}
+
+	want := RunState{Input: "/tmp/in.mp4", ClipsN: 7, BurnSubtitles: true, Ranker: RankerHeuristic, Aspect: "9:16", Reframe: "blur-pad",
+		SubtitleStyle: "top-title", TranscribeChunkSec: 600, TranscribeWorkers: 4, WhisperThreads: 3,
+		ChunkWindowSec: 1200, ChunkConcurrency: 2, LLMMaxAttempts: 5, LLMRetryBaseSec: 0.5, LLMRetryMaxSec: 8}
+	if err := saveRunState(runDir, want); err != nil {
+		t.Fatalf("save run state: %v", err)
+	}
+	got, err := LoadRunState(runDir)
+	if err != nil {
+		t.Fatalf("load run state: %v", err)
+	}
+	if got.Input != want.Input || got.ClipsN != want.ClipsN || got.BurnSubtitles != want.BurnSubtitles || got.Ranker != want.Ranker ||
+		got.Aspect != want.Aspect || got.Reframe != want.Reframe || got.SubtitleStyle != want.SubtitleStyle ||
+		got.TranscribeChunkSec != want.TranscribeChunkSec || got.TranscribeWorkers != want.TranscribeWorkers ||
+		got.WhisperThreads != want.WhisperThreads || got.ChunkWindowSec != want.ChunkWindowSec ||
+		got.ChunkConcurrency != want.ChunkConcurrency || got.LLMMaxAttempts != want.LLMMaxAttempts ||
+		got.LLMRetryBaseSec != want.LLMRetryBaseSec || got.LLMRetryMaxSec != want.LLMRetryMaxSec {
+		t.Fatalf("unexpected run state: %+v", got)
+	}
+}
+
+func TestRunStateApply(t *testing.T) {
+	st := RunState{Input: "/tmp/in.mp4", ClipsN: 7, TranscribeChunkSec: 600, TranscribeWorkers: 4, WhisperThreads: 3,
+		ChunkWindowSec: 1200, ChunkConcurrency: 2, LLMRetryMaxSec: 8, Diarize: "stereo", TightenPauseSec: 0.4}
+	cfg := Config{Ranker: RankerOpenRouter, WhisperModel: "base", LLMMaxAttempts: 3}
+	st.Apply(&cfg)
+
+	// Zero ranker, model and retry values keep the defaults.
+	if cfg.Ranker != RankerOpenRouter || cfg.WhisperModel != "base" || cfg.LLMMaxAttempts != 3 {
+		t.Fatalf("expected defaults to be kept: %+v", cfg)
+	}
+	if cfg.TranscribeChunk != 10*time.Minute || cfg.TranscribeWorkers != 4 || cfg.WhisperThreads != 3 {
+		t.Fatalf("unexpected transcription settings: %s, %d, %d", cfg.TranscribeChunk, cfg.TranscribeWorkers, cfg.WhisperThreads)
+	}
+	if cfg.LLMChunkWindow != 20*time.Minute || cfg.LLMChunkConcurrency != 2 || cfg.LLMRetryMaxDelay != 8*time.Second {
+		t.Fatalf("unexpected LLM settings: %s, %d, %s", cfg.LLMChunkWindow, cfg.LLMChunkConcurrency, cfg.LLMRetryMaxDelay)
+	}
+	if cfg.Diarize != "stereo" || cfg.TightenPause != 400*time.Millisecond {
+		t.Fatalf("unexpected settings: %q, %s", cfg.Diarize, cfg.TightenPause)
+	}
+	// The input and clip count are the caller's.
+	if cfg.InputPath != "" || cfg.ClipsN != 0 {
+		t.Fatalf("expected input and clip count to be left alone: %q, %d", cfg.InputPath, cfg.ClipsN)
+	}
+}
+
+func TestConfigCaptionOptions(t *testing.T) {
+	dir := t.TempDir()
+	stylePath := filepath.Join(dir, "brand.ass")
+	sheet := "[V4+ Styles]\n" +
+		"Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n" +
+		"Style: Brand, Roboto, 70, &H00FFFFFF, &H0000FFFF, &H00000000, &H00000000, 1,0,0,0,100,100,0,0,1,4,0,2, 60,60,90,1\n"
+	if err := os.WriteFile(stylePath, []byte(sheet), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	badPath := filepath.Join(dir, "bad.ass")
+	if err := os.WriteFile(badPath, []byte("[V4+ Styles]\nFormat: Name\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := Config{SubtitleStyle: "boxed", CaptionMode: "pop", FontsDir: dir}.captionOptions()
+	if err != nil || opts.Preset != "boxed" || opts.Sheet != nil || opts.Mode != "pop" {
+		t.Fatalf("unexpected preset options: %+v, %v", opts, err)
+	}
+	opts, err = Config{SubtitleStyle: stylePath}.captionOptions()
+	if err != nil || opts.Sheet == nil || opts.Sheet.Caption.Fontname != "Roboto" {
+		t.Fatalf("unexpected style file options: %+v, %v", opts, err)
+	}
+
+	for _, cfg := range []Config{
+		{SubtitleStyle: "neon"},
+		{CaptionMode: "typewriter"},
+		{SubtitleStyle: badPath},
+		{SubtitleStyle: filepath.Join(dir, "missing.ass")},
+		{FontsDir: stylePath},
+	} {
+		if _, err := cfg.captionOptions(); err == nil {
+			t.Fatalf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestConfigAudiogram(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.png")
+	if err := os.WriteFile(cover, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, cfg := range []Config{{}, {Cover: cover, Background: "#1A2B3C"}, {Background: "0x101820"}, {Background: "navy"}} {
+		if err := cfg.validateAudiogram(); err != nil {
+			t.Fatalf("unexpected error for %+v: %v", cfg, err)
+		}
+	}
+	for _, cfg := range []Config{{Cover: dir}, {Cover: filepath.Join(dir, "missing.png")}, {Background: "#12345"}, {Background: "red:s=1x1"}} {
+		if err := cfg.validateAudiogram(); err == nil {
+			t.Fatalf("expected error for %+v", cfg)
+		}
+	}
+
+	f, err := Config{Resolution: "720p"}.audiogramFrame(types.Frame{})
+	if err != nil || f.Aspect != "1:1" || f.Width != 720 || f.Height != 720 {
+		t.Fatalf("expected a square default frame, got %+v, %v", f, err)
+	}
+	vertical := types.Frame{Aspect: "9:16", Mode: "crop-center", Width: 1080, Height: 1920}
+	if f, err := (Config{}).audiogramFrame(vertical); err != nil || f != vertical {
+		t.Fatalf("expected the configured frame, got %+v, %v", f, err)
+	}
+}
+
+func TestCheckMedia(t *testing.T) {
+	video := types.MediaStream{Index: 0, Type: "video", Codec: "h264", Width: 1920, Height: 1080}
+	eng := types.MediaStream{Index: 1, Type: "audio", Codec: "aac", Language: "eng", Channels: 2}
+	spa := types.MediaStream{Index: 2, Type: "audio", Codec: "ac3", Language: "spa", Channels: 6, Default: true}
+	media := func(streams ...types.MediaStream) types.MediaInfo {
+		return types.MediaInfo{DurationSec: 60, Streams: streams}
+	}
+	tests := []struct {
+		name      string
+		info      types.MediaInfo
+		track     int
+		wantTrack int
+		wantErr   string
+	}{
+		{name: "default track", info: media(video, eng, spa), track: -1, wantTrack: 1},
+		{name: "explicit track", info: media(video, eng, spa), track: 0, wantTrack: 0},
+		{name: "audio only", info: media(eng), track: -1, wantTrack: 0},
+		{name: "missing track", info: media(video, eng, spa), track: 2, wantErr: "audio track 2 does not exist (tracks: 0: eng aac 2ch, 1: spa ac3 6ch default)"},
+		{name: "no audio", info: media(video), track: -1, wantErr: "no audio track"},
+		{name: "no streams", info: media(), track: -1, wantErr: "no audio or video streams"},
+		{name: "truncated", info: types.MediaInfo{Streams: []types.MediaStream{video, eng}}, track: -1, wantErr: "may be truncated"},
+		{name: "unknown audio codec", info: media(video, types.MediaStream{Index: 1, Type: "audio"}), track: -1, wantErr: "unsupported codec"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkMedia(tt.info, tt.track)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tt.wantTrack {
+				t.Fatalf("checkMedia = %d, %v; want %d", got, err, tt.wantTrack)
+			}
+		})
+	}
+}
+
+func TestConfigValidateFastCut(t *testing.T) {
+	tests := []struct {
+		mode, format string
+		wantErr      string
+	}{
+		{mode: "", format: "webm"},
+		{mode: types.FastCutCopy, format: ""},
+		{mode: types.FastCutSmart, format: "mov"},
+		{mode: "fast", wantErr: "unknown fast cut mode"},
+		{mode: types.FastCutCopy, format: "webm", wantErr: "needs mp4 or mov"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.format, func(t *testing.T) {
+			err := Config{FastCut: tt.mode, OutputFormat: tt.format}.validateFastCut()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfigClipDurations(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		wantErr  string
+		wantKbps int
+	}{
+		{name: "defaults", cfg: Config{}},
+		{name: "custom", cfg: Config{MinClip: 10 * time.Second, MaxClip: 45 * time.Second}},
+		{name: "min over max", cfg: Config{MinClip: 4 * time.Minute}, wantErr: "must be longer than min clip"},
+		{name: "sub-second min", cfg: Config{MinClip: 500 * time.Millisecond}, wantErr: "at least 1s"},
+		{name: "bitrate only", cfg: Config{MaxVideoKbps: 8000}, wantKbps: 8000},
+		{
+			// 287 MB over 3 minutes leaves ~11.9 Mbps, above the bitrate cap.
+			name:     "size above bitrate",
+			cfg:      Config{MaxClip: 3 * time.Minute, MaxFileSizeMB: 287, MaxVideoKbps: 8000},
+			wantKbps: 8000,
+		},
+		{
+			// 100 MB over 3 minutes: 100*8000*0.95/180 - 192.
+			name:     "size below bitrate",
+			cfg:      Config{MaxClip: 3 * time.Minute, MaxFileSizeMB: 100, MaxVideoKbps: 8000},
+			wantKbps: 4030,
+		},
+		{name: "size too small", cfg: Config{MaxClip: 3 * time.Minute, MaxFileSizeMB: 1}, wantErr: "too small"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateDurations()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := tt.cfg.videoKbpsCap(); got != tt.wantKbps {
+				t.Fatalf("videoKbpsCap() = %d, want %d", got, tt.wantKbps)
+			}
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	for _, name := range Profiles() {
+		p, err := LookupProfile(name)
+		if err != nil {
+			t.Fatalf("LookupProfile(%q): %v", name, err)
+		}
+		cfg := Config{
+			MinClip: p.MinClip, MaxClip: p.MaxClip, Aspect: p.Aspect, SubtitleStyle: p.SubtitleStyle,
+			CaptionMode: p.CaptionMode, MaxFileSizeMB: p.MaxFileSizeMB, MaxVideoKbps: p.MaxVideoKbps,
+		}
+		if err := cfg.validateDurations(); err != nil {
+			t.Fatalf("profile %s: %v", name, err)
+		}
+		if _, err := cfg.frame(); err != nil {
+			t.Fatalf("profile %s: %v", name, err)
+		}
+		if _, err := cfg.captionOptions(); err != nil {
+			t.Fatalf("profile %s: %v", name, err)
+		}
+	}
+	if _, err := LookupProfile("myspace"); err == nil {
+		t.Fatalf("expected an error for an unknown profile")
+	}
+}
+
+func TestConfigValidateLanguage(t *testing.T) {
+	for lang, ok := range map[string]bool{"": true, "auto": true, "es": true, "haw": true, "spanish": false, "e": false, "EN": false} {
+		err := Config{WhisperModel: "m.bin", Language: lang}.validateASR()
+		if (err == nil) != ok {
+			t.Fatalf("validateASR(language %q) = %v, want ok=%t", lang, err, ok)
+		}
+	}
+}
+
+func TestConfigWhisperModel(t *testing.T) {
+	cases := []struct {
+		model, name, path string
+		wantErr           bool
+	}{
+		{model: "base", name: "base", path: filepath.Join(".cache", "models", "ggml-base.bin")},
+		{model: "large-v3-turbo", name: "large-v3-turbo", path: filepath.Join(".cache", "models", "ggml-large-v3-turbo.bin")},
+		{model: "/m/ggml-small.en.bin", name: "small.en", path: "/m/ggml-small.en.bin"},
+		{model: "/m/custom.bin", name: "custom.bin", path: "/m/custom.bin"},
+		{model: "huge", wantErr: true},
+		{model: "", wantErr: true},
+	}
+	for _, tc := range cases {
+		m, err := Config{WhisperModel: tc.model}.whisperModel()
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("whisperModel(%q) err = %v, want err=%t", tc.model, err, tc.wantErr)
+		}
+		if !tc.wantErr && (m.name != tc.name || m.path != tc.path) {
+			t.Fatalf("whisperModel(%q) = %+v, want %s at %s", tc.model, m, tc.name, tc.path)
+		}
+	}
+}
+
+func TestConfigValidateEnglishOnlyModel(t *testing.T) {
+	cases := []struct {
+		lang      string
+		translate bool
+		ok        bool
+	}{
+		{lang: "", ok: true},
+		{lang: "en", ok: true},
+		{lang: "es", ok: false},
+		{lang: "en", translate: true, ok: false},
+	}
+	for _, tc := range cases {
+		err := Config{WhisperModel: "base.en", Language: tc.lang, Translate: tc.translate}.validateASR()
+		if (err == nil) != tc.ok {
+			t.Fatalf("validateASR(base.en, %q, translate=%t) = %v, want ok=%t", tc.lang, tc.translate, err, tc.ok)
+		}
+	}
+}
+
+func TestConfigValidateTranscribeChunk(t *testing.T) {
+	cases := []struct {
+		chunk            time.Duration
+		workers, threads int
+		ok               bool
+	}{
+		{ok: true},
+		{chunk: 10 * time.Minute, workers: 4, threads: 2, ok: true},
+		{chunk: 30 * time.Second, ok: false},
+		{chunk: 10 * time.Minute, workers: -1, ok: false},
+		{threads: -1, ok: false},
+	}
+	for _, tc := range cases {
+		cfg := Config{WhisperModel: "m.bin", TranscribeChunk: tc.chunk, TranscribeWorkers: tc.workers, WhisperThreads: tc.threads}
+		if err := cfg.validateASR(); (err == nil) != tc.ok {
+			t.Fatalf("validateASR(%+v) = %v, want ok=%t", tc, err, tc.ok)
+		}
+	}
+}
+
+func TestConfigValidateTranscriptImport(t *testing.T) {
+	tmp := t.TempDir()
+	srt := filepath.Join(tmp, "captions.srt")
+	if err := os.WriteFile(srt, []byte("1\n00:00:00,000 --> 00:00:01,000\nhi\n"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	cases := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		// No whisper model is needed for an imported transcript.
+		{name: "srt", cfg: Config{TranscriptPath: srt}, ok: true},
+		{name: "missing", cfg: Config{TranscriptPath: filepath.Join(tmp, "none.vtt")}},
+		{name: "unsupported", cfg: Config{TranscriptPath: filepath.Join(tmp, "captions.txt")}},
+		{name: "translate", cfg: Config{TranscriptPath: srt, Translate: true}},
+	}
+	for _, tc := range cases {
+		if err := tc.cfg.validateASR(); (err == nil) != tc.ok {
+			t.Fatalf("%s: validateASR = %v, want ok=%t", tc.name, err, tc.ok)
+		}
+	}
+}
+
+func TestConfigValidateDiarize(t *testing.T) {
+	tmp := t.TempDir()
+	vtt := filepath.Join(tmp, "captions.vtt")
+	if err := os.WriteFile(vtt, []byte("WEBVTT\n\n00:00.000 --> 00:01.000\n<v Ann>hi\n"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	cases := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{name: "off", cfg: Config{WhisperModel: "base"}, ok: true},
+		{name: "stereo", cfg: Config{WhisperModel: "base", Diarize: "stereo"}, ok: true},
+		{name: "tdrz model", cfg: Config{WhisperModel: "small.en-tdrz", Diarize: "tdrz"}, ok: true},
+		// A model file is not checked; it may be a tdrz model under any name.
+		{name: "tdrz file", cfg: Config{WhisperModel: "m.bin", Diarize: "tdrz"}, ok: true},
+		{name: "tdrz without tdrz model", cfg: Config{WhisperModel: "base", Diarize: "tdrz"}},
+		{name: "unknown", cfg: Config{WhisperModel: "base", Diarize: "pyannote"}},
+		{name: "imported", cfg: Config{TranscriptPath: vtt, Diarize: "stereo"}},
+		// tdrz models are English-only.
+		{name: "tdrz translate", cfg: Config{WhisperModel: "small.en-tdrz", Diarize: "tdrz", Language: "de"}},
+	}
+	for _, tc := range cases {
+		if err := tc.cfg.validateASR(); (err == nil) != tc.ok {
+			t.Fatalf("%s: validateASR = %v, want ok=%t", tc.name, err, tc.ok)
+		}
+	}
+}
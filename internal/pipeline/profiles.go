@@ -0,0 +1,151 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+	"github.com/forPelevin/hlcut/internal/domain/subtitles"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Profile bundles what a publishing platform accepts and what reads well on
+// it. Settings given explicitly take precedence over the profile's.
+type Profile struct {
+	Name string
+	// MinClip and MaxClip bound clip durations.
+	MinClip time.Duration
+	MaxClip time.Duration
+	Aspect  string
+	// SubtitleStyle and CaptionMode style the captions.
+	SubtitleStyle string
+	CaptionMode   string
+	// MaxFileSizeMB and MaxVideoKbps cap encoded clips; 0 means no limit.
+	MaxFileSizeMB int
+	MaxVideoKbps  int
+}
+
+// profiles are conservative: platform limits change, and staying well
+// inside them keeps uploads from being rejected or re-compressed hard.
+var profiles = []Profile{
+	{
+		Name:          "shorts",
+		MinClip:       15 * time.Second,
+		MaxClip:       60 * time.Second,
+		Aspect:        "9:16",
+		SubtitleStyle: subtitles.PresetTikTok,
+		CaptionMode:   subtitles.CaptionPop,
+		MaxVideoKbps:  12000,
+	},
+	{
+		Name:          "tiktok",
+		MinClip:       15 * time.Second,
+		MaxClip:       3 * time.Minute,
+		Aspect:        "9:16",
+		SubtitleStyle: subtitles.PresetTikTok,
+		CaptionMode:   subtitles.CaptionPop,
+		MaxFileSizeMB: 287,
+		MaxVideoKbps:  8000,
+	},
+	{
+		Name:          "reels",
+		MinClip:       15 * time.Second,
+		MaxClip:       90 * time.Second,
+		Aspect:        "9:16",
+		SubtitleStyle: subtitles.PresetBoxed,
+		CaptionMode:   subtitles.CaptionKaraoke,
+		MaxFileSizeMB: 1000,
+		MaxVideoKbps:  8000,
+	},
+	{
+		Name:          "linkedin",
+		MinClip:       30 * time.Second,
+		MaxClip:       3 * time.Minute,
+		Aspect:        "1:1",
+		SubtitleStyle: subtitles.PresetBoxed,
+		CaptionMode:   subtitles.CaptionKaraoke,
+		MaxFileSizeMB: 5000,
+		MaxVideoKbps:  10000,
+	},
+	{
+		Name:          "x",
+		MinClip:       15 * time.Second,
+		MaxClip:       140 * time.Second,
+		Aspect:        "16:9",
+		SubtitleStyle: subtitles.PresetMinimal,
+		CaptionMode:   subtitles.CaptionKaraoke,
+		MaxFileSizeMB: 512,
+		MaxVideoKbps:  8000,
+	},
+}
+
+// Profiles lists the platform profile names.
+func Profiles() []string {
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// LookupProfile returns the platform profile called name.
+func LookupProfile(name string) (Profile, error) {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown profile %q (want %s)", name, strings.Join(Profiles(), ", "))
+}
+
+// audioKbps is what the clip audio takes from a file size budget; the
+// largest of the output profiles' audio bitrates.
+const audioKbps = 192
+
+// ClipDurations resolves MinClip and MaxClip; unset bounds take the
+// defaults.
+func (c Config) ClipDurations() types.ClipDurations {
+	d := highlights.DefaultDurations()
+	if c.MinClip != 0 {
+		d.Min = c.MinClip
+	}
+	if c.MaxClip != 0 {
+		d.Max = c.MaxClip
+	}
+	return d
+}
+
+// validateDurations checks the clip duration bounds and the size caps.
+func (c Config) validateDurations() error {
+	if c.MinClip < 0 || c.MaxClip < 0 {
+		return fmt.Errorf("clip durations must be >= 0")
+	}
+	d := c.ClipDurations()
+	if d.Min < time.Second {
+		return fmt.Errorf("min clip duration must be at least 1s, got %s", d.Min)
+	}
+	if d.Max <= d.Min {
+		return fmt.Errorf("max clip duration %s must be longer than min clip duration %s", d.Max, d.Min)
+	}
+	if c.MaxFileSizeMB < 0 || c.MaxVideoKbps < 0 {
+		return fmt.Errorf("max file size and bitrate must be >= 0")
+	}
+	if c.MaxFileSizeMB > 0 && c.videoKbpsCap() <= 0 {
+		return fmt.Errorf("max file size %d MB is too small for %s clips", c.MaxFileSizeMB, d.Max)
+	}
+	return nil
+}
+
+// videoKbpsCap is the video bitrate cap: MaxVideoKbps, lowered so the
+// longest clip fits MaxFileSizeMB with its audio and 5% muxing overhead.
+func (c Config) videoKbpsCap() int {
+	kbps := c.MaxVideoKbps
+	if c.MaxFileSizeMB > 0 {
+		budget := int(float64(c.MaxFileSizeMB)*8000*0.95/c.ClipDurations().Max.Seconds()) - audioKbps
+		if kbps == 0 || budget < kbps {
+			kbps = budget
+		}
+	}
+	return kbps
+}
@@ -0,0 +1,189 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+	"github.com/forPelevin/hlcut/internal/usecase"
+)
+
+const (
+	runStateFile   = "run.json"
+	checkpointsDir = "checkpoints"
+)
+
+// RunState records the inputs of a run inside its run directory so that
+// `hlcut resume` can rebuild the same pipeline configuration. Secrets are
+// deliberately not stored; they are read from the environment again.
+type RunState struct {
+	Input              string    `json:"input"`
+	ClipsN             int       `json:"clips"`
+	MinClipSec         float64   `json:"min_clip_sec,omitempty"`
+	MaxClipSec         float64   `json:"max_clip_sec,omitempty"`
+	Profile            string    `json:"profile,omitempty"`
+	MaxFileSizeMB      int       `json:"max_file_size_mb,omitempty"`
+	MaxVideoKbps       int       `json:"max_video_kbps,omitempty"`
+	BurnSubtitles      bool      `json:"burn_subtitles"`
+	SubtitleFormats    []string  `json:"subtitle_formats,omitempty"`
+	SubtitleStyle      string    `json:"subtitle_style,omitempty"`
+	CaptionMode        string    `json:"caption_mode,omitempty"`
+	FontsDir           string    `json:"fonts_dir,omitempty"`
+	Ranker             string    `json:"ranker,omitempty"`
+	ChunkWindowSec     float64   `json:"chunk_window_sec,omitempty"`
+	ChunkConcurrency   int       `json:"chunk_concurrency,omitempty"`
+	LLMMaxAttempts     int       `json:"llm_max_attempts,omitempty"`
+	LLMRetryBaseSec    float64   `json:"llm_retry_base_sec,omitempty"`
+	LLMRetryMaxSec     float64   `json:"llm_retry_max_sec,omitempty"`
+	TranscribeChunkSec float64   `json:"transcribe_chunk_sec,omitempty"`
+	TranscribeWorkers  int       `json:"transcribe_workers,omitempty"`
+	WhisperThreads     int       `json:"whisper_threads,omitempty"`
+	TranscriptPath     string    `json:"transcript_path,omitempty"`
+	Diarize            string    `json:"diarize,omitempty"`
+	Aspect             string    `json:"aspect,omitempty"`
+	Reframe            string    `json:"reframe,omitempty"`
+	Resolution         string    `json:"resolution,omitempty"`
+	Tighten            bool      `json:"tighten,omitempty"`
+	TightenPauseSec    float64   `json:"tighten_pause_sec,omitempty"`
+	LoudnessLUFS       float64   `json:"loudness_lufs,omitempty"`
+	AudioFadeSec       float64   `json:"audio_fade_sec,omitempty"`
+	Cover              string    `json:"cover,omitempty"`
+	Background         string    `json:"background,omitempty"`
+	EpisodeTitle       string    `json:"episode_title,omitempty"`
+	AudioTrack         int       `json:"audio_track,omitempty"`
+	WhisperModel       string    `json:"whisper_model,omitempty"`
+	Language           string    `json:"language,omitempty"`
+	Translate          bool      `json:"translate,omitempty"`
+	OutputFormat       string    `json:"output_format,omitempty"`
+	FastCut            string    `json:"fast_cut,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// LoadRunState reads the state of a previous run from runDir.
+func LoadRunState(runDir string) (RunState, error) {
+	b, err := os.ReadFile(filepath.Join(runDir, checkpointsDir, runStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return RunState{}, fmt.Errorf("%s is not a resumable run directory (missing %s/%s)", runDir, checkpointsDir, runStateFile)
+	}
+	if err != nil {
+		return RunState{}, err
+	}
+	var st RunState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return RunState{}, fmt.Errorf("parse run state: %w", err)
+	}
+	if st.Input == "" || st.ClipsN <= 0 {
+		return RunState{}, fmt.Errorf("run state in %s is incomplete", runDir)
+	}
+	return st, nil
+}
+
+// SelectionCheckpointed reports whether the run in runDir has its clip
+// selection checkpointed, so resuming it needs no LLM.
+func SelectionCheckpointed(runDir string) bool {
+	return usecase.SelectionCheckpointed(filepath.Join(runDir, checkpointsDir))
+}
+
+// Apply copies the stored settings onto cfg, so that `hlcut resume` and
+// `hlcut rerender` rebuild the pipeline the run was started with. The input
+// and clip count are left to the caller. Zero ranker, whisper model and
+// retry values keep cfg's defaults.
+func (st RunState) Apply(cfg *Config) {
+	if st.Ranker != "" {
+		cfg.Ranker = st.Ranker
+	}
+	cfg.LLMChunkWindow, cfg.LLMChunkConcurrency = types.Seconds(st.ChunkWindowSec), st.ChunkConcurrency
+	if st.LLMMaxAttempts > 0 {
+		cfg.LLMMaxAttempts = st.LLMMaxAttempts
+	}
+	if st.LLMRetryBaseSec > 0 {
+		cfg.LLMRetryBaseDelay = types.Seconds(st.LLMRetryBaseSec)
+	}
+	if st.LLMRetryMaxSec > 0 {
+		cfg.LLMRetryMaxDelay = types.Seconds(st.LLMRetryMaxSec)
+	}
+
+	cfg.BurnSubtitles, cfg.SubtitleFormats = st.BurnSubtitles, st.SubtitleFormats
+	cfg.SubtitleStyle, cfg.CaptionMode, cfg.FontsDir = st.SubtitleStyle, st.CaptionMode, st.FontsDir
+
+	if st.WhisperModel != "" {
+		cfg.WhisperModel = st.WhisperModel
+	}
+	cfg.Language, cfg.Translate = st.Language, st.Translate
+	cfg.TranscribeChunk = types.Seconds(st.TranscribeChunkSec)
+	// Zero (runs saved before these were stored) means the adapter defaults.
+	cfg.TranscribeWorkers, cfg.WhisperThreads = st.TranscribeWorkers, st.WhisperThreads
+	cfg.TranscriptPath, cfg.Diarize = st.TranscriptPath, st.Diarize
+
+	cfg.Aspect, cfg.Reframe, cfg.Resolution = st.Aspect, st.Reframe, st.Resolution
+	cfg.Tighten, cfg.TightenPause = st.Tighten, types.Seconds(st.TightenPauseSec)
+	cfg.LoudnessLUFS, cfg.AudioFade = st.LoudnessLUFS, types.Seconds(st.AudioFadeSec)
+	cfg.Cover, cfg.Background, cfg.EpisodeTitle = st.Cover, st.Background, st.EpisodeTitle
+	cfg.AudioTrack = st.AudioTrack
+	cfg.OutputFormat, cfg.FastCut = st.OutputFormat, st.FastCut
+	cfg.MinClip, cfg.MaxClip, cfg.Profile = types.Seconds(st.MinClipSec), types.Seconds(st.MaxClipSec), st.Profile
+	cfg.MaxFileSizeMB, cfg.MaxVideoKbps = st.MaxFileSizeMB, st.MaxVideoKbps
+}
+
+// runState records the settings of a new run; clipsN, formats and frame
+// are the values resolved for it.
+func (c Config) runState(clipsN int, formats []string, frame types.Frame) RunState {
+	return RunState{
+		Input:              c.InputPath,
+		ClipsN:             clipsN,
+		MinClipSec:         c.MinClip.Seconds(),
+		MaxClipSec:         c.MaxClip.Seconds(),
+		Profile:            c.Profile,
+		MaxFileSizeMB:      c.MaxFileSizeMB,
+		MaxVideoKbps:       c.MaxVideoKbps,
+		BurnSubtitles:      c.BurnSubtitles,
+		SubtitleFormats:    formats,
+		SubtitleStyle:      c.SubtitleStyle,
+		CaptionMode:        c.CaptionMode,
+		FontsDir:           c.FontsDir,
+		Ranker:             c.Ranker,
+		ChunkWindowSec:     c.LLMChunkWindow.Seconds(),
+		ChunkConcurrency:   c.LLMChunkConcurrency,
+		LLMMaxAttempts:     c.LLMMaxAttempts,
+		LLMRetryBaseSec:    c.LLMRetryBaseDelay.Seconds(),
+		LLMRetryMaxSec:     c.LLMRetryMaxDelay.Seconds(),
+		TranscribeChunkSec: c.TranscribeChunk.Seconds(),
+		TranscribeWorkers:  c.TranscribeWorkers,
+		WhisperThreads:     c.WhisperThreads,
+		TranscriptPath:     c.TranscriptPath,
+		Diarize:            c.Diarize,
+		Aspect:             frame.Aspect,
+		Reframe:            frame.Mode,
+		Resolution:         c.Resolution,
+		Tighten:            c.Tighten,
+		TightenPauseSec:    c.TightenPause.Seconds(),
+		LoudnessLUFS:       c.LoudnessLUFS,
+		AudioFadeSec:       c.AudioFade.Seconds(),
+		Cover:              c.Cover,
+		Background:         c.Background,
+		EpisodeTitle:       c.EpisodeTitle,
+		AudioTrack:         c.AudioTrack,
+		WhisperModel:       c.WhisperModel,
+		Language:           c.Language,
+		Translate:          c.Translate,
+		OutputFormat:       c.OutputFormat,
+		FastCut:            c.FastCut,
+		CreatedAt:          time.Now().UTC(),
+	}
+}
+
+func saveRunState(runDir string, st RunState) error {
+	dir := filepath.Join(runDir, checkpointsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal run state: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, runStateFile), b, 0o644)
+}
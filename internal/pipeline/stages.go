@@ -0,0 +1,270 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+	"github.com/forPelevin/hlcut/internal/domain/subtitles"
+	"github.com/forPelevin/hlcut/internal/ports/adapters/ffmpeg"
+	"github.com/forPelevin/hlcut/internal/types"
+	"github.com/forPelevin/hlcut/internal/usecase"
+)
+
+// The functions below run single pipeline stages so they can be scripted
+// independently (see the stage subcommands in internal/cli). They share the
+// adapters, cache and run-directory layout with Run.
+
+// Transcribe extracts audio from cfg.InputPath and transcribes it, reusing the
+// transcript cache.
+func Transcribe(ctx context.Context, cfg Config) (types.Transcript, error) {
+	if err := cfg.validateInput(); err != nil {
+		return types.Transcript{}, err
+	}
+	if err := cfg.validateASR(); err != nil {
+		return types.Transcript{}, err
+	}
+	logf := cfg.logger()
+	v := ffmpeg.New(cfg.FFmpegPath, cfg.FFprobePath)
+	src, err := cfg.probeInput(ctx, v)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+	cfg.AudioTrack = src.AudioTrack
+
+	logf("preparing workspace")
+	cacheDir, transcriptCache, err := prepareCache(cfg)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+	uc := usecase.New(newDeps(cfg, v))
+	return uc.Transcribe(ctx, usecase.Input{
+		InputPath:   cfg.InputPath,
+		AudioTrack:  cfg.AudioTrack,
+		StereoAudio: cfg.stereoAudio(),
+		CacheDir:    cacheDir,
+		Logf:        logf,
+
+		TranscriptCache:   transcriptCache,
+		RefreshTranscript: cfg.RefreshCache,
+	})
+}
+
+// Select picks up to cfg.ClipsN clips from the candidates with the configured
+// LLM ranker.
+func Select(ctx context.Context, cfg Config, tr types.Transcript, cands []types.Candidate) ([]types.ClipSpec, error) {
+	if cfg.ClipsN <= 0 {
+		return nil, errors.New("clips must be > 0")
+	}
+	if err := cfg.validateDurations(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateLLM(); err != nil {
+		return nil, err
+	}
+	uc := usecase.New(newDeps(cfg, ffmpeg.New(cfg.FFmpegPath, cfg.FFprobePath)))
+	return uc.Select(ctx, usecase.Input{
+		ClipsN:    cfg.ClipsN,
+		Durations: cfg.ClipDurations(),
+		Logf:      cfg.logger(),
+	}, tr, cands)
+}
+
+// Candidates runs stage 3 only: builds candidate windows from the transcript
+// within the configured clip durations.
+func Candidates(cfg Config, tr types.Transcript) ([]types.Candidate, error) {
+	if err := cfg.validateDurations(); err != nil {
+		return nil, err
+	}
+	return highlights.BuildCandidates(tr, cfg.ClipDurations()), nil
+}
+
+// Render cuts the given clips from cfg.InputPath into a new run directory under
+// cfg.OutDir and writes its manifest. The run directory is resumable with
+// `hlcut resume`. It returns the manifest and the run directory.
+func Render(
+	ctx context.Context,
+	cfg Config,
+	tr types.Transcript,
+	clipSpecs []types.ClipSpec,
+) (types.Manifest, string, error) {
+	if err := cfg.validateInput(); err != nil {
+		return types.Manifest{}, "", err
+	}
+	formats, err := subtitles.ParseFormats(cfg.SubtitleFormats)
+	if err != nil {
+		return types.Manifest{}, "", err
+	}
+	if cfg.BurnSubtitles && len(tr.Segments) == 0 {
+		return types.Manifest{}, "", errors.New("burning subtitles requires a transcript")
+	}
+	if len(formats) > 0 && len(tr.Segments) == 0 {
+		return types.Manifest{}, "", errors.New("subtitle files require a transcript")
+	}
+	if cfg.Tighten && len(tr.Segments) == 0 {
+		return types.Manifest{}, "", errors.New("tightening requires a transcript")
+	}
+	frame, err := cfg.frame()
+	if err != nil {
+		return types.Manifest{}, "", err
+	}
+	if err := cfg.validateAudio(); err != nil {
+		return types.Manifest{}, "", err
+	}
+	if err := cfg.validateAudiogram(); err != nil {
+		return types.Manifest{}, "", err
+	}
+	if err := ffmpeg.CheckFormat(cfg.OutputFormat); err != nil {
+		return types.Manifest{}, "", err
+	}
+	if err := cfg.validateFastCut(); err != nil {
+		return types.Manifest{}, "", err
+	}
+	if err := cfg.validateDurations(); err != nil {
+		return types.Manifest{}, "", err
+	}
+	captions, err := cfg.captionOptions()
+	if err != nil {
+		return types.Manifest{}, "", err
+	}
+	v := ffmpeg.New(cfg.FFmpegPath, cfg.FFprobePath)
+	src, err := cfg.probeInput(ctx, v)
+	if err != nil {
+		return types.Manifest{}, "", err
+	}
+	cfg.AudioTrack = src.AudioTrack
+	ag, frame, err := cfg.audiogram(src.MediaInfo, frame)
+	if err != nil {
+		return types.Manifest{}, "", err
+	}
+	logf := cfg.logger()
+
+	logf("preparing workspace")
+	runOutDir := newRunOutDir(cfg)
+	if err := saveRunState(runOutDir, cfg.runState(max(len(clipSpecs), 1), formats, frame)); err != nil {
+		return types.Manifest{}, "", err
+	}
+	if err := prepareRunOutDir(cfg, runOutDir); err != nil {
+		return types.Manifest{}, "", err
+	}
+
+	uc := usecase.New(newDeps(cfg, v))
+	m, err := uc.Render(ctx, usecase.Input{
+		InputPath:       cfg.InputPath,
+		BurnSubtitles:   cfg.BurnSubtitles,
+		SubtitleFormats: formats,
+		Captions:        captions,
+		FontsDir:        cfg.FontsDir,
+		Frame:           frame,
+		Tighten:         cfg.Tighten,
+		TightenPause:    cfg.TightenPause,
+		Audio:           cfg.audio(),
+		AudioTrack:      cfg.AudioTrack,
+		Format:          cfg.OutputFormat,
+		FastCut:         cfg.FastCut,
+		MaxVideoKbps:    cfg.videoKbpsCap(),
+		MaxFileSizeMB:   cfg.MaxFileSizeMB,
+		Source:          &src,
+		Audiogram:       ag,
+		Jobs:            cfg.RenderJobs,
+		OutDir:          runOutDir,
+		Logf:            logf,
+		CheckpointDir:   filepath.Join(runOutDir, checkpointsDir),
+	}, tr, clipSpecs)
+	if err != nil {
+		return types.Manifest{}, "", fmt.Errorf("render: %w", err)
+	}
+	if err := writeManifest(runOutDir, m, logf); err != nil {
+		return types.Manifest{}, "", err
+	}
+	return m, runOutDir, nil
+}
+
+// Rerender applies hand edits of runDir/manifest.json: clips whose timing,
+// burn_subtitles flag, framing or tighten flag changed are rendered again and
+// the manifest is rewritten. The transcript comes from the run checkpoints or the transcript
+// cache, so whisper is not re-run.
+func Rerender(ctx context.Context, cfg Config, runDir string) (usecase.RerenderResult, error) {
+	logf := cfg.logger()
+	manifestPath := filepath.Join(runDir, "manifest.json")
+	b, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return usecase.RerenderResult{}, fmt.Errorf("read manifest: %w", err)
+	}
+	var m types.Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return usecase.RerenderResult{}, fmt.Errorf("parse %s: %w", manifestPath, err)
+	}
+	if m.Input == "" {
+		return usecase.RerenderResult{}, fmt.Errorf("%s: input is empty", manifestPath)
+	}
+	cfg.InputPath = m.Input
+	if err := cfg.validateInput(); err != nil {
+		return usecase.RerenderResult{}, err
+	}
+	// Clips are re-rendered with the settings of the run; a clip that needs
+	// its transcript again is transcribed the same way.
+	if st, err := LoadRunState(runDir); err == nil {
+		st.Apply(&cfg)
+	}
+	captions, err := cfg.captionOptions()
+	if err != nil {
+		return usecase.RerenderResult{}, err
+	}
+
+	v := ffmpeg.New(cfg.FFmpegPath, cfg.FFprobePath)
+	src, err := cfg.probeInput(ctx, v)
+	if err != nil {
+		return usecase.RerenderResult{}, err
+	}
+	// Audiogram clips whose framing was removed keep the default frame.
+	ag, agFrame, err := cfg.audiogram(src.MediaInfo, types.Frame{})
+	if err != nil {
+		return usecase.RerenderResult{}, err
+	}
+
+	logf("preparing workspace")
+	cacheDir, transcriptCache, err := prepareCache(cfg)
+	if err != nil {
+		return usecase.RerenderResult{}, err
+	}
+	// Any clip may switch subtitles on, so both output dirs must exist.
+	cfg.BurnSubtitles = true
+	if err := prepareRunOutDir(cfg, runDir); err != nil {
+		return usecase.RerenderResult{}, err
+	}
+
+	uc := usecase.New(newDeps(cfg, v))
+	res, err := uc.Rerender(ctx, usecase.Input{
+		InputPath:     cfg.InputPath,
+		Frame:         agFrame,
+		AudioTrack:    src.AudioTrack,
+		Format:        cfg.OutputFormat,
+		FastCut:       cfg.FastCut,
+		MaxVideoKbps:  cfg.videoKbpsCap(),
+		MaxFileSizeMB: cfg.MaxFileSizeMB,
+		Audiogram:     ag,
+		Captions:      captions,
+		FontsDir:      cfg.FontsDir,
+		TightenPause:  cfg.TightenPause,
+		Audio:         cfg.audio(),
+		Jobs:          cfg.RenderJobs,
+		CacheDir:      cacheDir,
+		OutDir:        runDir,
+		Logf:          logf,
+		CheckpointDir: filepath.Join(runDir, checkpointsDir),
+
+		TranscriptCache: transcriptCache,
+	}, m)
+	if err != nil {
+		return usecase.RerenderResult{}, err
+	}
+	if err := writeManifest(runDir, res.Manifest, logf); err != nil {
+		return usecase.RerenderResult{}, err
+	}
+	return res, nil
+}
@@ -0,0 +1,642 @@
+// Package chatcompletion refines highlight candidates through an
+// OpenAI-style chat completions endpoint. Provider adapters (openrouter,
+// openai) supply the endpoint, credentials and response-format capability.
+package chatcompletion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// ResponseFormat is the structured-output mode requested from the server.
+type ResponseFormat string
+
+const (
+	// FormatAuto starts with json_schema and downgrades to json_object, then
+	// to plain prompting, when the server rejects the response_format.
+	FormatAuto       ResponseFormat = "auto"
+	FormatJSONSchema ResponseFormat = "json_schema"
+	FormatJSONObject ResponseFormat = "json_object"
+	FormatNone       ResponseFormat = "none"
+)
+
+// ParseResponseFormat accepts "auto", "json_schema", "json_object" or "none";
+// empty means auto.
+func ParseResponseFormat(s string) (ResponseFormat, error) {
+	switch f := ResponseFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatAuto, nil
+	case FormatAuto, FormatJSONSchema, FormatJSONObject, FormatNone:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown response format %q (want auto, json_schema, json_object or none)", s)
+	}
+}
+
+type Config struct {
+	// Provider names the backend in errors, e.g. "openrouter".
+	Provider string
+	// URL is the full chat completions endpoint.
+	URL string
+	// Models are tried in order; later entries are fallbacks used when a
+	// model keeps failing.
+	Models []string
+	// APIKey is sent in AuthHeader; no auth header is sent when it is empty.
+	APIKey string
+	// AuthHeader defaults to "Authorization", which carries "Bearer <key>";
+	// any other header carries the raw key.
+	AuthHeader string
+	Format     ResponseFormat
+	// Retry applies per model; zero fields take DefaultRetryPolicy values.
+	Retry RetryPolicy
+	// Chunking enables map-reduce selection for long transcripts.
+	Chunking Chunking
+	Logf     func(format string, args ...any)
+}
+
+type Client struct {
+	cfg    Config
+	client *http.Client
+
+	mu     sync.Mutex
+	format ResponseFormat
+}
+
+const (
+	requestTimeout = 90 * time.Second
+	// maxPromptCandidates bounds a single prompt.
+	maxPromptCandidates = 80
+)
+
+func New(cfg Config) *Client {
+	if cfg.AuthHeader == "" {
+		cfg.AuthHeader = "Authorization"
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatAuto
+	}
+	format := cfg.Format
+	if format == FormatAuto {
+		format = FormatJSONSchema
+	}
+	return &Client{cfg: cfg, client: &http.Client{Timeout: 5 * time.Minute}, format: format}
+}
+
+func (c *Client) Refine(
+	ctx context.Context,
+	tr types.Transcript,
+	cands []types.Candidate,
+	clipsN int,
+	d types.ClipDurations,
+) ([]types.ClipSpec, error) {
+	if clipsN <= 0 || len(cands) == 0 {
+		return nil, nil
+	}
+	if d.Max <= 0 || d.Max < d.Min {
+		return nil, nil
+	}
+	timing := highlights.CollectTiming(tr)
+
+	if c.cfg.Chunking.Window > 0 {
+		if windows := highlights.SplitWindows(tr, c.cfg.Chunking.Window); len(windows) > 1 {
+			return c.refineChunked(ctx, timing, windows, cands, clipsN, d)
+		}
+	}
+	return c.refineOnce(ctx, timing, cands, clipsN, d, "")
+}
+
+// refineOnce asks the model to pick up to clipsN clips from cands in a single
+// request, each within d. note, if set, is appended to the instructions.
+func (c *Client) refineOnce(
+	ctx context.Context,
+	timing highlights.Timing,
+	cands []types.Candidate,
+	clipsN int,
+	d types.ClipDurations,
+	note string,
+) ([]types.ClipSpec, error) {
+	minClip, maxClip := d.Min, d.Max
+	top := selectPromptCandidates(cands, maxPromptCandidates)
+	if len(top) == 0 {
+		return nil, nil
+	}
+
+	type cand struct {
+		Idx      int      `json:"idx"`
+		StartSec float64  `json:"start_sec"`
+		EndSec   float64  `json:"end_sec"`
+		Text     string   `json:"text"`
+		Speakers []string `json:"speakers,omitempty"`
+		Info     float64  `json:"info"`
+		Hook     float64  `json:"hook"`
+	}
+	arr := make([]cand, 0, len(top))
+	diarized := false
+	for i, c := range top {
+		text, speakers := speakerText(c)
+		diarized = diarized || len(speakers) > 0
+		arr = append(arr, cand{
+			Idx:      i,
+			StartSec: c.Start.Seconds(),
+			EndSec:   c.End.Seconds(),
+			Text:     text,
+			Speakers: speakers,
+			Info:     c.InfoScore,
+			Hook:     c.HookScore,
+		})
+	}
+	if diarized {
+		note = speakerNote + note
+	}
+
+	prompt := map[string]any{
+		"maxClips":   clipsN,
+		"minSec":     minClip.Seconds(),
+		"maxSec":     maxClip.Seconds(),
+		"candidates": arr,
+	}
+	pb, err := json.Marshal(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("marshal prompt: %w", err)
+	}
+
+	content, model, ok, err := c.complete(ctx, pb, note)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return fallbackHighlights(top, clipsN, minClip, maxClip, timing), nil
+	}
+
+	clean, err := extractJSONObject(content)
+	if err != nil {
+		return fallbackHighlights(top, clipsN, minClip, maxClip, timing), nil
+	}
+
+	var out struct {
+		Clips []struct {
+			Idx      int      `json:"idx"`
+			StartSec float64  `json:"start_sec"`
+			EndSec   float64  `json:"end_sec"`
+			Title    string   `json:"title"`
+			Caption  string   `json:"caption"`
+			Tags     []string `json:"tags"`
+			Keywords []string `json:"keywords"`
+			Reason   string   `json:"reason"`
+		} `json:"clips"`
+	}
+	if err := json.Unmarshal([]byte(clean), &out); err != nil {
+		return fallbackHighlights(top, clipsN, minClip, maxClip, timing), nil
+	}
+
+	res := make([]types.ClipSpec, 0, min(len(out.Clips), clipsN))
+	for _, c := range out.Clips {
+		st, en, ok := normalizeClipRange(c.Idx, c.StartSec, c.EndSec, top, minClip, maxClip, timing)
+		if !ok {
+			continue
+		}
+		if !isDistinct(res, st, en, 2*time.Second) {
+			continue
+		}
+
+		title := strings.TrimSpace(c.Title)
+		caption := strings.TrimSpace(c.Caption)
+		if title == "" {
+			title = "Highlight"
+		}
+		if caption == "" {
+			caption = title
+		}
+
+		res = append(res, types.ClipSpec{
+			Start:    st,
+			End:      en,
+			Title:    title,
+			Caption:  caption,
+			Tags:     c.Tags,
+			Keywords: c.Keywords,
+			Reason:   c.Reason,
+			Model:    model,
+		})
+		if len(res) >= clipsN {
+			break
+		}
+	}
+
+	// If model failed to return valid clips, keep the pipeline useful with deterministic fallback.
+	if len(res) == 0 {
+		res = fallbackHighlights(top, clipsN, minClip, maxClip, timing)
+	}
+
+	if len(res) > clipsN {
+		res = res[:clipsN]
+	}
+	return res, nil
+}
+
+func (c *Client) currentFormat() ResponseFormat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.format
+}
+
+// downgrade moves from the rejected format to the next weaker one and reports
+// whether there is a format left to try.
+func (c *Client) downgrade(rejected ResponseFormat) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.format != rejected {
+		// Another call already downgraded; retry with its choice.
+		return true
+	}
+	switch rejected {
+	case FormatJSONSchema:
+		c.format = FormatJSONObject
+	case FormatJSONObject:
+		c.format = FormatNone
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *Client) post(ctx context.Context, candsJSON []byte, note, model string, format ResponseFormat) (string, bool, error) {
+	payload := map[string]any{
+		"model":  model,
+		"stream": false,
+		"messages": []map[string]any{
+			{"role": "user", "content": string(buildPrompt(candsJSON, note, format != FormatJSONSchema))},
+		},
+	}
+	switch format {
+	case FormatJSONSchema:
+		// strict schema: select clips with start/end and metadata.
+		payload["response_format"] = map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "hlcut_refine",
+				"schema": refineSchema,
+			},
+		}
+	case FormatJSONObject:
+		payload["response_format"] = map[string]any{"type": "json_object"}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", false, fmt.Errorf("marshal request: %w", err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, "POST", c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", false, err
+	}
+	if c.cfg.APIKey != "" {
+		if strings.EqualFold(c.cfg.AuthHeader, "Authorization") {
+			req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+		} else {
+			req.Header.Set(c.cfg.AuthHeader, c.cfg.APIKey)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if errors.Is(reqCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			err = fmt.Errorf("%s timeout after %s (model=%s)", c.cfg.Provider, requestTimeout, model)
+		}
+		return "", false, &requestError{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		re := &requestError{
+			status:     resp.StatusCode,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		rb, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			re.err = fmt.Errorf("%s status %d and read body failed: %v", c.cfg.Provider, resp.StatusCode, readErr)
+			return "", false, re
+		}
+		re.err = fmt.Errorf("%s status %d: %s", c.cfg.Provider, resp.StatusCode, truncate(redactSecrets(string(rb), c.cfg.APIKey), 400))
+		re.formatRejected = format != FormatNone && rejectsResponseFormat(resp.StatusCode, string(rb))
+		return "", false, re
+	}
+
+	var raw struct {
+		Choices []struct {
+			Message struct {
+				Content any `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return "", false, &requestError{err: fmt.Errorf("%s: decode response: %w", c.cfg.Provider, err)}
+	}
+	if len(raw.Choices) == 0 {
+		return "", false, nil
+	}
+	content, err := messageContentToString(raw.Choices[0].Message.Content)
+	if err != nil {
+		return "", false, nil
+	}
+	return content, true, nil
+}
+
+var responseFormatRE = regexp.MustCompile(`(?i)response_format|json_schema|json_object|structured output|grammar`)
+
+// rejectsResponseFormat reports whether a failed request was refused because
+// the server does not support the requested structured-output mode.
+func rejectsResponseFormat(status int, body string) bool {
+	switch status {
+	case http.StatusBadRequest, http.StatusNotImplemented, http.StatusUnprocessableEntity:
+		return responseFormatRE.MatchString(body)
+	default:
+		return false
+	}
+}
+
+var refineSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"clips": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"idx":       map[string]any{"type": "integer"},
+					"start_sec": map[string]any{"type": "number"},
+					"end_sec":   map[string]any{"type": "number"},
+					"title":     map[string]any{"type": "string"},
+					"caption":   map[string]any{"type": "string"},
+					"tags":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					"keywords":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					"reason":    map[string]any{"type": "string"},
+				},
+				"required": []string{"idx", "start_sec", "end_sec", "title", "caption", "tags", "keywords", "reason"},
+			},
+		},
+	},
+	"required": []string{"clips"},
+}
+
+// speakerNote explains the speaker markup of diarized candidates.
+const speakerNote = " Candidate texts mark speaker turns as [S1], [S2], ...; speakers lists who talks in the clip." +
+	" Prefer clips a viewer can follow: a complete exchange, or one speaker making a point."
+
+// speakerText prefixes each speaker turn of c with its label and lists the
+// speakers in order of appearance; plain text when c has no turns.
+func speakerText(c types.Candidate) (string, []string) {
+	if len(c.Turns) == 0 {
+		return c.Text, nil
+	}
+	var (
+		b        strings.Builder
+		speakers []string
+	)
+	for i, t := range c.Turns {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		if t.Speaker != "" {
+			fmt.Fprintf(&b, "[%s] ", t.Speaker)
+			if !slices.Contains(speakers, t.Speaker) {
+				speakers = append(speakers, t.Speaker)
+			}
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String(), speakers
+}
+
+func buildPrompt(candsJSON []byte, note string, describeSchema bool) []byte {
+	shape := ""
+	if describeSchema {
+		// Without a server-side schema the expected shape must be spelled out.
+		shape = "\n\nRespond with a JSON object of the form " +
+			`{"clips":[{"idx":0,"start_sec":0,"end_sec":0,"title":"","caption":"","tags":[""],"keywords":[""],"reason":""}]}` +
+			" where idx refers to the candidate."
+	}
+	return []byte(
+		"Select the best highlight clips from the candidate list. " +
+			"Return strictly valid JSON (no markdown, no code fences) matching the provided schema. " +
+			"Prefer clips that are both informative and hooky. " +
+			"Clips must be distinct scenes with no overlaps/intersections and can be anywhere from 0 to maxClips total. " +
+			"Each clip duration must be between minSec and maxSec. " +
+			"Clips must start cleanly and end on a complete thought, ideally right after a payoff/peak or hook explanation. " +
+			"For keywords, list up to 5 words spoken in the clip that carry its point; they are emphasized in the captions." +
+			note +
+			shape +
+			"\n\nCandidates JSON:\n" + string(candsJSON),
+	)
+}
+
+func messageContentToString(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case []any:
+		// Some providers return an array of {type,text} parts.
+		var b strings.Builder
+		for _, it := range x {
+			m, ok := it.(map[string]any)
+			if !ok {
+				continue
+			}
+			if t, ok := m["text"].(string); ok {
+				b.WriteString(t)
+			}
+		}
+		s := b.String()
+		if strings.TrimSpace(s) == "" {
+			return "", errors.New("chat completion: empty content")
+		}
+		return s, nil
+	default:
+		return "", fmt.Errorf("chat completion: unexpected content type %T", v)
+	}
+}
+
+func extractJSONObject(s string) (string, error) {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return "", errors.New("chat completion: empty content")
+	}
+
+	// Strip markdown code fences.
+	if strings.HasPrefix(t, "```") {
+		// Remove opening fence line.
+		if i := strings.Index(t, "\n"); i >= 0 {
+			t = t[i+1:]
+		}
+		// Remove trailing fence.
+		if j := strings.LastIndex(t, "```"); j >= 0 {
+			t = t[:j]
+		}
+		t = strings.TrimSpace(t)
+	}
+
+	// Best-effort: take the first JSON object found.
+	start := strings.Index(t, "{")
+	end := strings.LastIndex(t, "}")
+	if start >= 0 && end > start {
+		return t[start : end+1], nil
+	}
+
+	return "", fmt.Errorf("chat completion: could not locate JSON object in: %q", truncate(t, 200))
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+var (
+	bearerTokenRE = regexp.MustCompile(`(?i)\bBearer\s+[A-Za-z0-9._-]+\b`)
+	authHeaderRE  = regexp.MustCompile(`(?i)(authorization\s*[:=]\s*)([^\n\r,;]+)`)
+	apiKeyFieldRE = regexp.MustCompile(`(?i)(api[_-]?key\s*[:=]\s*)([^\n\r,;]+)`)
+)
+
+func redactSecrets(s, apiKey string) string {
+	if s == "" {
+		return s
+	}
+	out := s
+	if apiKey != "" {
+		out = strings.ReplaceAll(out, apiKey, "[REDACTED]")
+	}
+	out = bearerTokenRE.ReplaceAllString(out, "Bearer [REDACTED]")
+	out = authHeaderRE.ReplaceAllString(out, "${1}[REDACTED]")
+	out = apiKeyFieldRE.ReplaceAllString(out, "${1}[REDACTED]")
+	return out
+}
+
+func fallbackHighlights(
+	cands []types.Candidate,
+	clipsN int,
+	minClip, maxClip time.Duration,
+	timing highlights.Timing,
+) []types.ClipSpec {
+	picks := highlights.SelectBest(cands, clipsN, minClip, maxClip, timing)
+	out := make([]types.ClipSpec, 0, len(picks))
+	for _, p := range picks {
+		caption := strings.TrimSpace(p.Candidate.Text)
+		if caption == "" {
+			caption = "Highlight"
+		}
+		out = append(out, types.ClipSpec{
+			Start:   p.Start,
+			End:     p.End,
+			Title:   "Highlight",
+			Caption: caption,
+			Reason:  "fallback",
+		})
+	}
+	return out
+}
+
+func selectPromptCandidates(cands []types.Candidate, limit int) []types.Candidate {
+	if len(cands) == 0 || limit <= 0 {
+		return nil
+	}
+
+	best := make([]types.Candidate, len(cands))
+	copy(best, cands)
+	sort.Slice(best, func(i, j int) bool {
+		s1 := best[i].InfoScore + best[i].HookScore
+		s2 := best[j].InfoScore + best[j].HookScore
+		if s1 == s2 {
+			return best[i].Start < best[j].Start
+		}
+		return s1 > s2
+	})
+
+	out := make([]types.Candidate, 0, limit)
+	for _, c := range best {
+		if len(out) >= limit {
+			break
+		}
+		if !isDistinctCandidate(out, c.Start, c.End, 2*time.Second) {
+			continue
+		}
+		out = append(out, c)
+	}
+
+	if len(out) < limit {
+		for _, c := range cands {
+			if len(out) >= limit {
+				break
+			}
+			if !isDistinctCandidate(out, c.Start, c.End, 2*time.Second) {
+				continue
+			}
+			out = append(out, c)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Start < out[j].Start })
+	return out
+}
+
+func normalizeClipRange(
+	idx int,
+	startSec float64,
+	endSec float64,
+	cands []types.Candidate,
+	minClip time.Duration,
+	maxClip time.Duration,
+	timing highlights.Timing,
+) (time.Duration, time.Duration, bool) {
+	st := time.Duration(startSec * float64(time.Second))
+	en := time.Duration(endSec * float64(time.Second))
+	if st < 0 {
+		st = 0
+	}
+
+	if st, en, ok := highlights.NormalizeClip(st, en, minClip, maxClip, timing); ok {
+		return st, en, true
+	}
+
+	if idx < 0 || idx >= len(cands) {
+		return 0, 0, false
+	}
+	return highlights.NormalizeClip(cands[idx].Start, cands[idx].End, minClip, maxClip, timing)
+}
+
+func isDistinct(existing []types.ClipSpec, st, en, minGap time.Duration) bool {
+	for _, e := range existing {
+		if st < e.End+minGap && en > e.Start-minGap {
+			return false
+		}
+	}
+	return true
+}
+
+func isDistinctCandidate(existing []types.Candidate, st, en, minGap time.Duration) bool {
+	for _, e := range existing {
+		if st < e.End+minGap && en > e.Start-minGap {
+			return false
+		}
+	}
+	return true
+}
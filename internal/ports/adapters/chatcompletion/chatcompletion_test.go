@@ -1,9 +1,10 @@
-package openrouter
+package chatcompletion
 
 import (
 	"testing"
 	"time"
 
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
 	"github.com/forPelevin/hlcut/internal/types"
 )
 
@@ -84,7 +85,7 @@ func TestNormalizeClipRange_FallsBackToCandidateIdx(t *testing.T) {
 		cands,
 		20*time.Second,
 		60*time.Second,
-		transcriptTiming{},
+		highlights.Timing{},
 	)
 	if !ok {
 		t.Fatalf("expected clip to normalize")
@@ -101,7 +102,7 @@ func TestFallbackHighlights_DoesNotReturnOverlappingClips(t *testing.T) {
 		{Start: 36 * time.Second, End: 62 * time.Second, Text: "C", InfoScore: 7},
 	}
 
-	out := fallbackHighlights(cands, 3, 20*time.Second, 60*time.Second, transcriptTiming{})
+	out := fallbackHighlights(cands, 3, 20*time.Second, 60*time.Second, highlights.Timing{})
 	if len(out) != 2 {
 		t.Fatalf("expected 2 non-overlapping clips, got %d", len(out))
 	}
@@ -110,68 +111,21 @@ func TestFallbackHighlights_DoesNotReturnOverlappingClips(t *testing.T) {
 	}
 }
 
-func TestNormalizeClipDur_SnapsToPunctuationNearTail(t *testing.T) {
-	timing := transcriptTiming{
-		words: []timedWord{
-			{Start: 53 * time.Second, End: 54 * time.Second, Text: "almost"},
-			{Start: 54 * time.Second, End: 55 * time.Second, Text: "there"},
-			{Start: 55 * time.Second, End: 56 * time.Second, Text: "finished."},
-			{Start: 56 * time.Second, End: 57 * time.Second, Text: "next"},
-		},
-	}
-
-	_, en, ok := normalizeClipDur(0, 60*time.Second, 20*time.Second, 60*time.Second, timing)
-	if !ok {
-		t.Fatalf("expected normalized clip")
-	}
-	if en != 56*time.Second {
-		t.Fatalf("expected clip end to snap to punctuation at 56s, got %v", en)
-	}
-}
-
-func TestNormalizeClipDur_PrefersComprehensiveSentenceEnd(t *testing.T) {
-	timing := transcriptTiming{
-		words: []timedWord{
-			{Start: 53 * time.Second, End: 54 * time.Second, Text: "What"},
-			{Start: 54 * time.Second, End: 55 * time.Second, Text: "is"},
-			{Start: 55 * time.Second, End: 56 * time.Second, Text: "going"},
-			{Start: 56 * time.Second, End: 57 * time.Second, Text: "on?"},
-			{Start: 57 * time.Second, End: 57*time.Second + 200*time.Millisecond, Text: "I"},
-			{Start: 57*time.Second + 200*time.Millisecond, End: 58 * time.Second, Text: "am"},
-			{Start: 58 * time.Second, End: 59 * time.Second, Text: "out."},
-		},
-	}
-
-	_, en, ok := normalizeClipDur(0, 58*time.Second, 20*time.Second, 60*time.Second, timing)
-	if !ok {
-		t.Fatalf("expected normalized clip")
-	}
-	if en != 59*time.Second {
-		t.Fatalf("expected clip end to prefer full-resolution sentence at 59s, got %v", en)
-	}
-}
-
-func TestNormalizeClipDur_AvoidsQuestionTailWhenContinuationStartsImmediately(t *testing.T) {
-	timing := transcriptTiming{
-		words: []timedWord{
-			{Start: 73 * time.Second, End: 74 * time.Second, Text: "there's"},
-			{Start: 74 * time.Second, End: 75 * time.Second, Text: "more!"},
-			{Start: 75 * time.Second, End: 76 * time.Second, Text: "what"},
-			{Start: 76 * time.Second, End: 77 * time.Second, Text: "is"},
-			{Start: 77 * time.Second, End: 78 * time.Second, Text: "going"},
-			{Start: 78 * time.Second, End: 79 * time.Second, Text: "on?"},
-			{Start: 79 * time.Second, End: 79*time.Second + 200*time.Millisecond, Text: "i"},
-			{Start: 79*time.Second + 200*time.Millisecond, End: 80 * time.Second, Text: "was"},
-		},
-	}
-
-	// start=20s, max=60s => hard upper bound is 80s, so "on?" is the tail candidate;
-	// the logic should back off to "more!" to avoid abrupt unresolved question ending.
-	_, en, ok := normalizeClipDur(20*time.Second, 80*time.Second, 20*time.Second, 60*time.Second, timing)
-	if !ok {
-		t.Fatalf("expected normalized clip")
-	}
-	if en != 75*time.Second {
-		t.Fatalf("expected clip end to back off to 75s for smoother closure, got %v", en)
+func TestSpeakerText(t *testing.T) {
+	plain := types.Candidate{Text: "just one voice"}
+	if text, speakers := speakerText(plain); text != plain.Text || speakers != nil {
+		t.Fatalf("speakerText(plain) = %q, %v", text, speakers)
+	}
+	c := types.Candidate{Turns: []types.Turn{
+		{Speaker: "S1", Text: "Why now?"},
+		{Speaker: "S2", Text: "Rates fell."},
+		{Speaker: "S1", Text: "Really?"},
+	}}
+	text, speakers := speakerText(c)
+	if want := "[S1] Why now? [S2] Rates fell. [S1] Really?"; text != want {
+		t.Fatalf("text = %q, want %q", text, want)
+	}
+	if len(speakers) != 2 || speakers[0] != "S1" || speakers[1] != "S2" {
+		t.Fatalf("speakers = %v, want [S1 S2]", speakers)
 	}
 }
@@ -0,0 +1,228 @@
+package chatcompletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Chunking configures map-reduce selection: the transcript is split into
+// windows, the model nominates highlights per window (map), then ranks all
+// nominees in one final request (reduce). A zero Window disables it.
+type Chunking struct {
+	Window time.Duration
+	// Concurrency limits parallel window requests; <= 0 means 4.
+	Concurrency int
+}
+
+const (
+	defaultChunkConcurrency = 4
+	minNomineesPerWindow    = 2
+	maxNomineesPerWindow    = 10
+)
+
+const (
+	nominateNote = " These candidates cover one part of a longer video; nominate its strongest moments."
+	rankNote     = " These clips were nominated from different parts of a longer video;" +
+		" prefer a selection spread across the whole timeline over several clips from the same part."
+)
+
+type windowResult struct {
+	nominees []types.ClipSpec
+	err      error
+}
+
+func (c *Client) refineChunked(
+	ctx context.Context,
+	timing highlights.Timing,
+	windows []highlights.Window,
+	cands []types.Candidate,
+	clipsN int,
+	d types.ClipDurations,
+) ([]types.ClipSpec, error) {
+	concurrency := c.cfg.Chunking.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultChunkConcurrency
+	}
+	perWindow := (2*clipsN + len(windows) - 1) / len(windows)
+	perWindow = max(minNomineesPerWindow, min(perWindow, maxNomineesPerWindow))
+	c.logf(
+		"llm: chunked selection over %d windows of ~%s (concurrency %d, up to %d nominees each)",
+		len(windows), c.cfg.Chunking.Window, concurrency, perWindow,
+	)
+
+	// Map: nominate per window. Results are indexed by window so logs and
+	// the final prompt do not depend on completion order.
+	results := make([]windowResult, len(windows))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, w := range windows {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			results[i].nominees, results[i].err = c.refineOnce(ctx, timing, windowCandidates(w, cands, d), perWindow, d, nominateNote)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var (
+		nominees []types.ClipSpec
+		byWindow = make([][]types.ClipSpec, len(windows))
+		errs     []error
+	)
+	for i, r := range results {
+		w := windows[i]
+		if r.err != nil {
+			c.logf("llm: window %d/%d [%s-%s] failed: %v", i+1, len(windows), clock(w.Start), clock(w.End), r.err)
+			errs = append(errs, r.err)
+			continue
+		}
+		c.logf("llm: window %d/%d [%s-%s]: %d nominees", i+1, len(windows), clock(w.Start), clock(w.End), len(r.nominees))
+		byWindow[i] = r.nominees
+		nominees = append(nominees, r.nominees...)
+	}
+	if len(errs) == len(windows) {
+		return nil, fmt.Errorf("chunked selection: all %d windows failed: %w", len(windows), errors.Join(errs...))
+	}
+	if len(nominees) == 0 {
+		return nil, nil
+	}
+
+	// Reduce: rank all nominees globally.
+	c.logf("llm: final ranking over %d nominees", len(nominees))
+	final, err := c.refineOnce(ctx, timing, nomineeCandidates(nominees), clipsN, d, rankNote)
+	if err != nil {
+		c.logf("llm: final ranking failed, taking nominees round-robin: %v", err)
+		final = nil
+	}
+	return spreadAcrossWindows(final, byWindow, windows, clipsN), nil
+}
+
+// windowCandidates returns the candidates starting inside w, so supplied or
+// edited candidates reach the prompt even when they run past the window's
+// end. Only a window with none (candidate building caps its output on long
+// inputs) gets candidates built from its own transcript within d.
+func windowCandidates(w highlights.Window, cands []types.Candidate, d types.ClipDurations) []types.Candidate {
+	var out []types.Candidate
+	for _, c := range cands {
+		if w.Contains(c.Start) {
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 {
+		out = highlights.BuildCandidates(w.Transcript, d)
+	}
+	return out
+}
+
+// nomineeCandidates turns window picks into candidates for the final prompt,
+// describing each by its title and caption.
+func nomineeCandidates(nominees []types.ClipSpec) []types.Candidate {
+	out := make([]types.Candidate, 0, len(nominees))
+	for _, n := range nominees {
+		text := strings.TrimSpace(n.Title + ". " + n.Caption)
+		info, hook := highlights.Score(text)
+		out = append(out, types.Candidate{Start: n.Start, End: n.End, Text: text, InfoScore: info, HookScore: hook})
+	}
+	return out
+}
+
+// spreadAcrossWindows caps how many final clips come from one window and
+// fills the slots this frees with the best remaining nominees of other
+// windows, round-robin. With no final ranking all slots are filled that way.
+func spreadAcrossWindows(
+	final []types.ClipSpec,
+	byWindow [][]types.ClipSpec,
+	windows []highlights.Window,
+	clipsN int,
+) []types.ClipSpec {
+	windowOf := func(cs types.ClipSpec) int {
+		for i, w := range windows {
+			if w.Contains(cs.Start) {
+				return i
+			}
+		}
+		return len(windows) - 1
+	}
+	// Allow some concentration (1.5x an even share) but never let one part of
+	// the video crowd out the rest.
+	perWindowCap := max(1, (3*clipsN+2*len(windows)-1)/(2*len(windows)))
+
+	counts := make([]int, len(windows))
+	out := make([]types.ClipSpec, 0, clipsN)
+	freed := 0
+	for _, cs := range final {
+		if len(out) >= clipsN {
+			break
+		}
+		wi := windowOf(cs)
+		if counts[wi] >= perWindowCap {
+			freed++
+			continue
+		}
+		counts[wi]++
+		out = append(out, cs)
+	}
+	if final == nil {
+		freed = clipsN
+	}
+
+	for round := 0; freed > 0 && len(out) < clipsN; round++ {
+		added := false
+		for wi, ns := range byWindow {
+			if round >= len(ns) || counts[wi] >= perWindowCap || freed == 0 || len(out) >= clipsN {
+				continue
+			}
+			cs := ns[round]
+			if !isDistinct(out, cs.Start, cs.End, 2*time.Second) {
+				continue
+			}
+			counts[wi]++
+			freed--
+			out = append(out, cs)
+			added = true
+		}
+		if !added && allExhausted(byWindow, round) {
+			break
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start < out[j].Start })
+	return out
+}
+
+func allExhausted(byWindow [][]types.ClipSpec, round int) bool {
+	for _, ns := range byWindow {
+		if round+1 < len(ns) {
+			return false
+		}
+	}
+	return true
+}
+
+func clock(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d / time.Hour)
+	m := int(d % time.Hour / time.Minute)
+	s := int(d % time.Minute / time.Second)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -0,0 +1,195 @@
+package chatcompletion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// greedyServer always picks the first candidates of the prompt (by idx) and
+// records how many requests were in flight at once.
+type greedyServer struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	nominate    int
+	rank        int
+	prompts     []string
+}
+
+func (s *greedyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inFlight++
+	s.maxInFlight = max(s.maxInFlight, s.inFlight)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	var req struct {
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	prompt := req.Messages[0].Content
+
+	s.mu.Lock()
+	s.prompts = append(s.prompts, prompt)
+	if strings.Contains(prompt, "nominated from different parts") {
+		s.rank++
+	} else {
+		s.nominate++
+	}
+	s.mu.Unlock()
+
+	var prm struct {
+		MaxClips int `json:"maxClips"`
+	}
+	_ = json.Unmarshal([]byte(prompt[strings.Index(prompt, "Candidates JSON:\n")+len("Candidates JSON:\n"):]), &prm)
+	var clips []string
+	for i := 0; i < prm.MaxClips; i++ {
+		clips = append(clips, fmt.Sprintf(`{"idx":%d,"start_sec":0,"end_sec":0,"title":"T%d","caption":"C","tags":[],"reason":"r"}`, i, i))
+	}
+	content := `{"clips":[` + strings.Join(clips, ",") + `]}`
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"choices": []map[string]any{{"message": map[string]any{"content": content}}},
+	})
+}
+
+func TestRefine_ChunkedSpreadsAcrossWindows(t *testing.T) {
+	srv := &greedyServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	// 30 minutes of transcript, three candidates per 10-minute window.
+	var tr types.Transcript
+	for i := 0; i < 30; i++ {
+		tr.Segments = append(tr.Segments, types.Segment{Start: float64(i * 60), End: float64(i*60 + 60), Text: "x"})
+	}
+	var cands []types.Candidate
+	for w := 0; w < 3; w++ {
+		for k := 0; k < 3; k++ {
+			st := time.Duration(w)*10*time.Minute + time.Duration(k)*2*time.Minute
+			cands = append(cands, types.Candidate{Start: st, End: st + 30*time.Second, Text: "c"})
+		}
+	}
+
+	c := New(Config{
+		Provider: "test",
+		URL:      ts.URL,
+		Models:   []string{"m"},
+		Format:   FormatJSONSchema,
+		Chunking: Chunking{Window: 10 * time.Minute, Concurrency: 2},
+	})
+	clips, err := c.Refine(context.Background(), tr, cands, 2, highlights.DefaultDurations())
+	if err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+
+	if srv.nominate != 3 || srv.rank != 1 {
+		t.Fatalf("expected 3 nominate + 1 rank requests, got %d + %d", srv.nominate, srv.rank)
+	}
+	if srv.maxInFlight > 2 {
+		t.Fatalf("concurrency limit exceeded: %d in flight", srv.maxInFlight)
+	}
+	// The greedy final ranking picks the two earliest nominees, both from the
+	// first window; the cap keeps one and backfills from the second window.
+	if len(clips) != 2 {
+		t.Fatalf("expected 2 clips, got %+v", clips)
+	}
+	if clips[0].Start != 0 || clips[1].Start != 10*time.Minute {
+		t.Fatalf("expected clips from different windows, got %v and %v", clips[0].Start, clips[1].Start)
+	}
+}
+
+func TestRefine_ChunkedUsesSuppliedCandidates(t *testing.T) {
+	srv := &greedyServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var tr types.Transcript
+	for i := 0; i < 30; i++ {
+		tr.Segments = append(tr.Segments, types.Segment{Start: float64(i * 60), End: float64(i*60 + 60), Text: "x"})
+	}
+	// An edited candidate running across the edge of the second window.
+	cands := []types.Candidate{{Start: 19*time.Minute + 30*time.Second, End: 20*time.Minute + 30*time.Second, Text: "edited candidate"}}
+
+	c := New(Config{URL: ts.URL, Models: []string{"m"}, Format: FormatJSONSchema, Chunking: Chunking{Window: 10 * time.Minute}})
+	if _, err := c.Refine(context.Background(), tr, cands, 2, highlights.DefaultDurations()); err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	found := 0
+	for _, p := range srv.prompts {
+		if !strings.Contains(p, "nominated from different parts") && strings.Contains(p, "edited candidate") {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Fatalf("expected the supplied candidate in exactly one window prompt, found it in %d", found)
+	}
+}
+
+func TestRefine_ChunkingSkippedForShortTranscripts(t *testing.T) {
+	srv := &greedyServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	tr := types.Transcript{Segments: []types.Segment{{Start: 0, End: 60, Text: "x"}}}
+	c := New(Config{URL: ts.URL, Models: []string{"m"}, Format: FormatJSONSchema, Chunking: Chunking{Window: 10 * time.Minute}})
+	cands := []types.Candidate{{Start: 0, End: 30 * time.Second, Text: "c"}}
+	if _, err := c.Refine(context.Background(), tr, cands, 1, highlights.DefaultDurations()); err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if srv.nominate != 1 || srv.rank != 0 {
+		t.Fatalf("expected a single request, got %d + %d", srv.nominate, srv.rank)
+	}
+}
+
+func TestRefine_UsesClipDurations(t *testing.T) {
+	var prm struct {
+		MinSec float64 `json:"minSec"`
+		MaxSec float64 `json:"maxSec"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		prompt := req.Messages[0].Content
+		_ = json.Unmarshal([]byte(prompt[strings.Index(prompt, "Candidates JSON:\n")+len("Candidates JSON:\n"):]), &prm)
+		content := `{"clips":[{"idx":0,"start_sec":0,"end_sec":90,"title":"T","caption":"C","tags":[],"reason":"r"}]}`
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]any{"content": content}}},
+		})
+	}))
+	defer ts.Close()
+
+	d := types.ClipDurations{Min: 10 * time.Second, Max: 40 * time.Second}
+	c := New(Config{URL: ts.URL, Models: []string{"m"}, Format: FormatJSONSchema})
+	cands := []types.Candidate{{Start: 0, End: 90 * time.Second, Text: "c"}}
+	clips, err := c.Refine(context.Background(), types.Transcript{}, cands, 1, d)
+	if err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if prm.MinSec != 10 || prm.MaxSec != 40 {
+		t.Fatalf("expected the bounds in the prompt, got %v..%v", prm.MinSec, prm.MaxSec)
+	}
+	if len(clips) != 1 || clips[0].End-clips[0].Start > d.Max {
+		t.Fatalf("expected one clip of at most %s, got %+v", d.Max, clips)
+	}
+}
@@ -0,0 +1,208 @@
+package chatcompletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy bounds the attempts made against each model before the next
+// fallback model is tried.
+type RetryPolicy struct {
+	// MaxAttempts per model, including the first request.
+	MaxAttempts int
+	// BaseDelay is the first backoff delay; it doubles per attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay moves on
+	// to the next model instead of waiting.
+	MaxDelay time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: 2 * time.Second, MaxDelay: 30 * time.Second}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = def.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = def.MaxDelay
+	}
+	return p
+}
+
+// backoff returns the jittered delay before attempt+1: half of the
+// exponential delay is fixed, the other half random.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// requestError is a failed attempt, kept structured for retry decisions.
+type requestError struct {
+	// status is the HTTP status, 0 for transport errors, timeouts and
+	// unreadable responses.
+	status     int
+	retryAfter time.Duration
+	// formatRejected is set when the server refused the response_format.
+	formatRejected bool
+	err            error
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+func (e *requestError) Unwrap() error { return e.err }
+
+type outcome int
+
+const (
+	retrySameModel outcome = iota
+	tryNextModel
+	giveUp
+)
+
+// classify decides what to do after a failed attempt. Rate limits, server
+// errors and transport failures are retried; auth failures stop the chain
+// since every model shares the key; other client errors (unknown model,
+// payment required, bad request) are model-specific and move on.
+func classify(err error) outcome {
+	var re *requestError
+	if !errors.As(err, &re) {
+		return giveUp
+	}
+	switch s := re.status; {
+	case s == 0,
+		s == http.StatusRequestTimeout,
+		s == http.StatusConflict,
+		s == http.StatusTooEarly,
+		s == http.StatusTooManyRequests:
+		return retrySameModel
+	case s == http.StatusUnauthorized, s == http.StatusForbidden:
+		return giveUp
+	case s >= 500 && s != http.StatusNotImplemented:
+		return retrySameModel
+	default:
+		return tryNextModel
+	}
+}
+
+// complete sends the prompt to each model in turn, retrying transient
+// failures with backoff, and returns the content and the model that answered.
+// ok is false when the server answered without usable content. In auto mode a
+// rejected response_format is downgraded and the request repeated without
+// counting as an attempt; the working format is remembered for later calls.
+func (c *Client) complete(ctx context.Context, candsJSON []byte, note string) (string, string, bool, error) {
+	policy := c.cfg.Retry.withDefaults()
+	var lastErr error
+	for mi, model := range c.cfg.Models {
+		if mi > 0 {
+			c.logf("llm: %s trying fallback model %s (%d/%d)", c.cfg.Provider, model, mi+1, len(c.cfg.Models))
+		}
+		for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+			format := c.currentFormat()
+			started := time.Now()
+			content, ok, err := c.post(ctx, candsJSON, note, model, format)
+			if err == nil {
+				c.logf(
+					"llm: %s answered (model=%s, attempt %d/%d) in %s",
+					c.cfg.Provider, model, attempt, policy.MaxAttempts, time.Since(started).Round(100*time.Millisecond),
+				)
+				return content, model, ok, nil
+			}
+			if ctx.Err() != nil {
+				return "", "", false, ctx.Err()
+			}
+			var re *requestError
+			if errors.As(err, &re) && re.formatRejected && c.cfg.Format == FormatAuto && c.downgrade(format) {
+				c.logf("llm: %s rejected response_format %s; using %s", c.cfg.Provider, format, c.currentFormat())
+				attempt--
+				continue
+			}
+			lastErr = err
+
+			action := classify(err)
+			if action == retrySameModel && attempt < policy.MaxAttempts {
+				delay := policy.backoff(attempt)
+				if re != nil && re.retryAfter > 0 {
+					delay = re.retryAfter
+				}
+				if delay <= policy.MaxDelay {
+					c.logf(
+						"llm: %s attempt %d/%d (model=%s) failed: %v; retrying in %s",
+						c.cfg.Provider, attempt, policy.MaxAttempts, model, err, delay.Round(100*time.Millisecond),
+					)
+					if err := sleep(ctx, delay); err != nil {
+						return "", "", false, err
+					}
+					continue
+				}
+				c.logf("llm: %s asked to retry after %s, more than %s", c.cfg.Provider, delay, policy.MaxDelay)
+			}
+			c.logf("llm: %s attempt %d/%d (model=%s) failed: %v", c.cfg.Provider, attempt, policy.MaxAttempts, model, err)
+			if action == giveUp {
+				return "", "", false, err
+			}
+			break
+		}
+	}
+	if lastErr == nil {
+		return "", "", false, fmt.Errorf("%s: no model configured", c.cfg.Provider)
+	}
+	if len(c.cfg.Models) > 1 {
+		return "", "", false, fmt.Errorf("%s: all %d models failed, last error: %w", c.cfg.Provider, len(c.cfg.Models), lastErr)
+	}
+	return "", "", false, lastErr
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date; 0 means absent or unparsable.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		if sec < 0 {
+			return 0
+		}
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (c *Client) logf(format string, args ...any) {
+	if c.cfg.Logf != nil {
+		c.cfg.Logf(format, args...)
+	}
+}
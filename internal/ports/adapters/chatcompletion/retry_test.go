@@ -0,0 +1,183 @@
+package chatcompletion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// scriptedServer replies to each request with the next scripted status for
+// the requested model; 200 returns one valid clip.
+type scriptedServer struct {
+	mu         sync.Mutex
+	script     map[string][]int
+	retryAfter string
+	calls      []string
+}
+
+func (s *scriptedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	s.mu.Lock()
+	s.calls = append(s.calls, req.Model)
+	status := http.StatusOK
+	if q := s.script[req.Model]; len(q) > 0 {
+		status = q[0]
+		if len(q) > 1 {
+			s.script[req.Model] = q[1:]
+		}
+	}
+	s.mu.Unlock()
+
+	if status != http.StatusOK {
+		if s.retryAfter != "" {
+			w.Header().Set("Retry-After", s.retryAfter)
+		}
+		http.Error(w, `{"error":"nope"}`, status)
+		return
+	}
+	content := `{"clips":[{"idx":0,"start_sec":0,"end_sec":30,"title":"T","caption":"C","tags":[],"keywords":["compound"],"reason":"r"}]}`
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"choices": []map[string]any{{"message": map[string]any{"content": content}}},
+	})
+}
+
+func newScriptedClient(t *testing.T, srv *scriptedServer, models ...string) (*Client, *[]string) {
+	t.Helper()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	var logs []string
+	c := New(Config{
+		Provider: "test",
+		URL:      ts.URL,
+		Models:   models,
+		Format:   FormatJSONSchema,
+		Retry:    RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond},
+		Logf: func(format string, args ...any) {
+			logs = append(logs, format)
+		},
+	})
+	return c, &logs
+}
+
+var oneCandidate = []types.Candidate{{Start: 0, End: 30 * time.Second, Text: "hello"}}
+
+func TestRefine_RetriesRateLimitThenSucceeds(t *testing.T) {
+	srv := &scriptedServer{script: map[string][]int{"a": {429, 200}}, retryAfter: "0"}
+	c, _ := newScriptedClient(t, srv, "a")
+
+	clips, err := c.Refine(context.Background(), types.Transcript{}, oneCandidate, 1, highlights.DefaultDurations())
+	if err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if len(srv.calls) != 2 {
+		t.Fatalf("expected 2 requests, got %v", srv.calls)
+	}
+	if len(clips) != 1 || clips[0].Model != "a" || strings.Join(clips[0].Keywords, ",") != "compound" {
+		t.Fatalf("expected clip with keywords answered by a, got %+v", clips)
+	}
+}
+
+func TestRefine_FallsBackToNextModel(t *testing.T) {
+	srv := &scriptedServer{script: map[string][]int{"a": {404}, "b": {200}}}
+	c, _ := newScriptedClient(t, srv, "a", "b")
+
+	clips, err := c.Refine(context.Background(), types.Transcript{}, oneCandidate, 1, highlights.DefaultDurations())
+	if err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if strings.Join(srv.calls, ",") != "a,b" {
+		t.Fatalf("expected a single request per model, got %v", srv.calls)
+	}
+	if len(clips) != 1 || clips[0].Model != "b" {
+		t.Fatalf("expected clip answered by fallback b, got %+v", clips)
+	}
+}
+
+func TestRefine_AuthFailureIsFatal(t *testing.T) {
+	srv := &scriptedServer{script: map[string][]int{"a": {401}}}
+	c, _ := newScriptedClient(t, srv, "a", "b")
+
+	if _, err := c.Refine(context.Background(), types.Transcript{}, oneCandidate, 1, highlights.DefaultDurations()); err == nil {
+		t.Fatalf("expected error")
+	}
+	if strings.Join(srv.calls, ",") != "a" {
+		t.Fatalf("expected no retries or fallback after 401, got %v", srv.calls)
+	}
+}
+
+func TestRefine_AllModelsExhausted(t *testing.T) {
+	srv := &scriptedServer{script: map[string][]int{"a": {503}, "b": {502}}}
+	c, logs := newScriptedClient(t, srv, "a", "b")
+
+	_, err := c.Refine(context.Background(), types.Transcript{}, oneCandidate, 1, highlights.DefaultDurations())
+	if err == nil || !strings.Contains(err.Error(), "all 2 models failed") {
+		t.Fatalf("expected exhausted-chain error, got %v", err)
+	}
+	if strings.Join(srv.calls, ",") != "a,a,b,b" {
+		t.Fatalf("expected MaxAttempts per model, got %v", srv.calls)
+	}
+	if len(*logs) == 0 {
+		t.Fatalf("expected attempts to be logged")
+	}
+}
+
+func TestRefine_LongRetryAfterSkipsToNextModel(t *testing.T) {
+	srv := &scriptedServer{script: map[string][]int{"a": {429}, "b": {200}}, retryAfter: "3600"}
+	c, _ := newScriptedClient(t, srv, "a", "b")
+
+	started := time.Now()
+	clips, err := c.Refine(context.Background(), types.Transcript{}, oneCandidate, 1, highlights.DefaultDurations())
+	if err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if time.Since(started) > 5*time.Second {
+		t.Fatalf("should not wait for a Retry-After above MaxDelay")
+	}
+	if strings.Join(srv.calls, ",") != "a,b" || clips[0].Model != "b" {
+		t.Fatalf("unexpected calls %v / clips %+v", srv.calls, clips)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"-1", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.in, now); got != tt.want {
+			t.Fatalf("parseRetryAfter(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff_StaysWithinBounds(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for attempt := 1; attempt <= 5; attempt++ {
+		for i := 0; i < 50; i++ {
+			d := p.backoff(attempt)
+			if d < 50*time.Millisecond || d > p.MaxDelay {
+				t.Fatalf("attempt %d: backoff %v out of bounds", attempt, d)
+			}
+		}
+	}
+}
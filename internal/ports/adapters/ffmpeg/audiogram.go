@@ -0,0 +1,164 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// RenderAudiogram renders a clip of an audio-only input as video: the cover
+// image (or a solid color) as background, a waveform of the clip's audio, the
+// episode title at the top and, when set, burned captions. opts.Frame must be
+// set; it is the output size.
+func (a *Adapter) RenderAudiogram(
+	ctx context.Context,
+	in string,
+	start, end time.Duration,
+	outPath string,
+	ag types.Audiogram,
+	opts types.RenderOptions,
+) (types.RenderResult, error) {
+	if !opts.Frame.Reframes() {
+		return types.RenderResult{}, fmt.Errorf("audiogram needs an output frame")
+	}
+	var (
+		res      types.RenderResult
+		measured *loudness
+	)
+	if opts.Audio.LoudnessLUFS != 0 {
+		m, err := a.measureLoudness(ctx, in, start, end, opts)
+		if err != nil {
+			return types.RenderResult{}, err
+		}
+		if m.normalizable() {
+			measured = &m
+		}
+	}
+
+	titleFile := ""
+	if title := strings.TrimSpace(ag.Title); title != "" {
+		// drawtext reads the title from a file, which avoids filtergraph
+		// escaping of arbitrary text.
+		f, err := os.CreateTemp("", "hlcut-title-*.txt")
+		if err != nil {
+			return types.RenderResult{}, fmt.Errorf("audiogram title: %w", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(fitTitle(title, opts.Frame))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return types.RenderResult{}, fmt.Errorf("audiogram title: %w", err)
+		}
+		titleFile = f.Name()
+	}
+
+	dur := clipDuration(start, end, opts.Keep)
+	args := []string{
+		"-y",
+		"-ss", fmtSeconds(start),
+		"-to", fmtSeconds(end),
+		"-i", in,
+	}
+	if ag.Cover != "" {
+		args = append(args, "-loop", "1", "-framerate", strconv.Itoa(audiogramFPS), "-i", ag.Cover)
+	} else {
+		args = append(args, "-f", "lavfi", "-i", fmt.Sprintf(
+			"color=c=%s:s=%dx%d:r=%d", audiogramBackground(ag), opts.Frame.Width, opts.Frame.Height, audiogramFPS,
+		))
+	}
+	af := audioFilter(opts.Audio, dur, measured)
+	args = append(args,
+		"-filter_complex", audiogramFilter(start, opts, af, titleFile),
+		"-map", "[vout]", "-map", "[aout]",
+		"-t", fmtSeconds(dur),
+	)
+	args = append(args, encodeArgs(opts.Format, opts.MaxVideoKbps)...)
+	args = append(args, outPath)
+	b, err := exec.CommandContext(ctx, a.ffmpeg, args...).CombinedOutput()
+	if err != nil {
+		return types.RenderResult{}, fmt.Errorf("ffmpeg render audiogram: %w\n%s", err, string(b))
+	}
+	if measured != nil {
+		out, err := parseLoudnorm(b)
+		if err != nil {
+			return types.RenderResult{}, fmt.Errorf("ffmpeg render audiogram: %w", err)
+		}
+		res.SourceLoudnessLUFS = measured.inputI
+		res.LoudnessLUFS = out.outputI
+	}
+	return res, nil
+}
+
+const (
+	audiogramFPS = 30
+	// DefaultAudiogramBackground is used without a cover image or color.
+	DefaultAudiogramBackground = "#101820"
+)
+
+func audiogramBackground(ag types.Audiogram) string {
+	if ag.Background == "" {
+		return DefaultAudiogramBackground
+	}
+	return ag.Background
+}
+
+// audiogramFilter builds the audiogram graph. Input 0 is the audio, input 1
+// the background. The audio (joined kept ranges when tightened) goes through
+// af and is split into the output and the waveform.
+func audiogramFilter(start time.Duration, opts types.RenderOptions, af, titleFile string) string {
+	w, h := opts.Frame.Width, opts.Frame.Height
+	var b strings.Builder
+	src := "[" + audioMap(opts.AudioTrack) + "]"
+	if len(opts.Keep) > 0 {
+		for i, r := range opts.Keep {
+			fmt.Fprintf(&b, "%satrim=start=%s:end=%s,asetpts=PTS-STARTPTS[a%d];", src, fmtSeconds(r.Start-start), fmtSeconds(r.End-start), i)
+		}
+		for i := range opts.Keep {
+			fmt.Fprintf(&b, "[a%d]", i)
+		}
+		fmt.Fprintf(&b, "concat=n=%d:v=0:a=1[acat];", len(opts.Keep))
+		src = "[acat]"
+	}
+	if af == "" {
+		af = "anull"
+	}
+	fmt.Fprintf(&b, "%s%s,asplit=2[aout][awave];", src, af)
+	fmt.Fprintf(&b, "[1:v]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,setsar=1,format=yuv420p[bg];", w, h, w, h)
+	fmt.Fprintf(&b, "[awave]showwaves=s=%dx%d:mode=cline:rate=%d:colors=white,format=yuva420p[wave];",
+		even(w*9/10), even(h/4), audiogramFPS)
+	b.WriteString("[bg][wave]overlay=(W-w)/2:(H-h)/2:shortest=1")
+	if titleFile != "" {
+		size := min(w, h) / 18
+		fmt.Fprintf(&b,
+			",drawtext=textfile=%s:expansion=none:fontcolor=white:fontsize=%d:x=(w-text_w)/2:y=%d:box=1:boxcolor=black@0.45:boxborderw=%d",
+			escapeFilterPath(titleFile), size, h*8/100, size/2,
+		)
+	}
+	if vf := videoFilter(types.RenderOptions{BurnASS: opts.BurnASS, FontsDir: opts.FontsDir}); vf != "" {
+		b.WriteString("," + vf)
+	}
+	b.WriteString("[vout]")
+	return b.String()
+}
+
+// fitTitle shortens the title to one line of the frame; drawtext does not
+// wrap.
+func fitTitle(title string, f types.Frame) string {
+	// Glyphs average about 0.55em at the drawtext size of min(w,h)/18.
+	maxChars := max(8, f.Width*18*100/(min(f.Width, f.Height)*55)*9/10)
+	r := []rune(title)
+	if len(r) <= maxChars {
+		return title
+	}
+	return strings.TrimSpace(string(r[:maxChars-1])) + "…"
+}
+
+func even(n int) int { return n &^ 1 }
@@ -0,0 +1,95 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func TestAudiogramFilter(t *testing.T) {
+	const (
+		bg   = "[1:v]scale=1080:1920:force_original_aspect_ratio=increase,crop=1080:1920,setsar=1,format=yuv420p[bg];"
+		wave = "[awave]showwaves=s=972x480:mode=cline:rate=30:colors=white,format=yuva420p[wave];" +
+			"[bg][wave]overlay=(W-w)/2:(H-h)/2:shortest=1"
+	)
+	frame := types.Frame{Aspect: "9:16", Width: 1080, Height: 1920}
+	tests := []struct {
+		name      string
+		opts      types.RenderOptions
+		af        string
+		titleFile string
+		want      string
+	}{
+		{
+			name: "waveform only",
+			opts: types.RenderOptions{Frame: frame, AudioTrack: 1},
+			want: "[0:a:1]anull,asplit=2[aout][awave];" + bg + wave + "[vout]",
+		},
+		{
+			name: "tightened audio goes through af",
+			opts: types.RenderOptions{Frame: frame, Keep: []types.Range{
+				{Start: 11 * time.Second, End: 13 * time.Second},
+				{Start: 15 * time.Second, End: 16500 * time.Millisecond},
+			}},
+			af: "afade=t=in:st=0:d=0.500",
+			want: "[0:a:0]atrim=start=1.000:end=3.000,asetpts=PTS-STARTPTS[a0];" +
+				"[0:a:0]atrim=start=5.000:end=6.500,asetpts=PTS-STARTPTS[a1];" +
+				"[a0][a1]concat=n=2:v=0:a=1[acat];" +
+				"[acat]afade=t=in:st=0:d=0.500,asplit=2[aout][awave];" + bg + wave + "[vout]",
+		},
+		{
+			name:      "title and burned captions",
+			opts:      types.RenderOptions{Frame: frame, BurnASS: "C:/run/001.ass", FontsDir: "fonts"},
+			titleFile: "/tmp/hlcut-title-1.txt",
+			want: "[0:a:0]anull,asplit=2[aout][awave];" + bg + wave +
+				",drawtext=textfile=/tmp/hlcut-title-1.txt:expansion=none:fontcolor=white:fontsize=60:" +
+				"x=(w-text_w)/2:y=153:box=1:boxcolor=black@0.45:boxborderw=30" +
+				",subtitles=C\\:/run/001.ass:fontsdir=fonts[vout]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := audiogramFilter(10*time.Second, tt.opts, tt.af, tt.titleFile); got != tt.want {
+				t.Fatalf("audiogramFilter() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitTitle(t *testing.T) {
+	vertical := types.Frame{Width: 1080, Height: 1920}
+	tests := []struct {
+		name  string
+		title string
+		frame types.Frame
+		want  string
+	}{
+		{name: "fits", title: "Episode 12: Pricing", frame: vertical, want: "Episode 12: Pricing"},
+		{
+			name:  "cut to 28 runes on a vertical frame",
+			title: "How we doubled revenue without hiring anyone",
+			frame: vertical,
+			want:  "How we doubled revenue with…",
+		},
+		{
+			name:  "trailing space dropped before the ellipsis",
+			title: "Ñandú día por día, sin pro y contra",
+			frame: vertical,
+			want:  "Ñandú día por día, sin pro…",
+		},
+		{
+			name:  "wide frames fit more",
+			title: "How we doubled revenue without hiring anyone",
+			frame: types.Frame{Width: 1920, Height: 1080},
+			want:  "How we doubled revenue without hiring anyone",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fitTitle(tt.title, tt.frame); got != tt.want {
+				t.Fatalf("fitTitle(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
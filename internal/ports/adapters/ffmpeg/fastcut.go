@@ -0,0 +1,236 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// keyframeWindow bounds how far around a cut point packets are inspected.
+const keyframeWindow = 30 * time.Second
+
+// KeyframeBefore returns the last video keyframe at or before t, found by
+// inspecting packet flags with ffprobe; 0 when there is none.
+func (a *Adapter) KeyframeBefore(ctx context.Context, in string, t time.Duration) (time.Duration, error) {
+	for _, from := range []time.Duration{max(0, t-keyframeWindow), 0} {
+		kfs, err := a.keyframes(ctx, in, from, t+time.Millisecond)
+		if err != nil {
+			return 0, err
+		}
+		for i := len(kfs) - 1; i >= 0; i-- {
+			if kfs[i] <= t {
+				return kfs[i], nil
+			}
+		}
+		if from == 0 {
+			break
+		}
+	}
+	return 0, nil
+}
+
+// keyframes lists the video keyframe times in [from, to].
+func (a *Adapter) keyframes(ctx context.Context, in string, from, to time.Duration) ([]time.Duration, error) {
+	cmd := exec.CommandContext(ctx, a.ffprobe,
+		"-v", "error",
+		"-select_streams", "V:0",
+		"-read_intervals", fmtSeconds(from)+"%"+fmtSeconds(to),
+		"-show_entries", "packet=pts_time,flags",
+		"-of", "csv=p=0",
+		in,
+	)
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe keyframes: %w", err)
+	}
+	return parseKeyframes(string(b), from, to), nil
+}
+
+// parseKeyframes reads "pts_time,flags" lines, keeping keyframes (flag K)
+// in [from, to]. ffprobe starts reading at the keyframe before from, so
+// earlier packets are dropped.
+func parseKeyframes(out string, from, to time.Duration) []time.Duration {
+	var kfs []time.Duration
+	for _, line := range strings.Split(out, "\n") {
+		pts, flags, ok := strings.Cut(strings.TrimSpace(line), ",")
+		if !ok || !strings.Contains(flags, "K") {
+			continue
+		}
+		sec, err := strconv.ParseFloat(pts, 64)
+		if err != nil {
+			continue
+		}
+		if t := types.Seconds(sec); t >= from && t <= to {
+			kfs = append(kfs, t)
+		}
+	}
+	return kfs
+}
+
+// fastCuttable reports whether a clip can skip the video encode: nothing
+// filters the video or audio.
+func fastCuttable(opts types.RenderOptions) bool {
+	return opts.FastCut != "" && opts.BurnASS == "" && !opts.Frame.Reframes() && len(opts.Keep) == 0 &&
+		opts.Audio == (types.AudioOptions{})
+}
+
+// copyCut stream-copies [start, end). start should be a keyframe, or the
+// clip starts at the keyframe before it.
+func (a *Adapter) copyCut(ctx context.Context, in string, start, end time.Duration, outPath string, opts types.RenderOptions) error {
+	args := []string{
+		"-y",
+		"-ss", fmtSeconds(start),
+		"-to", fmtSeconds(end),
+		"-i", in,
+		"-map", "0:V:0", "-map", audioMap(opts.AudioTrack),
+		"-c", "copy",
+		"-avoid_negative_ts", "make_zero",
+	}
+	args = append(args, formatProfile(opts.Format).mux...)
+	args = append(args, outPath)
+	b, err := exec.CommandContext(ctx, a.ffmpeg, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg copy clip: %w\n%s", err, string(b))
+	}
+	return nil
+}
+
+// smartCut cuts exactly at start without encoding the whole clip: the video
+// up to the first keyframe is re-encoded, the rest is stream-copied, and the
+// audio is encoded. It reports false when the source cannot be smart-cut
+// (not h264, or no keyframe inside the clip) and the clip must be encoded.
+func (a *Adapter) smartCut(ctx context.Context, in string, start, end time.Duration, outPath string, opts types.RenderOptions) (bool, error) {
+	src, err := a.videoCodec(ctx, in)
+	if err != nil {
+		return false, err
+	}
+	if src.codec != "h264" {
+		return false, nil
+	}
+	kfs, err := a.keyframes(ctx, in, start, min(end, start+keyframeWindow))
+	if err != nil {
+		return false, err
+	}
+	if len(kfs) == 0 || kfs[0] >= end-time.Second {
+		return false, nil
+	}
+	k := kfs[0]
+	if k-start < time.Millisecond {
+		return true, a.copyCut(ctx, in, start, end, outPath, opts)
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(outPath), ".smartcut-")
+	if err != nil {
+		return false, fmt.Errorf("smart cut: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	head, tail, list := filepath.Join(tmp, "head.ts"), filepath.Join(tmp, "tail.ts"), filepath.Join(tmp, "list.txt")
+	// The head shares one track with the copied tail, so it is encoded with
+	// the source's profile and level; strict players reject a stream whose
+	// parameter sets change midway.
+	headArgs := []string{
+		"-y", "-ss", fmtSeconds(start), "-to", fmtSeconds(k), "-i", in,
+		"-map", "0:V:0", "-an", "-c:v", "libx264", "-preset", "veryfast", "-crf", "18", "-pix_fmt", src.pixFmt,
+	}
+	headArgs = append(headArgs, src.x264Args()...)
+	steps := [][]string{
+		append(headArgs, head),
+		{"-y", "-ss", fmtSeconds(k), "-to", fmtSeconds(end), "-i", in, "-map", "0:V:0", "-an", "-c:v", "copy", tail},
+	}
+	for _, args := range steps {
+		if b, err := exec.CommandContext(ctx, a.ffmpeg, args...).CombinedOutput(); err != nil {
+			return false, fmt.Errorf("ffmpeg smart cut: %w\n%s", err, string(b))
+		}
+	}
+	if err := os.WriteFile(list, []byte("file 'head.ts'\nfile 'tail.ts'\n"), 0o644); err != nil {
+		return false, fmt.Errorf("smart cut: %w", err)
+	}
+	args := []string{
+		"-y",
+		"-f", "concat", "-safe", "0", "-i", list,
+		"-ss", fmtSeconds(start), "-to", fmtSeconds(end), "-i", in,
+		"-map", "0:v", "-map", "1:a:" + strconv.Itoa(opts.AudioTrack),
+		"-c:v", "copy",
+	}
+	p := formatProfile(opts.Format)
+	args = append(args, p.audio...)
+	args = append(args, p.mux...)
+	args = append(args, outPath)
+	if b, err := exec.CommandContext(ctx, a.ffmpeg, args...).CombinedOutput(); err != nil {
+		return false, fmt.Errorf("ffmpeg smart cut: %w\n%s", err, string(b))
+	}
+	return true, nil
+}
+
+// videoStream is what a smart cut needs to know about the source video.
+type videoStream struct {
+	codec, pixFmt, profile string
+	// level is ffprobe's level_idc, e.g. 41 for level 4.1.
+	level int
+}
+
+// videoCodec probes the first video stream.
+func (a *Adapter) videoCodec(ctx context.Context, in string) (videoStream, error) {
+	cmd := exec.CommandContext(ctx, a.ffprobe,
+		"-v", "error",
+		"-select_streams", "V:0",
+		"-show_entries", "stream=codec_name,pix_fmt,profile,level",
+		"-of", "default=noprint_wrappers=1",
+		in,
+	)
+	b, err := cmd.Output()
+	if err != nil {
+		return videoStream{}, fmt.Errorf("ffprobe video codec: %w", err)
+	}
+	return parseVideoStream(string(b)), nil
+}
+
+// parseVideoStream reads ffprobe's key=value stream entries.
+func parseVideoStream(out string) videoStream {
+	var v videoStream
+	for _, line := range strings.Split(out, "\n") {
+		key, val, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "codec_name":
+			v.codec = val
+		case "pix_fmt":
+			v.pixFmt = val
+		case "profile":
+			v.profile = val
+		case "level":
+			v.level, _ = strconv.Atoi(val)
+		}
+	}
+	return v
+}
+
+// x264Profiles maps ffprobe's h264 profile names to libx264's.
+var x264Profiles = map[string]string{
+	"Baseline":              "baseline",
+	"Constrained Baseline":  "baseline",
+	"Main":                  "main",
+	"High":                  "high",
+	"High 10":               "high10",
+	"High 4:2:2":            "high422",
+	"High 4:4:4 Predictive": "high444",
+}
+
+// x264Args returns the libx264 options that reproduce the stream's profile
+// and level; unknown values are left to libx264.
+func (v videoStream) x264Args() []string {
+	var args []string
+	if p := x264Profiles[v.profile]; p != "" {
+		args = append(args, "-profile:v", p)
+	}
+	if v.level > 0 {
+		args = append(args, "-level:v", fmt.Sprintf("%d.%d", v.level/10, v.level%10))
+	}
+	return args
+}
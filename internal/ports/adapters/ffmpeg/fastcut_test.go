@@ -0,0 +1,115 @@
+package ffmpeg
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func TestParseKeyframes(t *testing.T) {
+	tests := []struct {
+		name     string
+		out      string
+		from, to time.Duration
+		want     []time.Duration
+	}{
+		{
+			name: "keeps keyframes in range",
+			out:  "10.000000,K__\n10.033333,___\n12.500000,K_\n14.000000,K__\n",
+			from: 10 * time.Second,
+			to:   13 * time.Second,
+			want: []time.Duration{10 * time.Second, 12500 * time.Millisecond},
+		},
+		{
+			name: "drops packets before the window",
+			out:  "8.000000,K__\n11.000000,K__",
+			from: 10 * time.Second,
+			to:   20 * time.Second,
+			want: []time.Duration{11 * time.Second},
+		},
+		{
+			name: "skips malformed lines",
+			out:  "\nN/A,K__\nnot a packet\n 3.250000,K_D \n",
+			from: 0,
+			to:   5 * time.Second,
+			want: []time.Duration{3250 * time.Millisecond},
+		},
+		{
+			name: "no keyframes",
+			out:  "1.000000,___\n2.000000,__D\n",
+			from: 0,
+			to:   5 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseKeyframes(tt.out, tt.from, tt.to); !slices.Equal(got, tt.want) {
+				t.Fatalf("parseKeyframes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFastCuttable(t *testing.T) {
+	tests := []struct {
+		name string
+		opts types.RenderOptions
+		want bool
+	}{
+		{name: "copy", opts: types.RenderOptions{FastCut: types.FastCutCopy}, want: true},
+		{name: "smart keeps source frame", opts: types.RenderOptions{FastCut: types.FastCutSmart, Frame: types.Frame{Aspect: "9:16"}}, want: true},
+		{name: "off", opts: types.RenderOptions{}},
+		{name: "burned subtitles", opts: types.RenderOptions{FastCut: types.FastCutCopy, BurnASS: "001.ass"}},
+		{name: "reframed", opts: types.RenderOptions{FastCut: types.FastCutCopy, Frame: types.Frame{Width: 1080, Height: 1920}}},
+		{name: "tightened", opts: types.RenderOptions{FastCut: types.FastCutCopy, Keep: []types.Range{{Start: 0, End: time.Second}}}},
+		{name: "loudnorm", opts: types.RenderOptions{FastCut: types.FastCutCopy, Audio: types.AudioOptions{LoudnessLUFS: -14}}},
+		{name: "fades", opts: types.RenderOptions{FastCut: types.FastCutCopy, Audio: types.AudioOptions{Fade: time.Second}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fastCuttable(tt.opts); got != tt.want {
+				t.Fatalf("fastCuttable() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVideoStreamX264Args(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want videoStream
+		args []string
+	}{
+		{
+			name: "high 4.1",
+			out:  "codec_name=h264\nprofile=High\npix_fmt=yuv420p\nlevel=41\n",
+			want: videoStream{codec: "h264", pixFmt: "yuv420p", profile: "High", level: 41},
+			args: []string{"-profile:v", "high", "-level:v", "4.1"},
+		},
+		{
+			name: "constrained baseline 3.0",
+			out:  "codec_name=h264\nprofile=Constrained Baseline\npix_fmt=yuv420p\nlevel=30",
+			want: videoStream{codec: "h264", pixFmt: "yuv420p", profile: "Constrained Baseline", level: 30},
+			args: []string{"-profile:v", "baseline", "-level:v", "3.0"},
+		},
+		{
+			name: "unknown profile and level",
+			out:  "codec_name=hevc\nprofile=Main 10\npix_fmt=yuv420p10le\nlevel=-99999\n",
+			want: videoStream{codec: "hevc", pixFmt: "yuv420p10le", profile: "Main 10", level: -99999},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseVideoStream(tt.out)
+			if got != tt.want {
+				t.Fatalf("parseVideoStream() = %+v, want %+v", got, tt.want)
+			}
+			if args := got.x264Args(); !slices.Equal(args, tt.args) {
+				t.Fatalf("x264Args() = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/framing"
+	"github.com/forPelevin/hlcut/internal/types"
 )
 
 type Adapter struct {
@@ -24,12 +27,15 @@ func New(ffmpegPath, ffprobePath string) *Adapter {
 	return &Adapter{ffmpeg: ffmpegPath, ffprobe: ffprobePath}
 }
 
-func (a *Adapter) ExtractAudioMono16k(ctx context.Context, inMP4, outWav string) error {
+// ExtractAudio16k writes audio track audioTrack of the input as the 16kHz
+// WAV whisper expects, downmixed to channels channels.
+func (a *Adapter) ExtractAudio16k(ctx context.Context, in, outWav string, audioTrack, channels int) error {
 	cmd := exec.CommandContext(ctx, a.ffmpeg,
 		"-y",
-		"-i", inMP4,
+		"-i", in,
+		"-map", audioMap(audioTrack),
 		"-vn",
-		"-ac", "1",
+		"-ac", strconv.Itoa(channels),
 		"-ar", "16000",
 		"-f", "wav",
 		outWav,
@@ -41,38 +47,182 @@ func (a *Adapter) ExtractAudioMono16k(ctx context.Context, inMP4, outWav string)
 	return nil
 }
 
-func (a *Adapter) RenderClip(ctx context.Context, inMP4 string, start, end time.Duration, outMP4 string, burnASS string) error {
+func (a *Adapter) RenderClip(
+	ctx context.Context,
+	in string,
+	start, end time.Duration,
+	outPath string,
+	opts types.RenderOptions,
+) (types.RenderResult, error) {
+	switch {
+	case !fastCuttable(opts):
+	case opts.FastCut == types.FastCutSmart:
+		ok, err := a.smartCut(ctx, in, start, end, outPath, opts)
+		if err != nil {
+			return types.RenderResult{}, err
+		}
+		if ok {
+			return types.RenderResult{CutMode: types.FastCutSmart}, nil
+		}
+	default:
+		if err := a.copyCut(ctx, in, start, end, outPath, opts); err != nil {
+			return types.RenderResult{}, err
+		}
+		return types.RenderResult{CutMode: types.FastCutCopy}, nil
+	}
+
+	var (
+		res      types.RenderResult
+		measured *loudness
+	)
+	if opts.Audio.LoudnessLUFS != 0 {
+		m, err := a.measureLoudness(ctx, in, start, end, opts)
+		if err != nil {
+			return types.RenderResult{}, err
+		}
+		// Silence measures -inf and cannot be normalized.
+		if m.normalizable() {
+			measured = &m
+		}
+	}
+
 	args := []string{
 		"-y",
 		"-ss", fmtSeconds(start),
 		"-to", fmtSeconds(end),
-		"-i", inMP4,
-	}
-	if burnASS != "" {
-		args = append(args, "-vf", "subtitles="+escapeFilterPath(burnASS))
-	}
-	args = append(args,
-		"-c:v", "libx264",
-		"-preset", "veryfast",
-		"-crf", "18",
-		"-c:a", "aac",
-		"-b:a", "192k",
-		outMP4,
-	)
+		"-i", in,
+	}
+	af := audioFilter(opts.Audio, clipDuration(start, end, opts.Keep), measured)
+	if len(opts.Keep) > 0 {
+		args = append(args, "-filter_complex", jumpCutFilter(start, opts, af), "-map", "[vout]", "-map", "[aout]")
+	} else {
+		args = append(args, "-map", "0:V:0", "-map", audioMap(opts.AudioTrack))
+		if vf := videoFilter(opts); vf != "" {
+			args = append(args, "-vf", vf)
+		}
+		if af != "" {
+			args = append(args, "-af", af)
+		}
+	}
+	args = append(args, encodeArgs(opts.Format, opts.MaxVideoKbps)...)
+	args = append(args, outPath)
 	cmd := exec.CommandContext(ctx, a.ffmpeg, args...)
 	b, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("ffmpeg render clip: %w\n%s", err, string(b))
+		return types.RenderResult{}, fmt.Errorf("ffmpeg render clip: %w\n%s", err, string(b))
+	}
+	if measured != nil {
+		out, err := parseLoudnorm(b)
+		if err != nil {
+			return types.RenderResult{}, fmt.Errorf("ffmpeg render clip: %w", err)
+		}
+		res.SourceLoudnessLUFS = measured.inputI
+		res.LoudnessLUFS = out.outputI
+	}
+	return res, nil
+}
+
+// videoFilter chains reframing and subtitle burn-in. Subtitles come last so
+// they are laid out on the output frame, matching the ASS PlayRes.
+func videoFilter(opts types.RenderOptions) string {
+	var parts []string
+	if opts.Frame.Reframes() {
+		parts = append(parts, frameFilter(opts.Frame))
+	}
+	if opts.BurnASS != "" {
+		sub := "subtitles=" + escapeFilterPath(opts.BurnASS)
+		if opts.FontsDir != "" {
+			sub += ":fontsdir=" + escapeFilterPath(opts.FontsDir)
+		}
+		parts = append(parts, sub)
+	}
+	return strings.Join(parts, ",")
+}
+
+// jumpCutFilter trims the kept ranges out of the input, joins them with
+// concat and runs the video filter and the audio filter af on the joined
+// streams. Input timestamps start at the seek point, so ranges are taken
+// relative to start.
+func jumpCutFilter(start time.Duration, opts types.RenderOptions, af string) string {
+	var b strings.Builder
+	for i, r := range opts.Keep {
+		from, to := fmtSeconds(r.Start-start), fmtSeconds(r.End-start)
+		fmt.Fprintf(&b, "[0:v]trim=start=%s:end=%s,setpts=PTS-STARTPTS[v%d];", from, to, i)
+		fmt.Fprintf(&b, "[%s]atrim=start=%s:end=%s,asetpts=PTS-STARTPTS[a%d];", audioMap(opts.AudioTrack), from, to, i)
+	}
+	for i := range opts.Keep {
+		fmt.Fprintf(&b, "[v%d][a%d]", i, i)
+	}
+	fmt.Fprintf(&b, "concat=n=%d:v=1:a=1[vcat][acat]", len(opts.Keep))
+	if vf := videoFilter(opts); vf != "" {
+		b.WriteString(";[vcat]" + vf + "[vout]")
+	} else {
+		b.WriteString(";[vcat]null[vout]")
+	}
+	if af != "" {
+		b.WriteString(";[acat]" + af + "[aout]")
+	} else {
+		b.WriteString(";[acat]anull[aout]")
+	}
+	return b.String()
+}
+
+// audioCutFilter joins the kept audio ranges of the audio track like
+// jumpCutFilter, for the loudness measurement pass.
+func audioCutFilter(start time.Duration, keep []types.Range, track int, af string) string {
+	var b strings.Builder
+	for i, r := range keep {
+		fmt.Fprintf(&b, "[%s]atrim=start=%s:end=%s,asetpts=PTS-STARTPTS[a%d];", audioMap(track), fmtSeconds(r.Start-start), fmtSeconds(r.End-start), i)
+	}
+	for i := range keep {
+		fmt.Fprintf(&b, "[a%d]", i)
+	}
+	fmt.Fprintf(&b, "concat=n=%d:v=0:a=1,%s[aout]", len(keep), af)
+	return b.String()
+}
+
+// audioMap is the stream specifier of an audio track.
+func audioMap(track int) string {
+	return "0:a:" + strconv.Itoa(track)
+}
+
+func clipDuration(start, end time.Duration, keep []types.Range) time.Duration {
+	if len(keep) == 0 {
+		return end - start
+	}
+	var d time.Duration
+	for _, r := range keep {
+		d += r.End - r.Start
+	}
+	return d
+}
+
+func frameFilter(f types.Frame) string {
+	w, h := f.Width, f.Height
+	cover := fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d", w, h, w, h)
+	fit := fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease:force_divisible_by=2", w, h)
+	switch f.Mode {
+	case framing.ModeBlurPad:
+		// The background is the cropped source, shrunk before blurring to keep
+		// the blur cheap, under the fitted source.
+		return fmt.Sprintf(
+			"split=2[src][top];[src]%s,scale=iw/4:-2,boxblur=10:2,scale=%d:%d,setsar=1[bg];[top]%s,setsar=1[fg];"+
+				"[bg][fg]overlay=(W-w)/2:(H-h)/2,setsar=1",
+			cover, w, h, fit,
+		)
+	case framing.ModeLetterbox:
+		return fmt.Sprintf("%s,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=black,setsar=1", fit, w, h)
+	default:
+		return cover + ",setsar=1"
 	}
-	return nil
 }
 
-func (a *Adapter) ProbeDuration(ctx context.Context, inMP4 string) (time.Duration, error) {
+func (a *Adapter) ProbeDuration(ctx context.Context, in string) (time.Duration, error) {
 	cmd := exec.CommandContext(ctx, a.ffprobe,
 		"-v", "error",
 		"-show_entries", "format=duration",
 		"-of", "default=noprint_wrappers=1:nokey=1",
-		inMP4,
+		in,
 	)
 	b, err := cmd.CombinedOutput()
 	if err != nil {
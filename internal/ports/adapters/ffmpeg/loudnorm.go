@@ -0,0 +1,127 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Social platforms normalize to about -14 LUFS; true peak and loudness range
+// follow the usual loudnorm settings for speech.
+const (
+	loudnormTP  = -1.5
+	loudnormLRA = 11
+)
+
+// loudness is what loudnorm reports with print_format=json.
+type loudness struct {
+	inputI, inputTP, inputLRA, inputThresh float64
+	outputI, targetOffset                  float64
+}
+
+func (l loudness) normalizable() bool {
+	return !math.IsInf(l.inputI, 0) && !math.IsNaN(l.inputI) && !math.IsInf(l.inputThresh, 0)
+}
+
+// measureLoudness is the first loudnorm pass: it analyzes the clip's audio
+// (after jump cuts) without writing anything.
+func (a *Adapter) measureLoudness(
+	ctx context.Context,
+	in string,
+	start, end time.Duration,
+	opts types.RenderOptions,
+) (loudness, error) {
+	analyze := fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s:print_format=json",
+		fmtFloat(opts.Audio.LoudnessLUFS), fmtFloat(loudnormTP), fmtFloat(loudnormLRA))
+	args := []string{
+		"-hide_banner",
+		"-ss", fmtSeconds(start),
+		"-to", fmtSeconds(end),
+		"-i", in,
+		"-vn",
+	}
+	if len(opts.Keep) > 0 {
+		args = append(args, "-filter_complex", audioCutFilter(start, opts.Keep, opts.AudioTrack, analyze), "-map", "[aout]")
+	} else {
+		args = append(args, "-map", audioMap(opts.AudioTrack), "-af", analyze)
+	}
+	args = append(args, "-f", "null", "-")
+	b, err := exec.CommandContext(ctx, a.ffmpeg, args...).CombinedOutput()
+	if err != nil {
+		return loudness{}, fmt.Errorf("ffmpeg measure loudness: %w\n%s", err, string(b))
+	}
+	l, err := parseLoudnorm(b)
+	if err != nil {
+		return loudness{}, fmt.Errorf("ffmpeg measure loudness: %w", err)
+	}
+	return l, nil
+}
+
+// audioFilter builds the audio chain of the render pass: the second, linear
+// loudnorm pass using the measurement (nil skips normalization), then fades.
+// loudnorm upsamples internally, so the output is resampled to 48kHz.
+func audioFilter(opts types.AudioOptions, dur time.Duration, m *loudness) string {
+	var parts []string
+	if m != nil {
+		parts = append(parts, fmt.Sprintf(
+			"loudnorm=I=%s:TP=%s:LRA=%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true:print_format=json",
+			fmtFloat(opts.LoudnessLUFS), fmtFloat(loudnormTP), fmtFloat(loudnormLRA),
+			fmtFloat(m.inputI), fmtFloat(m.inputTP), fmtFloat(m.inputLRA), fmtFloat(m.inputThresh), fmtFloat(m.targetOffset),
+		), "aresample=48000")
+	}
+	if fade := min(opts.Fade, dur/2); fade > 0 {
+		parts = append(parts,
+			"afade=t=in:st=0:d="+fmtSeconds(fade),
+			"afade=t=out:st="+fmtSeconds(dur-fade)+":d="+fmtSeconds(fade),
+		)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseLoudnorm reads the JSON block loudnorm prints at the end of ffmpeg's
+// log. Values are strings in that block, e.g. "input_i" : "-23.54".
+func parseLoudnorm(out []byte) (loudness, error) {
+	end := bytes.LastIndexByte(out, '}')
+	if end < 0 {
+		return loudness{}, errors.New("no loudnorm stats in ffmpeg output")
+	}
+	start := bytes.LastIndexByte(out[:end], '{')
+	if start < 0 {
+		return loudness{}, errors.New("no loudnorm stats in ffmpeg output")
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(out[start:end+1], &raw); err != nil {
+		return loudness{}, fmt.Errorf("parse loudnorm stats: %w", err)
+	}
+	var (
+		l        loudness
+		parseErr error
+	)
+	get := func(key string) float64 {
+		v, err := strconv.ParseFloat(strings.TrimSpace(raw[key]), 64)
+		if err != nil && parseErr == nil {
+			parseErr = fmt.Errorf("parse loudnorm %s %q: %w", key, raw[key], err)
+		}
+		return v
+	}
+	l.inputI = get("input_i")
+	l.inputTP = get("input_tp")
+	l.inputLRA = get("input_lra")
+	l.inputThresh = get("input_thresh")
+	l.outputI = get("output_i")
+	l.targetOffset = get("target_offset")
+	return l, parseErr
+}
+
+func fmtFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,123 @@
+package ffmpeg
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+const loudnormLog = `[Parsed_loudnorm_0 @ 0x600000c3c000]
+{
+	"input_i" : "-23.54",
+	"input_tp" : "-4.10",
+	"input_lra" : "6.30",
+	"input_thresh" : "-34.02",
+	"output_i" : "-14.02",
+	"output_tp" : "-1.50",
+	"output_lra" : "5.10",
+	"output_thresh" : "-24.40",
+	"normalization_type" : "linear",
+	"target_offset" : "0.02"
+}
+`
+
+func TestParseLoudnorm(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    loudness
+		wantErr string
+	}{
+		{
+			name: "stats after other log lines",
+			out:  "size=N/A time=00:00:30.00 {not json}\n" + loudnormLog,
+			want: loudness{inputI: -23.54, inputTP: -4.1, inputLRA: 6.3, inputThresh: -34.02, outputI: -14.02, targetOffset: 0.02},
+		},
+		{name: "missing block", out: "size=N/A time=00:00:30.00 bitrate=N/A\n", wantErr: "no loudnorm stats"},
+		{name: "unbalanced block", out: "only a closing brace }", wantErr: "no loudnorm stats"},
+		{name: "not json", out: "{ input_i: -23 }", wantErr: "parse loudnorm stats"},
+		{
+			name:    "bad number",
+			out:     strings.Replace(loudnormLog, `"-4.10"`, `"loud"`, 1),
+			wantErr: `parse loudnorm input_tp "loud"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLoudnorm([]byte(tt.out))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseLoudnorm() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLoudnorm(): %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("parseLoudnorm() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLoudnorm_Silence(t *testing.T) {
+	out := strings.NewReplacer(`"-23.54"`, `"-inf"`, `"-34.02"`, `"-inf"`).Replace(loudnormLog)
+	got, err := parseLoudnorm([]byte(out))
+	if err != nil {
+		t.Fatalf("parseLoudnorm(): %v", err)
+	}
+	if !math.IsInf(got.inputI, -1) || got.normalizable() {
+		t.Fatalf("expected silence to measure -inf and not be normalizable, got %+v", got)
+	}
+}
+
+func TestAudioFilter(t *testing.T) {
+	m := &loudness{inputI: -23.54, inputTP: -4.1, inputLRA: 6.3, inputThresh: -34.02, targetOffset: 0.02}
+	loudnorm := "loudnorm=I=-14:TP=-1.5:LRA=11:measured_I=-23.54:measured_TP=-4.1:measured_LRA=6.3:" +
+		"measured_thresh=-34.02:offset=0.02:linear=true:print_format=json,aresample=48000"
+	tests := []struct {
+		name string
+		opts types.AudioOptions
+		dur  time.Duration
+		m    *loudness
+		want string
+	}{
+		{name: "nothing", opts: types.AudioOptions{}, dur: 30 * time.Second},
+		{
+			name: "no measurement skips loudnorm",
+			opts: types.AudioOptions{LoudnessLUFS: -14},
+			dur:  30 * time.Second,
+		},
+		{name: "loudnorm", opts: types.AudioOptions{LoudnessLUFS: -14}, dur: 30 * time.Second, m: m, want: loudnorm},
+		{
+			name: "fades",
+			opts: types.AudioOptions{Fade: 500 * time.Millisecond},
+			dur:  30 * time.Second,
+			want: "afade=t=in:st=0:d=0.500,afade=t=out:st=29.500:d=0.500",
+		},
+		{
+			name: "fade clamped to half the clip",
+			opts: types.AudioOptions{Fade: 3 * time.Second},
+			dur:  4 * time.Second,
+			want: "afade=t=in:st=0:d=2.000,afade=t=out:st=2.000:d=2.000",
+		},
+		{
+			name: "loudnorm before fades",
+			opts: types.AudioOptions{LoudnessLUFS: -14, Fade: time.Second},
+			dur:  10 * time.Second,
+			m:    m,
+			want: loudnorm + ",afade=t=in:st=0:d=1.000,afade=t=out:st=9.000:d=1.000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := audioFilter(tt.opts, tt.dur, tt.m); got != tt.want {
+				t.Fatalf("audioFilter() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Probe reads the input's container and streams with ffprobe. The error of an
+// unreadable file carries ffprobe's own message, e.g. "moov atom not found"
+// for a truncated MP4.
+func (a *Adapter) Probe(ctx context.Context, in string) (types.MediaInfo, error) {
+	cmd := exec.CommandContext(ctx, a.ffprobe,
+		"-v", "error",
+		"-show_format",
+		"-show_streams",
+		"-of", "json",
+		in,
+	)
+	b, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return types.MediaInfo{}, fmt.Errorf("ffprobe: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return types.MediaInfo{}, fmt.Errorf("ffprobe: %w", err)
+	}
+	return parseProbe(b)
+}
+
+type probeOutput struct {
+	Streams []struct {
+		Index        int               `json:"index"`
+		CodecType    string            `json:"codec_type"`
+		CodecName    string            `json:"codec_name"`
+		Duration     string            `json:"duration"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		RFrameRate   string            `json:"r_frame_rate"`
+		AvgFrameRate string            `json:"avg_frame_rate"`
+		Channels     int               `json:"channels"`
+		SampleRate   string            `json:"sample_rate"`
+		Tags         map[string]string `json:"tags"`
+		Disposition  struct {
+			Default     int `json:"default"`
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+		SideDataList []struct {
+			Rotation *float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+func parseProbe(b []byte) (types.MediaInfo, error) {
+	var raw probeOutput
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return types.MediaInfo{}, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+	info := types.MediaInfo{
+		Format:      raw.Format.FormatName,
+		DurationSec: parseFloat(raw.Format.Duration),
+		Title:       tag(raw.Format.Tags, "title"),
+	}
+	for _, s := range raw.Streams {
+		st := types.MediaStream{
+			Index:       s.Index,
+			Type:        s.CodecType,
+			Codec:       s.CodecName,
+			DurationSec: parseFloat(s.Duration),
+			Default:     s.Disposition.Default != 0,
+			Language:    tag(s.Tags, "language"),
+		}
+		switch s.CodecType {
+		case "video":
+			st.Width, st.Height = s.Width, s.Height
+			st.AttachedPic = s.Disposition.AttachedPic != 0
+			st.Rotation = rotation(tag(s.Tags, "rotate"))
+			for _, sd := range s.SideDataList {
+				if sd.Rotation != nil {
+					st.Rotation = normRotation(int(math.Round(*sd.Rotation)))
+				}
+			}
+			nominal, avg := parseRate(s.RFrameRate), parseRate(s.AvgFrameRate)
+			st.FPS = avg
+			if st.FPS == 0 {
+				st.FPS = nominal
+			}
+			st.VFR = nominal > 0 && avg > 0 && math.Abs(nominal-avg)/nominal > 0.01
+		case "audio":
+			st.Channels = s.Channels
+			st.SampleRate, _ = strconv.Atoi(s.SampleRate)
+		}
+		info.Streams = append(info.Streams, st)
+	}
+	if info.DurationSec == 0 {
+		// Some containers (e.g. raw streams) only carry stream durations.
+		for _, s := range info.Streams {
+			info.DurationSec = max(info.DurationSec, s.DurationSec)
+		}
+	}
+	return info, nil
+}
+
+// parseRate reads an ffprobe rate such as "30000/1001"; "0/0" is 0.
+func parseRate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		return parseFloat(s)
+	}
+	n, d := parseFloat(num), parseFloat(den)
+	if d == 0 {
+		return 0
+	}
+	return math.Round(n/d*1000) / 1000
+}
+
+func rotation(s string) int {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return normRotation(v)
+}
+
+// normRotation maps a rotation in degrees onto 0..359.
+func normRotation(v int) int {
+	return ((v % 360) + 360) % 360
+}
+
+func parseFloat(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+func tag(tags map[string]string, key string) string {
+	for k, v := range tags {
+		if strings.EqualFold(k, key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// probeJSON is trimmed `ffprobe -show_format -show_streams -of json` output
+// of a phone recording with a second audio track and embedded cover art.
+const probeJSON = `{
+  "streams": [
+    {
+      "index": 0, "codec_name": "h264", "codec_type": "video", "width": 1920, "height": 1080,
+      "r_frame_rate": "30/1", "avg_frame_rate": "2997/100", "duration": "61.000000",
+      "disposition": {"default": 1, "attached_pic": 0},
+      "tags": {"language": "und"},
+      "side_data_list": [{"side_data_type": "Display Matrix", "rotation": -90}]
+    },
+    {
+      "index": 1, "codec_name": "aac", "codec_type": "audio", "channels": 2, "sample_rate": "48000",
+      "duration": "61.020000", "disposition": {"default": 1}, "tags": {"LANGUAGE": " eng "}
+    },
+    {
+      "index": 2, "codec_name": "aac", "codec_type": "audio", "channels": 1, "sample_rate": "44100",
+      "disposition": {"default": 0}, "tags": {"language": "spa"}
+    },
+    {
+      "index": 3, "codec_name": "mjpeg", "codec_type": "video", "width": 600, "height": 600,
+      "r_frame_rate": "90000/1", "avg_frame_rate": "0/0",
+      "disposition": {"default": 0, "attached_pic": 1}
+    }
+  ],
+  "format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "61.024000", "tags": {"title": " Episode 12 "}}
+}`
+
+func TestParseProbe(t *testing.T) {
+	got, err := parseProbe([]byte(probeJSON))
+	if err != nil {
+		t.Fatalf("parseProbe(): %v", err)
+	}
+	want := types.MediaInfo{
+		Format:      "mov,mp4,m4a,3gp,3g2,mj2",
+		DurationSec: 61.024,
+		Title:       "Episode 12",
+		Streams: []types.MediaStream{
+			{
+				Index: 0, Type: "video", Codec: "h264", DurationSec: 61, Default: true, Language: "und",
+				Width: 1920, Height: 1080, Rotation: 270, FPS: 29.97, VFR: false,
+			},
+			{Index: 1, Type: "audio", Codec: "aac", DurationSec: 61.02, Default: true, Language: "eng", Channels: 2, SampleRate: 48000},
+			{Index: 2, Type: "audio", Codec: "aac", Language: "spa", Channels: 1, SampleRate: 44100},
+			{Index: 3, Type: "video", Codec: "mjpeg", Width: 600, Height: 600, FPS: 90000, AttachedPic: true},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseProbe() =\n%+v\nwant\n%+v", got, want)
+	}
+	if v, ok := got.Video(); !ok || v.Index != 0 {
+		t.Fatalf("expected cover art to be skipped as video, got %+v", v)
+	}
+}
+
+func TestParseProbe_VFRAndStreamDuration(t *testing.T) {
+	got, err := parseProbe([]byte(`{"streams":[
+		{"index":0,"codec_type":"video","r_frame_rate":"60/1","avg_frame_rate":"24000/1001","duration":"12.5","tags":{"rotate":"-90"}},
+		{"index":1,"codec_type":"audio","duration":"12.75"}
+	],"format":{"format_name":"h264"}}`))
+	if err != nil {
+		t.Fatalf("parseProbe(): %v", err)
+	}
+	v := got.Streams[0]
+	if !v.VFR || v.FPS != 23.976 || v.Rotation != 270 {
+		t.Fatalf("expected a rotated VFR stream at 23.976 fps, got %+v", v)
+	}
+	if got.DurationSec != 12.75 {
+		t.Fatalf("expected the longest stream duration without a format one, got %v", got.DurationSec)
+	}
+
+	if _, err := parseProbe([]byte("moov atom not found")); err == nil {
+		t.Fatalf("expected an error for output that is not JSON")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"30/1", 30},
+		{"30000/1001", 29.97},
+		{"0/0", 0},
+		{"25", 25},
+		{"", 0},
+		{"x/y", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRate(tt.in); got != tt.want {
+			t.Fatalf("parseRate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"90", 90},
+		{"-90", 270},
+		{"450", 90},
+		{"", 0},
+		{"sideways", 0},
+	}
+	for _, tt := range tests {
+		if got := rotation(tt.in); got != tt.want {
+			t.Fatalf("rotation(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
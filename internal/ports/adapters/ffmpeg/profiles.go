@@ -0,0 +1,91 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Output formats of rendered clips. The format name is also the file
+// extension.
+const (
+	FormatMP4  = "mp4"
+	FormatWebM = "webm"
+	FormatMOV  = "mov"
+)
+
+// profile is how clips of an output format are encoded.
+type profile struct {
+	video []string
+	audio []string
+	// mux holds muxer options.
+	mux []string
+	// capRate caps the video bitrate at kbps; nil when the codec cannot.
+	capRate func(kbps int) []string
+}
+
+// profiles is the codec table of the output formats.
+var profiles = map[string]profile{
+	// h264/aac plays everywhere; faststart puts the index first for uploads.
+	FormatMP4: {
+		video: []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "18", "-pix_fmt", "yuv420p"},
+		audio: []string{"-c:a", "aac", "-b:a", "192k"},
+		mux:   []string{"-movflags", "+faststart"},
+		capRate: func(kbps int) []string {
+			return []string{"-maxrate", kbit(kbps), "-bufsize", kbit(2 * kbps)}
+		},
+	},
+	// vp9/opus for the web; constant quality with -b:v 0.
+	FormatWebM: {
+		video: []string{
+			"-c:v", "libvpx-vp9", "-crf", "31", "-b:v", "0",
+			"-deadline", "good", "-cpu-used", "4", "-row-mt", "1", "-pix_fmt", "yuv420p",
+		},
+		audio: []string{"-c:a", "libopus", "-b:a", "160k"},
+		// A bitrate with -crf is constrained quality: the bitrate is the cap.
+		capRate: func(kbps int) []string { return []string{"-b:v", kbit(kbps)} },
+	},
+	// ProRes 422 HQ with PCM audio, an intermediate for further editing:
+	// large files, but no visible loss when re-encoded.
+	FormatMOV: {
+		video: []string{"-c:v", "prores_ks", "-profile:v", "3", "-vendor", "apl0", "-pix_fmt", "yuv422p10le"},
+		audio: []string{"-c:a", "pcm_s16le"},
+	},
+}
+
+// Formats lists the output formats, the default first.
+func Formats() []string { return []string{FormatMP4, FormatWebM, FormatMOV} }
+
+// CheckFormat reports whether name is an output format; empty means mp4.
+func CheckFormat(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := profiles[name]; !ok {
+		return fmt.Errorf("unknown output format %q (want %s)", name, strings.Join(Formats(), ", "))
+	}
+	return nil
+}
+
+// formatProfile returns the profile of an output format; empty means mp4.
+func formatProfile(format string) profile {
+	p, ok := profiles[format]
+	if !ok {
+		return profiles[FormatMP4]
+	}
+	return p
+}
+
+// encodeArgs are the codec and muxer arguments of an output format, with the
+// video bitrate capped at maxKbps when set and the codec supports it.
+func encodeArgs(format string, maxKbps int) []string {
+	p := formatProfile(format)
+	args := append([]string(nil), p.video...)
+	if maxKbps > 0 && p.capRate != nil {
+		args = append(args, p.capRate(maxKbps)...)
+	}
+	args = append(args, p.audio...)
+	return append(args, p.mux...)
+}
+
+func kbit(kbps int) string { return strconv.Itoa(kbps) + "k" }
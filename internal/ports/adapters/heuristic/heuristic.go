@@ -0,0 +1,214 @@
+// Package heuristic ranks highlight candidates without a language model. It
+// picks clips by the deterministic info/hook scores and derives titles,
+// captions and tags from the transcript text, so hlcut can run fully offline.
+package heuristic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+const (
+	maxTitleWords   = 8
+	maxCaptionRunes = 150
+	maxTags         = 5
+)
+
+type Adapter struct{}
+
+func New() *Adapter {
+	return &Adapter{}
+}
+
+func (a *Adapter) Refine(
+	_ context.Context,
+	tr types.Transcript,
+	cands []types.Candidate,
+	clipsN int,
+	d types.ClipDurations,
+) ([]types.ClipSpec, error) {
+	if clipsN <= 0 || len(cands) == 0 {
+		return nil, nil
+	}
+	minClip, maxClip := d.Min, d.Max
+	if maxClip <= 0 || maxClip < minClip {
+		return nil, nil
+	}
+
+	picks := highlights.SelectBest(cands, clipsN, minClip, maxClip, highlights.CollectTiming(tr))
+	out := make([]types.ClipSpec, 0, len(picks))
+	for _, p := range picks {
+		text := clipText(tr, p.Start, p.End)
+		if text == "" {
+			text = strings.TrimSpace(p.Candidate.Text)
+		}
+		sentences := splitSentences(text)
+		out = append(out, types.ClipSpec{
+			Start:   p.Start,
+			End:     p.End,
+			Title:   buildTitle(sentences),
+			Caption: buildCaption(sentences),
+			Tags:    buildTags(text),
+			Reason: fmt.Sprintf(
+				"heuristic: info %.1f, hook %.1f",
+				p.Candidate.InfoScore,
+				p.Candidate.HookScore,
+			),
+		})
+	}
+	return out, nil
+}
+
+// clipText joins the transcript segments that overlap [st, en).
+func clipText(tr types.Transcript, st, en time.Duration) string {
+	var parts []string
+	for _, s := range tr.Segments {
+		if types.Seconds(s.End) <= st || types.Seconds(s.Start) >= en {
+			continue
+		}
+		if t := strings.TrimSpace(s.Text); t != "" {
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func splitSentences(text string) []string {
+	var out []string
+	var b strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			out = append(out, s)
+		}
+		b.Reset()
+	}
+	for _, r := range text {
+		b.WriteRune(r)
+		if r == '.' || r == '!' || r == '?' {
+			flush()
+		}
+	}
+	flush()
+	return out
+}
+
+// buildTitle uses the sentence with the strongest hook/info score, cut to a
+// few words.
+func buildTitle(sentences []string) string {
+	best, bestScore := "", -1.0
+	for _, s := range sentences {
+		if len(strings.Fields(s)) < 3 && best != "" {
+			continue
+		}
+		info, hook := highlights.Score(s)
+		if score := info + hook; score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	words := strings.Fields(best)
+	if len(words) == 0 {
+		return "Highlight"
+	}
+	cut := len(words) > maxTitleWords
+	if cut {
+		words = words[:maxTitleWords]
+	}
+	title := strings.TrimRightFunc(strings.Join(words, " "), func(r rune) bool {
+		return unicode.IsPunct(r) && r != '?' && r != '!'
+	})
+	if cut {
+		title += "…"
+	}
+	return capitalize(title)
+}
+
+// buildCaption keeps whole leading sentences while they fit the caption
+// length; a single long sentence is truncated on a word boundary.
+func buildCaption(sentences []string) string {
+	var b strings.Builder
+	for _, s := range sentences {
+		n := len([]rune(b.String()))
+		if n > 0 && n+1+len([]rune(s)) > maxCaptionRunes {
+			break
+		}
+		if n > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s)
+	}
+	caption := b.String()
+	if r := []rune(caption); len(r) > maxCaptionRunes {
+		caption = string(r[:maxCaptionRunes])
+		if i := strings.LastIndexByte(caption, ' '); i > 0 {
+			caption = caption[:i]
+		}
+		caption = strings.TrimRightFunc(caption, unicode.IsPunct) + "…"
+	}
+	if caption == "" {
+		return "Highlight"
+	}
+	return caption
+}
+
+// buildTags returns the most frequent content words of the clip.
+func buildTags(text string) []string {
+	counts := map[string]int{}
+	first := map[string]int{}
+	for i, w := range strings.Fields(strings.ToLower(text)) {
+		w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if len([]rune(w)) < 4 || stopwords[w] {
+			continue
+		}
+		if _, ok := first[w]; !ok {
+			first[w] = i
+		}
+		counts[w]++
+	}
+	words := make([]string, 0, len(counts))
+	for w := range counts {
+		words = append(words, w)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return first[words[i]] < first[words[j]]
+	})
+	if len(words) > maxTags {
+		words = words[:maxTags]
+	}
+	return words
+}
+
+func capitalize(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+var stopwords = map[string]bool{
+	"about": true, "after": true, "again": true, "also": true, "because": true,
+	"been": true, "before": true, "being": true, "could": true, "does": true,
+	"doing": true, "down": true, "each": true, "even": true, "every": true,
+	"from": true, "going": true, "gonna": true, "have": true, "here": true,
+	"into": true, "just": true, "know": true, "like": true, "make": true,
+	"more": true, "most": true, "much": true, "need": true, "only": true,
+	"other": true, "really": true, "right": true, "said": true, "same": true,
+	"should": true, "some": true, "something": true, "that": true, "that's": true,
+	"their": true, "them": true, "then": true, "there": true, "there's": true,
+	"these": true, "they": true, "thing": true, "things": true, "think": true,
+	"this": true, "those": true, "through": true, "very": true, "want": true,
+	"well": true, "were": true, "what": true, "when": true, "where": true,
+	"which": true, "while": true, "will": true, "with": true, "would": true,
+	"yeah": true, "your": true, "you're": true, "it's": true, "don't": true,
+}
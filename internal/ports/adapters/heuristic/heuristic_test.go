@@ -0,0 +1,60 @@
+package heuristic
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func TestRefine_PicksBestNonOverlappingClipsWithMetadata(t *testing.T) {
+	tr := types.Transcript{Segments: []types.Segment{
+		{Start: 0, End: 25, Text: "Welcome back to the channel everyone."},
+		{Start: 30, End: 55, Text: "Here is why caching matters. Caching saves money and caching saves time."},
+		{Start: 60, End: 85, Text: "Thanks for watching."},
+	}}
+	cands := []types.Candidate{
+		{Start: 0, End: 25 * time.Second, Text: "Welcome back", InfoScore: 1},
+		{Start: 30 * time.Second, End: 55 * time.Second, Text: "Here is why caching matters", InfoScore: 2, HookScore: 3},
+		{Start: 35 * time.Second, End: 58 * time.Second, Text: "overlap", InfoScore: 2, HookScore: 2},
+	}
+
+	out, err := New().Refine(context.Background(), tr, cands, 3, highlights.DefaultDurations())
+	if err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected 2 clips, got %d: %+v", len(out), out)
+	}
+	got := out[0]
+	if got.Start != 30*time.Second {
+		t.Fatalf("expected best clip first at 30s, got %v", got.Start)
+	}
+	if got.Title != "Here is why caching matters" {
+		t.Fatalf("unexpected title %q", got.Title)
+	}
+	if !strings.HasPrefix(got.Caption, "Here is why caching matters.") {
+		t.Fatalf("unexpected caption %q", got.Caption)
+	}
+	if want := []string{"caching", "saves", "matters", "money", "time"}; !reflect.DeepEqual(got.Tags, want) {
+		t.Fatalf("tags = %v, want %v", got.Tags, want)
+	}
+	if !strings.HasPrefix(got.Reason, "heuristic:") {
+		t.Fatalf("unexpected reason %q", got.Reason)
+	}
+}
+
+func TestBuildCaption_TruncatesLongSentence(t *testing.T) {
+	long := strings.Repeat("word ", 60) + "end."
+	caption := buildCaption([]string{long})
+	if n := len([]rune(caption)); n > maxCaptionRunes+1 {
+		t.Fatalf("caption too long: %d runes", n)
+	}
+	if !strings.HasSuffix(caption, "…") {
+		t.Fatalf("expected ellipsis, got %q", caption)
+	}
+}
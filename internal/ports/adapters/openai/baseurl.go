@@ -0,0 +1,70 @@
+package openai
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const defaultBaseURL = "https://api.openai.com"
+
+// normalizeBaseURL trims the base URL to the server root; a trailing /v1 is
+// accepted because most local servers document their URL that way.
+func normalizeBaseURL(baseURL string) string {
+	baseURL = strings.TrimSpace(baseURL)
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	return strings.TrimSuffix(baseURL, "/v1")
+}
+
+// ValidateBaseURL checks OPENAI_BASE_URL. https is required unless
+// allowHTTPLoopback is set, which permits plain http to loopback hosts only
+// (llama.cpp, Ollama or vLLM on the same machine).
+func ValidateBaseURL(baseURL string, allowHTTPLoopback bool) error {
+	baseURL = normalizeBaseURL(baseURL)
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid OPENAI_BASE_URL: %w", err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("invalid OPENAI_BASE_URL %q: absolute URL with host is required", baseURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("invalid OPENAI_BASE_URL %q: userinfo is not allowed", baseURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid OPENAI_BASE_URL %q: query and fragment are not allowed", baseURL)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("invalid OPENAI_BASE_URL %q: host is required", baseURL)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return nil
+	case "http":
+		if !allowHTTPLoopback {
+			return fmt.Errorf("invalid OPENAI_BASE_URL %q: https is required (set OPENAI_ALLOW_HTTP_LOOPBACK=1 for a local server)", baseURL)
+		}
+		if !isLoopbackHost(host) {
+			return fmt.Errorf("invalid OPENAI_BASE_URL %q: plain http is only allowed for loopback hosts, got %q", baseURL, host)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid OPENAI_BASE_URL %q: https is required", baseURL)
+	}
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,55 @@
+// Package openai ranks clips with any server that speaks the OpenAI
+// /v1/chat/completions API: OpenAI itself or local servers such as llama.cpp,
+// Ollama and vLLM.
+package openai
+
+import (
+	"context"
+
+	"github.com/forPelevin/hlcut/internal/ports/adapters/chatcompletion"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+type Config struct {
+	BaseURL string
+	// APIKey may be empty for local servers without authentication.
+	APIKey string
+	Model  string
+	// AuthHeader carries the key; "Authorization" (default) sends it as a
+	// bearer token, any other header (e.g. "api-key") sends it verbatim.
+	AuthHeader string
+	// Format selects the structured-output mode; auto detects what the
+	// server supports.
+	Format   chatcompletion.ResponseFormat
+	Retry    chatcompletion.RetryPolicy
+	Chunking chatcompletion.Chunking
+	Logf     func(format string, args ...any)
+}
+
+type Adapter struct {
+	c *chatcompletion.Client
+}
+
+func New(cfg Config) *Adapter {
+	return &Adapter{c: chatcompletion.New(chatcompletion.Config{
+		Provider:   "openai",
+		URL:        normalizeBaseURL(cfg.BaseURL) + "/v1/chat/completions",
+		Models:     []string{cfg.Model},
+		APIKey:     cfg.APIKey,
+		AuthHeader: cfg.AuthHeader,
+		Format:     cfg.Format,
+		Retry:      cfg.Retry,
+		Chunking:   cfg.Chunking,
+		Logf:       cfg.Logf,
+	})}
+}
+
+func (a *Adapter) Refine(
+	ctx context.Context,
+	tr types.Transcript,
+	cands []types.Candidate,
+	clipsN int,
+	d types.ClipDurations,
+) ([]types.ClipSpec, error) {
+	return a.c.Refine(ctx, tr, cands, clipsN, d)
+}
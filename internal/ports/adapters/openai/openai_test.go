@@ -0,0 +1,125 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/highlights"
+	"github.com/forPelevin/hlcut/internal/ports/adapters/chatcompletion"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// stubServer answers chat completions like a local server that only
+// supports json_object output.
+type stubServer struct {
+	mu      sync.Mutex
+	formats []string
+	auth    []string
+	paths   []string
+}
+
+func (s *stubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ResponseFormat *struct {
+			Type string `json:"type"`
+		} `json:"response_format"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	format := ""
+	if req.ResponseFormat != nil {
+		format = req.ResponseFormat.Type
+	}
+
+	s.mu.Lock()
+	s.formats = append(s.formats, format)
+	s.auth = append(s.auth, r.Header.Get("Authorization")+"|"+r.Header.Get("api-key"))
+	s.paths = append(s.paths, r.URL.Path)
+	s.mu.Unlock()
+
+	if format == "json_schema" {
+		http.Error(w, `{"error":"response_format type json_schema is not supported"}`, http.StatusBadRequest)
+		return
+	}
+	content := `{"clips":[{"idx":0,"start_sec":0,"end_sec":30,"title":"Stub","caption":"From stub","tags":["a"],"reason":"r"}]}`
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"choices": []map[string]any{{"message": map[string]any{"content": content}}},
+	})
+}
+
+func TestRefine_DetectsJSONObjectSupport(t *testing.T) {
+	stub := &stubServer{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	if err := ValidateBaseURL(srv.URL, true); err != nil {
+		t.Fatalf("stub URL should be allowed with loopback opt-in: %v", err)
+	}
+	a := New(Config{BaseURL: srv.URL + "/v1", Model: "local", Format: chatcompletion.FormatAuto})
+	cands := []types.Candidate{{Start: 0, End: 30 * time.Second, Text: "hello", InfoScore: 1}}
+
+	for i := 0; i < 2; i++ {
+		clips, err := a.Refine(context.Background(), types.Transcript{}, cands, 1, highlights.DefaultDurations())
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if len(clips) != 1 || clips[0].Title != "Stub" {
+			t.Fatalf("call %d: unexpected clips %+v", i, clips)
+		}
+	}
+
+	want := []string{"json_schema", "json_object", "json_object"}
+	if len(stub.formats) != len(want) {
+		t.Fatalf("formats = %v, want %v", stub.formats, want)
+	}
+	for i := range want {
+		if stub.formats[i] != want[i] {
+			t.Fatalf("formats = %v, want %v", stub.formats, want)
+		}
+	}
+	if stub.paths[0] != "/v1/chat/completions" {
+		t.Fatalf("unexpected path %q", stub.paths[0])
+	}
+	if stub.auth[0] != "|" {
+		t.Fatalf("expected no auth header without key, got %q", stub.auth[0])
+	}
+}
+
+func TestRefine_CustomAuthHeader(t *testing.T) {
+	stub := &stubServer{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	a := New(Config{
+		BaseURL:    srv.URL,
+		APIKey:     "secret",
+		AuthHeader: "api-key",
+		Format:     chatcompletion.FormatJSONObject,
+	})
+	cands := []types.Candidate{{Start: 0, End: 30 * time.Second, Text: "hello"}}
+	if _, err := a.Refine(context.Background(), types.Transcript{}, cands, 1, highlights.DefaultDurations()); err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if len(stub.auth) != 1 || stub.auth[0] != "|secret" {
+		t.Fatalf("expected raw key in api-key header, got %v", stub.auth)
+	}
+}
+
+func TestRefine_FixedFormatDoesNotDowngrade(t *testing.T) {
+	stub := &stubServer{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	a := New(Config{BaseURL: srv.URL, Format: chatcompletion.FormatJSONSchema})
+	cands := []types.Candidate{{Start: 0, End: 30 * time.Second, Text: "hello"}}
+	if _, err := a.Refine(context.Background(), types.Transcript{}, cands, 1, highlights.DefaultDurations()); err == nil {
+		t.Fatalf("expected json_schema rejection to surface")
+	}
+	if len(stub.formats) != 1 {
+		t.Fatalf("expected a single request, got %v", stub.formats)
+	}
+}
@@ -0,0 +1,320 @@
+// Package transcriptfile imports existing transcripts (captions, whisper.cpp
+// or OpenAI-style JSON) in place of speech recognition. Cues without word
+// timing get approximate word timings, so subtitles, candidates and
+// tightening work as they do on whisper output.
+package transcriptfile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/forPelevin/hlcut/internal/ports/adapters/whispercpp"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Transcript file formats.
+const (
+	FormatSRT = "srt"
+	FormatVTT = "vtt"
+	// FormatJSON is whisper.cpp's -ojf output, OpenAI's verbose_json or an
+	// hlcut transcript; they are told apart by their keys.
+	FormatJSON = "json"
+	// FormatJSONL holds one segment object ({"start","end","text"}) per line.
+	FormatJSONL = "jsonl"
+)
+
+// Detect returns the format of a transcript file from its extension.
+func Detect(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".srt":
+		return FormatSRT, nil
+	case ".vtt":
+		return FormatVTT, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("unsupported transcript file %q (want .srt, .vtt, .json or .jsonl)", filepath.Base(path))
+	}
+}
+
+// Adapter is a ports.TranscriptSource reading a transcript file.
+type Adapter struct {
+	path string
+	// language fills in the transcript language when the file has none.
+	language string
+}
+
+func New(path, language string) *Adapter {
+	return &Adapter{path: path, language: language}
+}
+
+// Transcribe ignores the audio and loads the transcript file.
+func (a *Adapter) Transcribe(_ context.Context, _, _ string) (types.Transcript, error) {
+	tr, err := Load(a.path)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+	if tr.Language == "" {
+		tr.Language = a.language
+	}
+	return tr, nil
+}
+
+func (a *Adapter) Source() string { return a.path }
+
+// Load parses a transcript file; segments without word timing get
+// synthesized words.
+func Load(path string) (types.Transcript, error) {
+	format, err := Detect(path)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+	tr, err := Parse(format, b)
+	if err != nil {
+		return types.Transcript{}, fmt.Errorf("parse transcript %s: %w", path, err)
+	}
+	return tr, nil
+}
+
+// Parse parses a transcript in format.
+func Parse(format string, b []byte) (types.Transcript, error) {
+	b = bytes.TrimPrefix(b, []byte("\ufeff"))
+	var (
+		tr  types.Transcript
+		err error
+	)
+	switch format {
+	case FormatSRT, FormatVTT:
+		tr, err = parseCues(b)
+	case FormatJSON:
+		tr, err = parseJSON(b)
+	case FormatJSONL:
+		tr, err = parseJSONL(b)
+	default:
+		return types.Transcript{}, fmt.Errorf("unknown transcript format %q", format)
+	}
+	if err != nil {
+		return types.Transcript{}, err
+	}
+	if len(tr.Segments) == 0 {
+		return types.Transcript{}, errors.New("no timed text found")
+	}
+	sortSegments(tr.Segments)
+	for i, seg := range tr.Segments {
+		if len(seg.Words) == 0 {
+			tr.Segments[i].Words = synthesizeWords(seg)
+		}
+	}
+	return tr, nil
+}
+
+func sortSegments(segs []types.Segment) {
+	sort.SliceStable(segs, func(i, j int) bool { return segs[i].Start < segs[j].Start })
+}
+
+// reMarkup matches inline caption markup: HTML-like tags (<i>, <v Name>,
+// <00:00:01.000>) and SSA overrides ({\an8}).
+var reMarkup = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+
+// reVoice matches a WebVTT voice tag (<v Ann> or <v.loud Ann>), whose
+// name becomes the cue's speaker.
+var reVoice = regexp.MustCompile(`<v(?:\.[^ \t>]*)?[ \t]+([^>]+)>`)
+
+// parseCues parses SRT and WebVTT: blocks separated by blank lines, each
+// with a "start --> end" line followed by its text. Blocks without timing
+// (the WEBVTT header, NOTE, STYLE, REGION) are skipped.
+func parseCues(b []byte) (types.Transcript, error) {
+	var tr types.Transcript
+	text := strings.ReplaceAll(string(b), "\r\n", "\n")
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		for i, line := range lines {
+			from, to, ok := strings.Cut(line, "-->")
+			if !ok {
+				continue
+			}
+			start, err := parseTimestamp(from)
+			if err != nil {
+				return types.Transcript{}, fmt.Errorf("cue %q: %w", line, err)
+			}
+			// WebVTT cue settings follow the end time.
+			var end float64
+			if f := strings.Fields(to); len(f) > 0 {
+				end, err = parseTimestamp(f[0])
+			} else {
+				err = errors.New("missing end time")
+			}
+			if err != nil {
+				return types.Transcript{}, fmt.Errorf("cue %q: %w", line, err)
+			}
+			raw := strings.Join(lines[i+1:], " ")
+			cue := strings.Join(strings.Fields(reMarkup.ReplaceAllString(raw, "")), " ")
+			if cue != "" && end > start {
+				seg := types.Segment{Start: start, End: end, Text: cue}
+				if m := reVoice.FindStringSubmatch(raw); m != nil {
+					seg.Speaker = strings.TrimSpace(m[1])
+				}
+				tr.Segments = append(tr.Segments, seg)
+			}
+			break
+		}
+	}
+	return tr, nil
+}
+
+// parseTimestamp parses HH:MM:SS,mmm (SRT) and [HH:]MM:SS.mmm (WebVTT).
+func parseTimestamp(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	var sec float64
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		sec = sec*60 + v
+	}
+	return sec, nil
+}
+
+// jsonSegment is a segment of OpenAI's verbose_json, an hlcut transcript or
+// a JSONL line.
+type jsonSegment struct {
+	Start   float64      `json:"start"`
+	End     float64      `json:"end"`
+	Text    string       `json:"text"`
+	Words   []types.Word `json:"words"`
+	Speaker string       `json:"speaker"`
+}
+
+func (s jsonSegment) segment() types.Segment {
+	seg := types.Segment{Start: s.Start, End: s.End, Text: strings.TrimSpace(s.Text), Speaker: strings.TrimSpace(s.Speaker)}
+	for _, w := range s.Words {
+		w.Word = strings.TrimSpace(w.Word)
+		if w.Word != "" {
+			seg.Words = append(seg.Words, w)
+		}
+	}
+	return seg
+}
+
+// parseJSON parses whisper.cpp JSON ("transcription") or a "segments"
+// document: OpenAI's verbose_json, whose word timings come as a separate
+// top-level list, or an hlcut transcript with words per segment.
+func parseJSON(b []byte) (types.Transcript, error) {
+	var doc struct {
+		Transcription json.RawMessage `json:"transcription"`
+		Language      string          `json:"language"`
+		Translated    bool            `json:"translated"`
+		Segments      []jsonSegment   `json:"segments"`
+		Words         []types.Word    `json:"words"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return types.Transcript{}, err
+	}
+	if doc.Transcription != nil {
+		return whispercpp.ParseJSON(b)
+	}
+	if doc.Segments == nil {
+		return types.Transcript{}, errors.New(`want a "transcription" (whisper.cpp) or "segments" (verbose_json) list`)
+	}
+	tr := types.Transcript{Language: languageCode(doc.Language), Translated: doc.Translated}
+	for _, s := range doc.Segments {
+		tr.Segments = append(tr.Segments, s.segment())
+	}
+	sortSegments(tr.Segments)
+	assignWords(tr.Segments, doc.Words)
+	return tr, nil
+}
+
+func parseJSONL(b []byte) (types.Transcript, error) {
+	var tr types.Transcript
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var s jsonSegment
+		if err := json.Unmarshal(line, &s); err != nil {
+			return types.Transcript{}, fmt.Errorf("line %d: %w", n, err)
+		}
+		tr.Segments = append(tr.Segments, s.segment())
+	}
+	return tr, sc.Err()
+}
+
+// assignWords adds top-level words to the segments they start in; a word
+// starting between segments goes to the one before it.
+func assignWords(segs []types.Segment, words []types.Word) {
+	for _, w := range words {
+		w.Word = strings.TrimSpace(w.Word)
+		if w.Word == "" {
+			continue
+		}
+		i := sort.Search(len(segs), func(i int) bool { return segs[i].Start > w.Start }) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i < len(segs) {
+			segs[i].Words = append(segs[i].Words, w)
+		}
+	}
+}
+
+// reLanguage matches a whisper language code; OpenAI reports language
+// names ("english"), which are dropped.
+var reLanguage = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+func languageCode(s string) string {
+	if reLanguage.MatchString(s) {
+		return s
+	}
+	return ""
+}
+
+// synthesizeWords spreads a segment's duration over its words in proportion
+// to their length, so longer words take longer to say.
+func synthesizeWords(seg types.Segment) []types.Word {
+	fields := strings.Fields(seg.Text)
+	dur := seg.End - seg.Start
+	if len(fields) == 0 || dur <= 0 {
+		return nil
+	}
+	total := 0
+	for _, f := range fields {
+		total += utf8.RuneCountInString(f)
+	}
+	words := make([]types.Word, len(fields))
+	at := seg.Start
+	for i, f := range fields {
+		end := at + dur*float64(utf8.RuneCountInString(f))/float64(total)
+		if i == len(fields)-1 {
+			end = seg.End
+		}
+		words[i] = types.Word{Start: at, End: end, Word: f}
+		at = end
+	}
+	return words
+}
@@ -0,0 +1,161 @@
+package transcriptfile
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name   string
+		format string
+		in     string
+		want   []types.Segment
+		lang   string
+	}{
+		{
+			name:   "srt",
+			format: FormatSRT,
+			in: "1\r\n00:00:01,000 --> 00:00:03,000\r\n<i>Hello</i> there,\r\nfriend\r\n\r\n" +
+				"2\r\n00:00:04,500 --> 00:00:05,500\r\n{\\an8}Bye\r\n",
+			want: []types.Segment{
+				{Start: 1, End: 3, Text: "Hello there, friend"},
+				{Start: 4.5, End: 5.5, Text: "Bye"},
+			},
+		},
+		{
+			name:   "vtt",
+			format: FormatVTT,
+			in: "WEBVTT\n\nNOTE made by hand\n\nintro\n00:01.000 --> 00:02.000 align:start\n<v Ann>Hi all\n\n" +
+				"01:00:00.000 --> 01:00:01.000\nLater\n",
+			want: []types.Segment{
+				{Start: 1, End: 2, Text: "Hi all", Speaker: "Ann"},
+				{Start: 3600, End: 3601, Text: "Later"},
+			},
+		},
+		{
+			name:   "whisper.cpp json",
+			format: FormatJSON,
+			in: `{"result":{"language":"de"},"transcription":[{"offsets":{"from":0,"to":1000},"text":" Hallo Welt",` +
+				`"tokens":[{"text":" Hallo","offsets":{"from":0,"to":400}},{"text":" Welt","offsets":{"from":400,"to":1000}}]}]}`,
+			want: []types.Segment{{Start: 0, End: 1, Text: "Hallo Welt", Words: []types.Word{
+				{Start: 0, End: 0.4, Word: "Hallo"},
+				{Start: 0.4, End: 1, Word: "Welt"},
+			}}},
+			lang: "de",
+		},
+		{
+			name:   "verbose json",
+			format: FormatJSON,
+			in: `{"language":"english","segments":[{"id":1,"start":2,"end":3,"text":" two"},{"id":0,"start":0,"end":2,"text":" one"}],` +
+				`"words":[{"word":"one","start":0.1,"end":0.9},{"word":"two","start":2.1,"end":2.8}]}`,
+			want: []types.Segment{
+				{Start: 0, End: 2, Text: "one", Words: []types.Word{{Start: 0.1, End: 0.9, Word: "one"}}},
+				{Start: 2, End: 3, Text: "two", Words: []types.Word{{Start: 2.1, End: 2.8, Word: "two"}}},
+			},
+		},
+		{
+			name:   "jsonl",
+			format: FormatJSONL,
+			in:     "{\"start\":0,\"end\":1,\"text\":\"a\",\"speaker\":\"S1\"}\n\n{\"start\":1,\"end\":2,\"text\":\"b\"}\n",
+			want: []types.Segment{
+				{Start: 0, End: 1, Text: "a", Speaker: "S1"},
+				{Start: 1, End: 2, Text: "b"},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tr, err := Parse(tc.format, []byte(tc.in))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if tr.Language != tc.lang {
+				t.Fatalf("language = %q, want %q", tr.Language, tc.lang)
+			}
+			if len(tr.Segments) != len(tc.want) {
+				t.Fatalf("got %d segments, want %d: %+v", len(tr.Segments), len(tc.want), tr.Segments)
+			}
+			for i, want := range tc.want {
+				got := tr.Segments[i]
+				if got.Start != want.Start || got.End != want.End || got.Text != want.Text || got.Speaker != want.Speaker {
+					t.Fatalf("segment %d = %+v, want %+v", i, got, want)
+				}
+				if want.Words != nil && !sameWords(got.Words, want.Words) {
+					t.Fatalf("segment %d words = %+v, want %+v", i, got.Words, want.Words)
+				}
+				if len(got.Words) == 0 {
+					t.Fatalf("segment %d has no words", i)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_SynthesizesWordTimings(t *testing.T) {
+	tr, err := Parse(FormatSRT, []byte("1\n00:00:10,000 --> 00:00:13,000\nI said hello\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// 1 + 4 + 5 runes share 3 seconds.
+	want := []types.Word{
+		{Start: 10, End: 10.3, Word: "I"},
+		{Start: 10.3, End: 11.5, Word: "said"},
+		{Start: 11.5, End: 13, Word: "hello"},
+	}
+	if got := tr.Segments[0].Words; !sameWords(got, want) {
+		t.Fatalf("words = %+v, want %+v", got, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]struct{ format, in string }{
+		"no cues":         {FormatSRT, "just text\n"},
+		"bad timestamp":   {FormatSRT, "1\n00:00:xx,000 --> 00:00:01,000\nhi\n"},
+		"unknown json":    {FormatJSON, `{"text":"hi"}`},
+		"bad jsonl line":  {FormatJSONL, "{\"start\":0,\"end\":1,\"text\":\"a\"}\nnope\n"},
+		"unknown format":  {"txt", "hi"},
+		"empty verbose":   {FormatJSON, `{"segments":[]}`},
+		"bad json syntax": {FormatJSON, `{`},
+	}
+	for name, tc := range cases {
+		if _, err := Parse(tc.format, []byte(tc.in)); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestAdapter_FillsLanguage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captions.vtt")
+	if err := os.WriteFile(path, []byte("WEBVTT\n\n00:00.000 --> 00:01.000\nHola\n"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	tr, err := New(path, "es").Transcribe(t.Context(), "", "")
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+	if tr.Language != "es" || len(tr.Segments) != 1 {
+		t.Fatalf("unexpected transcript %+v", tr)
+	}
+	if _, err := Detect("captions.txt"); err == nil {
+		t.Fatalf("expected .txt to be rejected")
+	}
+}
+
+func sameWords(got, want []types.Word) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i].Word != want[i].Word ||
+			math.Abs(got[i].Start-want[i].Start) > 1e-9 ||
+			math.Abs(got[i].End-want[i].End) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
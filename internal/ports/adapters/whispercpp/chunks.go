@@ -0,0 +1,332 @@
+package whispercpp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// Chunking splits the audio at silences into chunks of about Length,
+// transcribed by parallel whisper processes and stitched back on the
+// global timeline. Completed chunks are cached, so a failed run only
+// redoes the chunks it did not finish. A zero Length disables it.
+type Chunking struct {
+	Length time.Duration
+	// Workers limits parallel whisper processes; <= 0 means 2.
+	Workers int
+	// Refresh ignores cached chunks and transcribes every chunk again.
+	Refresh bool
+}
+
+const (
+	defaultChunkWorkers = 2
+	// Silences quieter than silenceNoise and longer than silenceMin are cut
+	// points; cutting inside one does not split a word.
+	silenceNoise = "-35dB"
+	silenceMin   = 0.4
+)
+
+// span is a part of the audio; atSilence reports whether it ends in a
+// detected silence rather than at a hard cut.
+type span struct {
+	start, end time.Duration
+	atSilence  bool
+}
+
+type chunkResult struct {
+	raw    whisperJSON
+	cached bool
+	err    error
+}
+
+func (a *Adapter) transcribeChunked(ctx context.Context, wavPath, cacheDir string) (types.Transcript, error) {
+	total, silences, err := a.detectSilences(ctx, wavPath)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+	spans := chunkSpans(total, silences, a.cfg.Chunking.Length)
+	if len(spans) == 1 {
+		a.cfg.Logf("whisper: %s of audio fits one chunk", total.Truncate(time.Second))
+		raw, err := a.run(ctx, wavPath, filepath.Join(cacheDir, "whisper"), a.cfg.Threads)
+		if err != nil {
+			return types.Transcript{}, err
+		}
+		tr := raw.toTranscript()
+		tr.Translated = a.cfg.Translate
+		return tr, nil
+	}
+
+	workers := a.cfg.Chunking.Workers
+	if workers <= 0 {
+		workers = defaultChunkWorkers
+	}
+	workers = min(workers, len(spans))
+	threads := a.cfg.Threads
+	if threads <= 0 {
+		threads = max(1, runtime.NumCPU()/workers)
+	}
+	hard := 0
+	for _, s := range spans[:len(spans)-1] {
+		if !s.atSilence {
+			hard++
+		}
+	}
+	a.cfg.Logf(
+		"whisper: %d chunks of ~%s (%d silences, %d hard cuts), %d workers x %d threads",
+		len(spans), a.cfg.Chunking.Length, len(silences), hard, workers, threads,
+	)
+
+	chunkDir := filepath.Join(cacheDir, "chunks")
+	if err := os.MkdirAll(chunkDir, 0o755); err != nil {
+		return types.Transcript{}, err
+	}
+
+	// Results are indexed by chunk so stitching does not depend on
+	// completion order.
+	results := make([]chunkResult, len(spans))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, s := range spans {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			results[i] = a.transcribeChunk(ctx, wavPath, chunkDir, s, threads)
+			r := results[i]
+			switch {
+			case r.err != nil:
+				a.cfg.Logf("whisper: chunk %d/%d [%s-%s] failed: %v", i+1, len(spans), clock(s.start), clock(s.end), r.err)
+			case r.cached:
+				a.cfg.Logf("whisper: chunk %d/%d [%s-%s] cached", i+1, len(spans), clock(s.start), clock(s.end))
+			default:
+				a.cfg.Logf("whisper: chunk %d/%d [%s-%s] done", i+1, len(spans), clock(s.start), clock(s.end))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+	}
+	if len(errs) > 0 {
+		return types.Transcript{}, fmt.Errorf("whisper.cpp: %d of %d chunks failed (finished chunks are cached): %w", len(errs), len(spans), errors.Join(errs...))
+	}
+
+	raws := make([]whisperJSON, len(results))
+	for i, r := range results {
+		raws[i] = r.raw
+	}
+	tr := stitch(spans, raws)
+	tr.Translated = a.cfg.Translate
+	return tr, nil
+}
+
+// transcribeChunk cuts s out of the audio and transcribes it, reusing the
+// chunk's JSON from an earlier run. The chunk is keyed by its bounds, so a
+// different chunk length never reuses it.
+func (a *Adapter) transcribeChunk(ctx context.Context, wavPath, chunkDir string, s span, threads int) chunkResult {
+	prefix := filepath.Join(chunkDir, fmt.Sprintf("%d-%d", s.start.Milliseconds(), s.end.Milliseconds()))
+	if raw, ok := a.cachedChunk(prefix); ok {
+		return chunkResult{raw: raw, cached: true}
+	}
+
+	chunkWav := prefix + ".wav"
+	defer func() { _ = os.Remove(chunkWav) }()
+	cmd := exec.CommandContext(ctx, a.cfg.FFmpeg,
+		"-y",
+		"-ss", seconds(s.start),
+		"-t", seconds(s.end-s.start),
+		"-i", wavPath,
+		"-c:a", "pcm_s16le",
+		chunkWav,
+	)
+	if b, err := cmd.CombinedOutput(); err != nil {
+		return chunkResult{err: fmt.Errorf("ffmpeg cut chunk: %w\n%s", err, string(b))}
+	}
+	raw, err := a.run(ctx, chunkWav, prefix, threads)
+	return chunkResult{raw: raw, err: err}
+}
+
+// cachedChunk returns the chunk transcript an earlier run left at
+// prefix.json, unless the chunks are refreshed.
+func (a *Adapter) cachedChunk(prefix string) (whisperJSON, bool) {
+	if a.cfg.Chunking.Refresh {
+		return whisperJSON{}, false
+	}
+	raw, err := readWhisperJSON(prefix + ".json")
+	return raw, err == nil
+}
+
+// detectSilences returns the audio duration and its silences, found with
+// ffmpeg's silencedetect filter.
+func (a *Adapter) detectSilences(ctx context.Context, wavPath string) (time.Duration, []span, error) {
+	cmd := exec.CommandContext(ctx, a.cfg.FFmpeg,
+		"-hide_banner",
+		"-nostats",
+		"-i", wavPath,
+		"-af", fmt.Sprintf("silencedetect=noise=%s:d=%g", silenceNoise, silenceMin),
+		"-f", "null",
+		"-",
+	)
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, nil, fmt.Errorf("ffmpeg silencedetect: %w\n%s", err, string(b))
+	}
+	total, silences := parseSilences(b)
+	if total <= 0 {
+		return 0, nil, fmt.Errorf("ffmpeg silencedetect: no duration in output")
+	}
+	return total, silences, nil
+}
+
+// parseSilences reads the input duration and the silence_start /
+// silence_end pairs from silencedetect's log. A silence still open at the
+// end of the input is dropped.
+func parseSilences(out []byte) (time.Duration, []span) {
+	var (
+		total    time.Duration
+		silences []span
+		start    = time.Duration(-1)
+	)
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "Duration: "); i >= 0 && total == 0 {
+			v, _, _ := strings.Cut(line[i+len("Duration: "):], ",")
+			total = parseClock(v)
+		}
+		if v, ok := fieldAfter(line, "silence_start: "); ok {
+			start = v
+		}
+		if v, ok := fieldAfter(line, "silence_end: "); ok && start >= 0 {
+			silences = append(silences, span{start: start, end: v})
+			start = -1
+		}
+	}
+	return total, silences
+}
+
+func fieldAfter(line, key string) (time.Duration, bool) {
+	i := strings.Index(line, key)
+	if i < 0 {
+		return 0, false
+	}
+	v := strings.Fields(line[i+len(key):])
+	if len(v) == 0 {
+		return 0, false
+	}
+	sec, err := strconv.ParseFloat(v[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return max(0, time.Duration(sec*float64(time.Second))), true
+}
+
+// parseClock parses ffmpeg's HH:MM:SS.ss.
+func parseClock(s string) time.Duration {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	var sec float64
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0
+		}
+		sec = sec*60 + v
+	}
+	return time.Duration(sec * float64(time.Second))
+}
+
+// chunkSpans cuts [0, total) into chunks of about length, each ending in
+// the middle of the silence closest to its target end within half a length
+// of it, or at the target when there is none. The last chunk takes the
+// remainder, up to one and a half lengths.
+func chunkSpans(total time.Duration, silences []span, length time.Duration) []span {
+	var out []span
+	pos := time.Duration(0)
+	for total-pos > length+length/2 {
+		target := pos + length
+		cut := span{start: pos, end: target}
+		best := time.Duration(-1)
+		for _, s := range silences {
+			mid := s.start + (s.end-s.start)/2
+			if mid <= pos+length/2 || mid >= target+length/2 {
+				continue
+			}
+			if d := (mid - target).Abs(); best < 0 || d < best {
+				best = d
+				cut.end, cut.atSilence = mid, true
+			}
+		}
+		out = append(out, cut)
+		pos = cut.end
+	}
+	return append(out, span{start: pos, end: total})
+}
+
+// stitch joins chunk transcripts, moving their timestamps to the global
+// timeline. The language is the one most chunks report, so a short chunk
+// of music or another language does not decide it. Speakers are labelled
+// over all chunks at once, so tinydiarize turns carry across chunk
+// boundaries instead of every chunk restarting at S1.
+func stitch(spans []span, raws []whisperJSON) types.Transcript {
+	var tr types.Transcript
+	var all whisperJSON
+	votes := map[string]int{}
+	for i, raw := range raws {
+		all.Transcription = append(all.Transcription, raw.Transcription...)
+		ct := raw.toTranscript()
+		if ct.Language != "" {
+			votes[ct.Language]++
+			if votes[ct.Language] > votes[tr.Language] {
+				tr.Language = ct.Language
+			}
+		}
+		off := spans[i].start.Seconds()
+		for _, seg := range ct.Segments {
+			seg.Start += off
+			seg.End += off
+			for j := range seg.Words {
+				seg.Words[j].Start += off
+				seg.Words[j].End += off
+			}
+			tr.Segments = append(tr.Segments, seg)
+		}
+	}
+	for i, speaker := range all.speakers() {
+		tr.Segments[i].Speaker = speaker
+	}
+	return tr
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func clock(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
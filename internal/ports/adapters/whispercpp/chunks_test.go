@@ -0,0 +1,153 @@
+package whispercpp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCachedChunk_Refresh(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "0-60000")
+	doc := `{"result":{"language":"en"},"transcription":[{"offsets":{"from":0,"to":1000},"text":" cached"}]}`
+	if err := os.WriteFile(prefix+".json", []byte(doc), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	raw, ok := New(Config{}).cachedChunk(prefix)
+	if !ok || len(raw.Transcription) != 1 {
+		t.Fatalf("expected the cached chunk to be reused, got %+v, %t", raw, ok)
+	}
+	if _, ok := New(Config{Chunking: Chunking{Refresh: true}}).cachedChunk(prefix); ok {
+		t.Fatalf("expected a refresh to ignore the cached chunk")
+	}
+	if _, ok := New(Config{}).cachedChunk(filepath.Join(filepath.Dir(prefix), "60000-120000")); ok {
+		t.Fatalf("expected a missing chunk not to be cached")
+	}
+}
+
+func TestParseSilences(t *testing.T) {
+	// Trimmed from `ffmpeg -i audio.wav -af silencedetect=noise=-35dB:d=0.4 -f null -`.
+	out := `Input #0, wav, from 'audio.wav':
+  Duration: 00:25:03.52, bitrate: 256 kb/s
+  Stream #0:0: Audio: pcm_s16le ([1][0][0][0] / 0x0001), 16000 Hz, 1 channels, s16, 256 kb/s
+[silencedetect @ 0x600000b54000] silence_start: 598.112
+[silencedetect @ 0x600000b54000] silence_end: 599.004 | silence_duration: 0.892
+[silencedetect @ 0x600000b54000] silence_start: 1204.5
+[silencedetect @ 0x600000b54000] silence_end: 1205.25 | silence_duration: 0.75
+[silencedetect @ 0x600000b54000] silence_start: 1502.9
+size=N/A time=00:25:03.52 bitrate=N/A speed= 812x
+`
+	total, silences := parseSilences([]byte(out))
+	if want := 25*time.Minute + 3520*time.Millisecond; total != want {
+		t.Fatalf("total = %s, want %s", total, want)
+	}
+	// The silence still open at the end is dropped.
+	want := []span{
+		{start: 598112 * time.Millisecond, end: 599004 * time.Millisecond},
+		{start: 1204500 * time.Millisecond, end: 1205250 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(silences, want) {
+		t.Fatalf("silences = %+v, want %+v", silences, want)
+	}
+	if total, _ := parseSilences([]byte("garbage\n")); total != 0 {
+		t.Fatalf("expected no duration in garbage, got %s", total)
+	}
+}
+
+func TestChunkSpans(t *testing.T) {
+	minutes := func(m float64) time.Duration { return time.Duration(m * float64(time.Minute)) }
+	cases := []struct {
+		name     string
+		total    time.Duration
+		silences []span
+		want     []span
+	}{
+		{
+			name:  "fits one chunk",
+			total: minutes(14),
+			want:  []span{{start: 0, end: minutes(14)}},
+		},
+		{
+			name:  "nearest silence wins",
+			total: minutes(25),
+			silences: []span{
+				{start: minutes(8), end: minutes(8) + time.Second},
+				{start: minutes(10.5), end: minutes(10.5) + 2*time.Second},
+			},
+			want: []span{
+				{start: 0, end: minutes(10.5) + time.Second, atSilence: true},
+				{start: minutes(10.5) + time.Second, end: minutes(25)},
+			},
+		},
+		{
+			name:  "hard cut without a silence in reach",
+			total: minutes(25),
+			// Too early and too late: more than half a length from 10m.
+			silences: []span{{start: minutes(4), end: minutes(4) + time.Second}, {start: minutes(16), end: minutes(16) + time.Second}},
+			want: []span{
+				{start: 0, end: minutes(10)},
+				{start: minutes(10), end: minutes(25)},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := chunkSpans(tc.total, tc.silences, minutes(10)); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("chunkSpans = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStitch(t *testing.T) {
+	chunk := func(lang, text string) whisperJSON {
+		var raw whisperJSON
+		doc := `{"result":{"language":"` + lang + `"},"transcription":[{"offsets":{"from":500,"to":1500},"text":" ` + text + `",` +
+			`"tokens":[{"text":" ` + text + `","offsets":{"from":500,"to":1500}}]}]}`
+		if err := json.Unmarshal([]byte(doc), &raw); err != nil {
+			t.Fatalf("fixture: %v", err)
+		}
+		return raw
+	}
+	spans := []span{{start: 0, end: time.Minute}, {start: time.Minute, end: 2 * time.Minute}, {start: 2 * time.Minute, end: 150 * time.Second}}
+	tr := stitch(spans, []whisperJSON{chunk("de", "eins"), chunk("de", "zwei"), chunk("en", "music")})
+	if tr.Language != "de" {
+		t.Fatalf("language = %q, want the majority de", tr.Language)
+	}
+	if len(tr.Segments) != 3 {
+		t.Fatalf("got %d segments, want 3", len(tr.Segments))
+	}
+	for i, off := range []float64{0, 60, 120} {
+		seg := tr.Segments[i]
+		if seg.Start != off+0.5 || seg.End != off+1.5 || seg.Words[0].Start != off+0.5 || seg.Words[0].End != off+1.5 {
+			t.Fatalf("segment %d = %+v, want it shifted by %gs", i, seg, off)
+		}
+	}
+}
+
+func TestStitch_TinydiarizeAcrossChunks(t *testing.T) {
+	chunk := func(turns ...bool) whisperJSON {
+		var raw whisperJSON
+		for i, turn := range turns {
+			seg := whisperSeg{Text: " words", SpeakerTurnNext: turn}
+			seg.Offsets.From, seg.Offsets.To = i*1000, i*1000+900
+			raw.Transcription = append(raw.Transcription, seg)
+		}
+		return raw
+	}
+	spans := []span{{start: 0, end: time.Minute}, {start: time.Minute, end: 2 * time.Minute}, {start: 2 * time.Minute, end: 3 * time.Minute}}
+	// The second chunk has no turn marker of its own; the third starts with
+	// the speaker the second one ended with.
+	tr := stitch(spans, []whisperJSON{chunk(false, true), chunk(false, false), chunk(true, false)})
+	want := []string{"S1", "S1", "S2", "S2", "S2", "S1"}
+	if len(tr.Segments) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(tr.Segments), len(want))
+	}
+	for i, w := range want {
+		if tr.Segments[i].Speaker != w {
+			t.Fatalf("segment %d speaker = %q, want %q", i, tr.Segments[i].Speaker, w)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package whispercpp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Model is a ggml whisper model published with whisper.cpp.
+type Model struct {
+	Name string
+	// SHA256 pins the file content; empty means the model is not pinned
+	// yet and its checksum is reported but not enforced.
+	SHA256 string
+}
+
+// DefaultModel is what `make setup` downloads.
+const DefaultModel = "base"
+
+// modelBaseURL is where whisper.cpp publishes its ggml models.
+const modelBaseURL = "https://huggingface.co/ggerganov/whisper.cpp/resolve/main/"
+
+// models is the registry of known model names. Only pinned entries are
+// verified against a checksum; pin a model by adding the SHA256 of a file
+// downloaded from modelBaseURL. scripts/setup.sh reads the pins from here,
+// so keep every entry on one line.
+var models = []Model{
+	{Name: "tiny"},
+	{Name: "tiny.en"},
+	{Name: "base", SHA256: "60ed5bc3dd14eea856493d334349b405782ddcaf0028d4b5df4088345fba2efe"},
+	{Name: "base.en"},
+	{Name: "base-q5_1"},
+	{Name: "small"},
+	{Name: "small.en"},
+	{Name: "small.en-tdrz"},
+	{Name: "small-q5_1"},
+	{Name: "medium"},
+	{Name: "medium.en"},
+	{Name: "medium-q5_0"},
+	{Name: "large-v3"},
+	{Name: "large-v3-q5_0"},
+	{Name: "large-v3-turbo"},
+	{Name: "large-v3-turbo-q5_0"},
+}
+
+// Models lists the registry.
+func Models() []Model {
+	return append([]Model(nil), models...)
+}
+
+// LookupModel returns the registry entry called name.
+func LookupModel(name string) (Model, bool) {
+	for _, m := range models {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// ModelForFile returns the registry entry whose file name matches path's.
+func ModelForFile(path string) (Model, bool) {
+	for _, m := range models {
+		if m.File() == filepath.Base(path) {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// File is the model's file name.
+func (m Model) File() string { return "ggml-" + m.Name + ".bin" }
+
+// URL is where the model is downloaded from.
+func (m Model) URL() string { return modelBaseURL + m.File() }
+
+// EnglishOnly reports whether the model only transcribes English.
+func (m Model) EnglishOnly() bool { return strings.Contains(m.Name, ".en") }
+
+// Tinydiarize reports whether the model marks speaker turns (see
+// DiarizeTinydiarize).
+func (m Model) Tinydiarize() bool { return strings.HasSuffix(m.Name, "-tdrz") }
+
+// IsModelPath reports whether a --whisper-model value is a file path rather
+// than a registry name.
+func IsModelPath(s string) bool {
+	return strings.ContainsRune(s, filepath.Separator) || strings.Contains(s, "/") || strings.HasSuffix(s, ".bin")
+}
+
+// UnknownModelError lists the registry names for an unknown model name.
+func UnknownModelError(name string) error {
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	return fmt.Errorf("unknown whisper model %q (want a path to a .bin file or one of %s)", name, strings.Join(names, ", "))
+}
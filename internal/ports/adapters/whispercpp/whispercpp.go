@@ -7,47 +7,130 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/forPelevin/hlcut/internal/types"
 )
 
+// LanguageAuto lets whisper detect the spoken language.
+const LanguageAuto = "auto"
+
+// Diarization modes.
+const (
+	// DiarizeTinydiarize marks speaker turns with a tdrz model (-tdrz).
+	DiarizeTinydiarize = "tdrz"
+	// DiarizeStereo tells speakers apart by channel energy in stereo audio
+	// (-di), e.g. an interview recorded with one microphone per channel.
+	DiarizeStereo = "stereo"
+)
+
+type Config struct {
+	Bin   string
+	Model string
+	// Language is the spoken language as a whisper code (en, es, ...) or
+	// LanguageAuto; empty means auto. whisper.cpp itself defaults to en.
+	Language string
+	// Translate makes whisper translate the speech to English.
+	Translate bool
+	// Diarize labels segment speakers: DiarizeTinydiarize,
+	// DiarizeStereo or empty for none.
+	Diarize string
+	// Threads is whisper's -t per process; 0 keeps whisper's default, or
+	// splits the CPUs between workers when chunking.
+	Threads int
+	// Chunking transcribes long audio in parallel chunks; FFmpeg finds
+	// and cuts them.
+	Chunking Chunking
+	FFmpeg   string
+	Logf     func(string, ...any)
+}
+
 type Adapter struct {
-	bin   string
-	model string
+	cfg Config
 }
 
-func New(binPath, modelPath string) *Adapter {
-	return &Adapter{bin: binPath, model: modelPath}
+func New(cfg Config) *Adapter {
+	if cfg.Language == "" {
+		cfg.Language = LanguageAuto
+	}
+	if cfg.FFmpeg == "" {
+		cfg.FFmpeg = "ffmpeg"
+	}
+	if cfg.Logf == nil {
+		cfg.Logf = func(string, ...any) {}
+	}
+	return &Adapter{cfg: cfg}
 }
 
 func (a *Adapter) Transcribe(ctx context.Context, wavPath, cacheDir string) (types.Transcript, error) {
-	outPrefix := filepath.Join(cacheDir, "whisper")
+	if a.cfg.Chunking.Length > 0 {
+		return a.transcribeChunked(ctx, wavPath, cacheDir)
+	}
+	raw, err := a.run(ctx, wavPath, filepath.Join(cacheDir, "whisper"), a.cfg.Threads)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+	tr := raw.toTranscript()
+	tr.Translated = a.cfg.Translate
+	return tr, nil
+}
+
+// run transcribes wavPath with one whisper process writing outPrefix.json.
+func (a *Adapter) run(ctx context.Context, wavPath, outPrefix string, threads int) (whisperJSON, error) {
 	args := []string{
-		"-m", a.model,
+		"-m", a.cfg.Model,
 		"-f", wavPath,
+		"-l", a.cfg.Language,
 		"-ojf",
 		"-of", outPrefix,
 	}
-	cmd := exec.CommandContext(ctx, a.bin, args...)
+	if threads > 0 {
+		args = append(args, "-t", strconv.Itoa(threads))
+	}
+	if a.cfg.Translate {
+		args = append(args, "-tr")
+	}
+	switch a.cfg.Diarize {
+	case DiarizeTinydiarize:
+		args = append(args, "-tdrz")
+	case DiarizeStereo:
+		args = append(args, "-di")
+	}
+	cmd := exec.CommandContext(ctx, a.cfg.Bin, args...)
 	b, err := cmd.CombinedOutput()
 	if err != nil {
-		return types.Transcript{}, fmt.Errorf("whisper.cpp failed: %w\n%s", err, string(b))
+		return whisperJSON{}, fmt.Errorf("whisper.cpp failed: %w\n%s", err, string(b))
 	}
+	return readWhisperJSON(outPrefix + ".json")
+}
 
-	jb, err := os.ReadFile(outPrefix + ".json")
+func readWhisperJSON(path string) (whisperJSON, error) {
+	jb, err := os.ReadFile(path)
 	if err != nil {
-		return types.Transcript{}, err
+		return whisperJSON{}, err
 	}
-
 	var raw whisperJSON
 	if err := json.Unmarshal(jb, &raw); err != nil {
+		return whisperJSON{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return raw, nil
+}
+
+// ParseJSON parses whisper.cpp's -ojf output, grouping tokens into words.
+func ParseJSON(b []byte) (types.Transcript, error) {
+	var raw whisperJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
 		return types.Transcript{}, err
 	}
 	return raw.toTranscript(), nil
 }
 
 type whisperJSON struct {
+	// Result.Language is the spoken language, detected or as requested.
+	Result struct {
+		Language string `json:"language"`
+	} `json:"result"`
 	Transcription []whisperSeg `json:"transcription"`
 }
 
@@ -58,6 +141,12 @@ type whisperSeg struct {
 	} `json:"offsets"`
 	Text   string       `json:"text"`
 	Tokens []whisperTok `json:"tokens"`
+	// Speaker is the stereo diarization result: the louder channel ("0" or
+	// "1") or "?" when undecided.
+	Speaker string `json:"speaker"`
+	// SpeakerTurnNext is set by tinydiarize when the next segment is
+	// another speaker's.
+	SpeakerTurnNext bool `json:"speaker_turn_next"`
 }
 
 type whisperTok struct {
@@ -69,19 +158,47 @@ type whisperTok struct {
 }
 
 func (w whisperJSON) toTranscript() types.Transcript {
-	var tr types.Transcript
-	for _, s := range w.Transcription {
+	tr := types.Transcript{Language: w.Result.Language}
+	speakers := w.speakers()
+	for i, s := range w.Transcription {
 		seg := types.Segment{
-			Start: msToSec(s.Offsets.From),
-			End:   msToSec(s.Offsets.To),
-			Text:  strings.TrimSpace(s.Text),
-			Words: tokensToWords(s.Tokens),
+			Start:   msToSec(s.Offsets.From),
+			End:     msToSec(s.Offsets.To),
+			Text:    strings.TrimSpace(s.Text),
+			Words:   tokensToWords(s.Tokens),
+			Speaker: speakers[i],
 		}
 		tr.Segments = append(tr.Segments, seg)
 	}
 	return tr
 }
 
+var stereoSpeakers = map[string]string{"0": "S1", "1": "S2"}
+
+// speakers labels the segments S1, S2, ... Stereo diarization names the
+// channel; tinydiarize only marks turns, so its labels alternate between
+// S1 and S2, which fits the two-person conversations it is made for.
+func (w whisperJSON) speakers() []string {
+	out := make([]string, len(w.Transcription))
+	tdrz := false
+	for _, s := range w.Transcription {
+		tdrz = tdrz || s.SpeakerTurnNext
+	}
+	turn := 0
+	for i, s := range w.Transcription {
+		switch {
+		case stereoSpeakers[s.Speaker] != "":
+			out[i] = stereoSpeakers[s.Speaker]
+		case tdrz:
+			out[i] = fmt.Sprintf("S%d", turn%2+1)
+			if s.SpeakerTurnNext {
+				turn++
+			}
+		}
+	}
+	return out
+}
+
 func tokensToWords(toks []whisperTok) []types.Word {
 	var out []types.Word
 
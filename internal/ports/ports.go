@@ -8,20 +8,53 @@ import (
 )
 
 type VideoTool interface {
-	ExtractAudioMono16k(ctx context.Context, inMP4, outWav string) error
-	RenderClip(ctx context.Context, inMP4 string, start, end time.Duration, outMP4 string, burnASS string) error
-	ProbeDuration(ctx context.Context, inMP4 string) (time.Duration, error)
+	// ExtractAudio16k writes a 16 kHz WAV with channels channels (1 for
+	// transcription, 2 for stereo diarization).
+	ExtractAudio16k(ctx context.Context, in, outWav string, audioTrack, channels int) error
+	RenderClip(
+		ctx context.Context,
+		in string,
+		start, end time.Duration,
+		outPath string,
+		opts types.RenderOptions,
+	) (types.RenderResult, error)
+	// RenderAudiogram renders a clip of an audio-only input; opts.Frame sets
+	// the output size.
+	RenderAudiogram(
+		ctx context.Context,
+		inAudio string,
+		start, end time.Duration,
+		outPath string,
+		ag types.Audiogram,
+		opts types.RenderOptions,
+	) (types.RenderResult, error)
+	ProbeDuration(ctx context.Context, in string) (time.Duration, error)
+	// Probe describes the input's container and streams.
+	Probe(ctx context.Context, in string) (types.MediaInfo, error)
+	// KeyframeBefore returns the last video keyframe at or before t.
+	KeyframeBefore(ctx context.Context, in string, t time.Duration) (time.Duration, error)
 }
 
 type ASR interface {
 	Transcribe(ctx context.Context, wavPath, cacheDir string) (types.Transcript, error)
 }
 
+// TranscriptSource is an ASR that reads an existing transcript instead of
+// the audio, so audio extraction is skipped for it.
+type TranscriptSource interface {
+	ASR
+	// Source describes where the transcript comes from, for logs.
+	Source() string
+}
+
 type LLMRanker interface {
+	// Refine picks up to clipsN clips from cands, each within the duration
+	// bounds d.
 	Refine(
 		ctx context.Context,
 		tr types.Transcript,
 		cands []types.Candidate,
 		clipsN int,
+		d types.ClipDurations,
 	) ([]types.ClipSpec, error)
 }
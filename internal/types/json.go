@@ -0,0 +1,95 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Candidates and clip specs are exchanged as JSON artifacts (checkpoints,
+// stage subcommands). Times are encoded as seconds so the files stay readable
+// and hand-editable, matching the manifest.
+
+type candidateJSON struct {
+	StartSec  float64 `json:"start_sec"`
+	EndSec    float64 `json:"end_sec"`
+	Text      string  `json:"text"`
+	Turns     []Turn  `json:"turns,omitempty"`
+	InfoScore float64 `json:"info_score"`
+	HookScore float64 `json:"hook_score"`
+}
+
+func (c Candidate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(candidateJSON{
+		StartSec:  c.Start.Seconds(),
+		EndSec:    c.End.Seconds(),
+		Text:      c.Text,
+		Turns:     c.Turns,
+		InfoScore: c.InfoScore,
+		HookScore: c.HookScore,
+	})
+}
+
+func (c *Candidate) UnmarshalJSON(b []byte) error {
+	var v candidateJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*c = Candidate{
+		Start:     Seconds(v.StartSec),
+		End:       Seconds(v.EndSec),
+		Text:      v.Text,
+		Turns:     v.Turns,
+		InfoScore: v.InfoScore,
+		HookScore: v.HookScore,
+	}
+	return nil
+}
+
+type clipSpecJSON struct {
+	StartSec float64  `json:"start_sec"`
+	EndSec   float64  `json:"end_sec"`
+	Title    string   `json:"title"`
+	Caption  string   `json:"caption"`
+	Tags     []string `json:"tags"`
+	Keywords []string `json:"keywords,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Model    string   `json:"model,omitempty"`
+}
+
+func (c ClipSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(clipSpecJSON{
+		StartSec: c.Start.Seconds(),
+		EndSec:   c.End.Seconds(),
+		Title:    c.Title,
+		Caption:  c.Caption,
+		Tags:     c.Tags,
+		Keywords: c.Keywords,
+		Reason:   c.Reason,
+		Model:    c.Model,
+	})
+}
+
+func (c *ClipSpec) UnmarshalJSON(b []byte) error {
+	var v clipSpecJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*c = ClipSpec{
+		Start:    Seconds(v.StartSec),
+		End:      Seconds(v.EndSec),
+		Title:    v.Title,
+		Caption:  v.Caption,
+		Tags:     v.Tags,
+		Keywords: v.Keywords,
+		Reason:   v.Reason,
+		Model:    v.Model,
+	}
+	return nil
+}
+
+// Seconds converts fractional seconds (as used in JSON artifacts) to a
+// duration, rounding to the nearest nanosecond.
+func Seconds(sec float64) time.Duration {
+	return time.Duration(math.Round(sec * float64(time.Second)))
+}
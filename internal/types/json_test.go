@@ -0,0 +1,53 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClipSpecJSON_RoundTripSeconds(t *testing.T) {
+	in := []ClipSpec{{
+		Start:   12*time.Second + 500*time.Millisecond,
+		End:     40 * time.Second,
+		Title:   "t",
+		Caption: "c",
+		Tags:    []string{"x"},
+		Model:   "m",
+	}}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(b), `"start_sec":12.5`) {
+		t.Fatalf("expected seconds encoding, got %s", b)
+	}
+
+	var out []ClipSpec
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(out) != 1 || out[0].Start != in[0].Start || out[0].End != in[0].End || out[0].Title != "t" || out[0].Model != "m" {
+		t.Fatalf("unexpected round trip: %+v", out)
+	}
+}
+
+func TestCandidateJSON_RoundTripSeconds(t *testing.T) {
+	in := Candidate{
+		Start: 1500 * time.Millisecond, End: 30 * time.Second, Text: "hi", InfoScore: 1, HookScore: 2,
+		Turns: []Turn{{Speaker: "S1", Text: "hi"}},
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var out Candidate
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("unexpected round trip: %+v != %+v", out, in)
+	}
+}
@@ -3,7 +3,11 @@ package types
 import "time"
 
 type Transcript struct {
-	Segments []Segment `json:"segments"`
+	// Language is the spoken language code reported by the ASR (e.g. "es");
+	// empty when unknown. Translated means the text was translated to English.
+	Language   string    `json:"language,omitempty"`
+	Translated bool      `json:"translated,omitempty"`
+	Segments   []Segment `json:"segments"`
 }
 
 type Segment struct {
@@ -11,47 +15,309 @@ type Segment struct {
 	End   float64 `json:"end"`
 	Text  string  `json:"text"`
 	Words []Word  `json:"words,omitempty"`
+	// Speaker labels who talks (S1, S2, ...) when the transcript is
+	// diarized; empty when unknown.
+	Speaker string `json:"speaker,omitempty"`
 }
 
 type Word struct {
 	Start float64 `json:"start"`
 	End   float64 `json:"end"`
 	Word  string  `json:"word"`
+	// Speaker overrides the segment's speaker for this word.
+	Speaker string `json:"speaker,omitempty"`
+}
+
+// WordSpeaker returns who says w in seg.
+func WordSpeaker(seg Segment, w Word) string {
+	if w.Speaker != "" {
+		return w.Speaker
+	}
+	return seg.Speaker
+}
+
+// Speakers lists the transcript's speakers in order of first appearance.
+func (t Transcript) Speakers() []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(s string) {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	for _, seg := range t.Segments {
+		add(seg.Speaker)
+		for _, w := range seg.Words {
+			add(w.Speaker)
+		}
+	}
+	return out
+}
+
+// ClipDurations bounds the length of candidate and selected clips.
+type ClipDurations struct {
+	Min time.Duration
+	Max time.Duration
 }
 
 type Candidate struct {
 	Start time.Duration
 	End   time.Duration
 	Text  string
+	// Turns splits Text by speaker in a diarized transcript.
+	Turns []Turn
 
 	InfoScore float64
 	HookScore float64
 }
 
+// Turn is one speaker's stretch of a candidate.
+type Turn struct {
+	Speaker string `json:"speaker"`
+	Text    string `json:"text"`
+}
+
 type ClipSpec struct {
 	Start   time.Duration
 	End     time.Duration
 	Title   string
 	Caption string
 	Tags    []string
-	Reason  string
+	// Keywords are words of the clip worth emphasizing in captions.
+	Keywords []string
+	Reason   string
+	// Model is the LLM that chose the clip; empty for clips picked without one.
+	Model string
+}
+
+// Frame is the output geometry of rendered clips. The zero value keeps the
+// source geometry.
+type Frame struct {
+	// Aspect is the target aspect ratio, e.g. "9:16".
+	Aspect string
+	// Mode is how the source is fitted into the frame: crop-center, blur-pad
+	// or letterbox.
+	Mode   string
+	Width  int
+	Height int
+}
+
+// Reframes reports whether clips are scaled into a target frame.
+func (f Frame) Reframes() bool { return f.Width > 0 && f.Height > 0 }
+
+// Range is a span of source time.
+type Range struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// RenderOptions describe how a clip is rendered beyond its time range.
+type RenderOptions struct {
+	// BurnASS is the subtitle file burned into the video; empty for none.
+	BurnASS string
+	// FontsDir is searched for the fonts the burned subtitles use.
+	FontsDir string
+	Frame    Frame
+	// Keep lists the source ranges joined into the clip when it is
+	// tightened; empty renders the whole clip range.
+	Keep  []Range
+	Audio AudioOptions
+	// AudioTrack selects the input's audio stream (ffmpeg's "0:a:N").
+	AudioTrack int
+	// Format is the output format (mp4, webm, mov); empty means mp4.
+	Format string
+	// MaxVideoKbps caps the encoded video bitrate (0 = uncapped); ProRes
+	// and stream copies ignore it.
+	MaxVideoKbps int
+	// FastCut stream-copies the clip instead of encoding it (FastCutCopy or
+	// FastCutSmart); ignored when anything filters the clip.
+	FastCut string
+}
+
+// Fast cut modes.
+const (
+	// FastCutCopy stream-copies from the keyframe at or before the start.
+	FastCutCopy = "copy"
+	// FastCutSmart re-encodes up to the first keyframe and copies the rest,
+	// keeping the exact start.
+	FastCutSmart = "smart"
+)
+
+// AudioOptions describe the audio processing of rendered clips.
+type AudioOptions struct {
+	// LoudnessLUFS is the EBU R128 integrated loudness target of two-pass
+	// loudnorm, e.g. -14; 0 leaves the loudness as is.
+	LoudnessLUFS float64
+	// Fade is the length of the fade-in and fade-out at the clip boundaries;
+	// 0 disables fades.
+	Fade time.Duration
+}
+
+// RenderResult reports what was measured while rendering a clip.
+type RenderResult struct {
+	// CutMode is the fast-cut mode the clip was cut with (FastCutCopy or
+	// FastCutSmart); empty when it was encoded, including a smart cut that
+	// fell back to encoding.
+	CutMode string
+	// SourceLoudnessLUFS and LoudnessLUFS are the integrated loudness before
+	// and after normalization; zero when loudness was not normalized.
+	SourceLoudnessLUFS float64
+	LoudnessLUFS       float64
+}
+
+// MediaInfo describes an input file as probed by ffprobe.
+type MediaInfo struct {
+	// Format is the container, e.g. "mov,mp4,m4a,3gp,3g2,mj2".
+	Format      string        `json:"format,omitempty"`
+	DurationSec float64       `json:"duration_sec"`
+	Title       string        `json:"title,omitempty"`
+	Streams     []MediaStream `json:"streams"`
+}
+
+// MediaStream is one stream of an input file.
+type MediaStream struct {
+	// Index is the stream index in the container.
+	Index int `json:"index"`
+	// Type is video, audio, subtitle, data or attachment.
+	Type        string  `json:"type"`
+	Codec       string  `json:"codec,omitempty"`
+	DurationSec float64 `json:"duration_sec,omitempty"`
+	Default     bool    `json:"default,omitempty"`
+	Language    string  `json:"language,omitempty"`
+
+	// Video streams. Rotation is the display rotation in degrees; VFR is
+	// set when the average frame rate differs from the nominal one.
+	Width    int     `json:"width,omitempty"`
+	Height   int     `json:"height,omitempty"`
+	Rotation int     `json:"rotation,omitempty"`
+	FPS      float64 `json:"fps,omitempty"`
+	VFR      bool    `json:"vfr,omitempty"`
+	// AttachedPic marks cover art in audio files, which is not video.
+	AttachedPic bool `json:"attached_pic,omitempty"`
+
+	// Audio streams.
+	Channels   int `json:"channels,omitempty"`
+	SampleRate int `json:"sample_rate,omitempty"`
+}
+
+func (m MediaInfo) Duration() time.Duration { return Seconds(m.DurationSec) }
+
+// Video returns the first video stream that is not cover art.
+func (m MediaInfo) Video() (MediaStream, bool) {
+	for _, s := range m.Streams {
+		if s.Type == "video" && !s.AttachedPic {
+			return s, true
+		}
+	}
+	return MediaStream{}, false
+}
+
+// AudioTracks lists the audio streams in order; audio track N is ffmpeg's
+// "0:a:N".
+func (m MediaInfo) AudioTracks() []MediaStream {
+	var out []MediaStream
+	for _, s := range m.Streams {
+		if s.Type == "audio" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// DefaultAudioTrack picks the audio track flagged as default, else the
+// first one.
+func (m MediaInfo) DefaultAudioTrack() int {
+	for i, s := range m.AudioTracks() {
+		if s.Default {
+			return i
+		}
+	}
+	return 0
+}
+
+// InputInfo is the probed input of a run as recorded in the manifest.
+type InputInfo struct {
+	MediaInfo
+	// AudioTrack is the audio track transcribed and rendered.
+	AudioTrack int `json:"audio_track"`
+}
+
+// Audiogram describes the picture rendered for clips of audio-only inputs.
+type Audiogram struct {
+	// Cover is an image shown as background; empty uses Background.
+	Cover string
+	// Background is an ffmpeg color, e.g. "#101820" or "navy".
+	Background string
+	// Title is the episode title shown at the top; empty for none.
+	Title string
+}
+
+// CutInfo records how a fast-cut clip was cut. StartSec is where the clip
+// actually starts: the copy mode snaps to the preceding keyframe.
+type CutInfo struct {
+	Mode     string  `json:"mode"`
+	StartSec float64 `json:"start_sec"`
+	EndSec   float64 `json:"end_sec"`
+}
+
+// SubtitleFile is a subtitle file written next to a clip, e.g. an SRT sidecar
+// for platforms that take soft captions. ManifestClip.SubtitleFiles lists all
+// of them; ManifestClip.Subtitles only names the ASS file burned in.
+type SubtitleFile struct {
+	// Format is ass, srt or vtt.
+	Format string `json:"format"`
+	// File is relative to the run directory.
+	File string `json:"file"`
 }
 
 type Manifest struct {
-	Input string         `json:"input"`
-	Clips []ManifestClip `json:"clips"`
+	Input string `json:"input"`
+	// Source is the probed input; empty in manifests of older runs.
+	Source *InputInfo `json:"source,omitempty"`
+	// Language is the spoken language of the input; Translated means the
+	// transcript and subtitles are an English translation.
+	Language   string `json:"language,omitempty"`
+	Translated bool   `json:"translated,omitempty"`
+	// WhisperModel names the whisper model of the transcript; empty when
+	// the transcript came from elsewhere.
+	WhisperModel string         `json:"whisper_model,omitempty"`
+	Clips        []ManifestClip `json:"clips"`
 }
 
 type ManifestClip struct {
-	ID        string   `json:"id"`
-	StartSec  float64  `json:"start_sec"`
-	EndSec    float64  `json:"end_sec"`
-	InfoScore float64  `json:"info_score"`
-	HookScore float64  `json:"hook_score"`
-	Text      string   `json:"text"`
-	File      string   `json:"file"`
-	Subtitles string   `json:"subtitles"`
-	Title     string   `json:"title"`
-	Caption   string   `json:"caption"`
-	Tags      []string `json:"tags"`
+	ID            string         `json:"id"`
+	StartSec      float64        `json:"start_sec"`
+	EndSec        float64        `json:"end_sec"`
+	InfoScore     float64        `json:"info_score"`
+	HookScore     float64        `json:"hook_score"`
+	Text          string         `json:"text"`
+	File          string         `json:"file"`
+	Subtitles     string         `json:"subtitles"`
+	BurnSubtitles bool           `json:"burn_subtitles"`
+	SubtitleFiles []SubtitleFile `json:"subtitle_files,omitempty"`
+	Title         string         `json:"title"`
+	Caption       string         `json:"caption"`
+	Tags          []string       `json:"tags"`
+	Keywords      []string       `json:"keywords,omitempty"`
+	Model         string         `json:"model,omitempty"`
+	// Output geometry; empty when the clip keeps the source geometry.
+	Aspect  string `json:"aspect,omitempty"`
+	Reframe string `json:"reframe,omitempty"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	// Jump cuts: the clip keeps its source range but filler words and long
+	// pauses were cut out, leaving TightenedSec of video.
+	Tighten      bool    `json:"tighten,omitempty"`
+	Cuts         int     `json:"cuts,omitempty"`
+	TightenedSec float64 `json:"tightened_sec,omitempty"`
+	// Integrated loudness (LUFS) of the clip before and after loudness
+	// normalization; empty unless it was normalized.
+	SourceLoudnessLUFS float64 `json:"source_loudness_lufs,omitempty"`
+	LoudnessLUFS       float64 `json:"loudness_lufs,omitempty"`
+	// Cut is set for clips cut without encoding (--fast-cut).
+	Cut *CutInfo `json:"cut,omitempty"`
+	// Audiogram is set for clips of audio-only inputs, rendered as a
+	// waveform over a cover image or solid color.
+	Audiogram bool `json:"audiogram,omitempty"`
 }
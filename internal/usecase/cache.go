@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+const transcriptCacheFormat = 1
+
+type transcriptCacheFile struct {
+	Format     int              `json:"format"`
+	Transcript types.Transcript `json:"transcript"`
+}
+
+// loadTranscriptCache reports ok=false without an error when no cache entry
+// exists; an error means an entry exists but cannot be trusted.
+func loadTranscriptCache(path string) (types.Transcript, bool, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return types.Transcript{}, false, nil
+	}
+	if err != nil {
+		return types.Transcript{}, false, err
+	}
+	var f transcriptCacheFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return types.Transcript{}, false, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if f.Format != transcriptCacheFormat {
+		return types.Transcript{}, false, fmt.Errorf("%s: unsupported cache format %d", path, f.Format)
+	}
+	return f.Transcript, true, nil
+}
+
+func storeTranscriptCache(path string, tr types.Transcript) error {
+	b, err := json.Marshal(transcriptCacheFile{Format: transcriptCacheFormat, Transcript: tr})
+	if err != nil {
+		return fmt.Errorf("marshal transcript cache: %w", err)
+	}
+	return writeFileAtomic(path, b)
+}
+
+// writeFileAtomic avoids leaving a truncated artifact behind when the process
+// is interrupted mid-write, which would otherwise poison later runs.
+func writeFileAtomic(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
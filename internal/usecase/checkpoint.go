@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// checkpoints persists the result of each completed stage so an interrupted
+// run can continue from the first incomplete stage. A zero value (empty dir)
+// disables checkpointing: loads miss and saves are no-ops.
+type checkpoints struct {
+	dir string
+}
+
+// load reports ok=false without an error when the checkpoint does not exist.
+// An unreadable checkpoint is reported as an error and treated as missing by
+// callers, so the stage is simply redone.
+func (c checkpoints) load(name string, v any) (bool, error) {
+	if c.dir == "" {
+		return false, nil
+	}
+	b, err := os.ReadFile(c.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, fmt.Errorf("checkpoint %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func (c checkpoints) save(name string, v any) error {
+	if c.dir == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint %s: %w", name, err)
+	}
+	if err := writeFileAtomic(c.path(name), b); err != nil {
+		return fmt.Errorf("write checkpoint %s: %w", name, err)
+	}
+	return nil
+}
+
+// SelectionCheckpointed reports whether dir holds a clip selection
+// checkpoint.
+func SelectionCheckpointed(dir string) bool {
+	return fileExists(checkpoints{dir: dir}.path(checkpointSelection))
+}
+
+func (c checkpoints) path(name string) string {
+	return filepath.Join(c.dir, name+".json")
+}
+
+type audioCheckpoint struct {
+	WAV string `json:"wav"`
+}
+
+type clipCheckpoint struct {
+	Clip types.ManifestClip `json:"clip"`
+	// DurationSec is the probed duration right after rendering; resume uses it
+	// to reject clips truncated by an interrupted re-render.
+	DurationSec float64 `json:"duration_sec"`
+}
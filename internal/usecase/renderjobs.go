@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// renderJob is one clip of stage 5 (or of a rerender).
+type renderJob struct {
+	id            string
+	cs            types.ClipSpec
+	burnSubtitles bool
+	// subtitleFormats are the subtitle files written besides the burned ASS.
+	subtitleFormats []string
+	frame           types.Frame
+	// tighten renders the clip as jump cuts, see the tighten package.
+	tighten bool
+	// reason is logged before rendering, e.g. why a rerender is needed.
+	reason string
+	// reuse accepts a complete render from a previous run instead of
+	// rendering again.
+	reuse bool
+}
+
+// renderJobs renders clips with up to in.Jobs concurrent renders, started in
+// clip order. Log lines are emitted in clip order too, so output does not
+// depend on which render finishes first. The first failure cancels the
+// renders still running and no further clips are started.
+func (u Usecase) renderJobs(
+	ctx context.Context,
+	in Input,
+	cp checkpoints,
+	tr types.Transcript,
+	jobs []renderJob,
+) ([]types.ManifestClip, error) {
+	workers := max(1, min(in.Jobs, len(jobs)))
+	if workers > 1 {
+		logf(in.Logf, "rendering %d clips with %d parallel jobs", len(jobs), workers)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logs := newOrderedLog(in.Logf, len(jobs))
+	defer logs.flushAll()
+
+	var (
+		out      = make([]types.ManifestClip, len(jobs))
+		mu       sync.Mutex
+		next     int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	// take hands out job indexes in order; -1 once all are taken or the
+	// render is cancelled.
+	take := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= len(jobs) || ctx.Err() != nil {
+			return -1
+		}
+		next++
+		return next - 1
+	}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := take(); i >= 0; i = take() {
+				jin := in
+				jin.Logf = logs.forJob(i)
+				mc, err := u.renderOne(ctx, jin, cp, tr, i, len(jobs), jobs[i])
+				logs.finish(i)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+					return
+				}
+				out[i] = mc
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (u Usecase) renderOne(
+	ctx context.Context,
+	in Input,
+	cp checkpoints,
+	tr types.Transcript,
+	n, total int,
+	j renderJob,
+) (types.ManifestClip, error) {
+	if j.reuse {
+		var done clipCheckpoint
+		clipPath := filepath.Join(in.OutDir, filepath.FromSlash(clipFile(j.id, in.Format)))
+		if loadCheckpoint(in, cp, "clip-"+j.id, &done) && u.clipIsComplete(ctx, clipPath, done) {
+			logf(in.Logf, "clip %s already rendered, skipping", j.id)
+			return done.Clip, nil
+		}
+	}
+	if j.reason != "" {
+		logf(in.Logf, "clip %s: %s", j.id, j.reason)
+	}
+	logf(
+		in.Logf,
+		"rendering clip %d/%d (%s) [%s -> %s]",
+		n+1,
+		total,
+		j.id,
+		formatTimestamp(j.cs.Start),
+		formatTimestamp(j.cs.End),
+	)
+	started := time.Now()
+	mc, err := u.renderClip(ctx, in, cp, tr, j)
+	if err != nil {
+		return types.ManifestClip{}, fmt.Errorf("clip %s: %w", j.id, err)
+	}
+	logf(in.Logf, "clip %s rendered in %s", j.id, shortDuration(time.Since(started)))
+	return mc, nil
+}
+
+// orderedLog passes through the lines of the earliest unfinished job and
+// buffers the others until every job before them has finished.
+type orderedLog struct {
+	mu      sync.Mutex
+	logf    func(string, ...any)
+	next    int
+	done    []bool
+	pending [][]string
+}
+
+func newOrderedLog(fn func(string, ...any), n int) *orderedLog {
+	return &orderedLog{logf: fn, done: make([]bool, n), pending: make([][]string, n)}
+}
+
+func (o *orderedLog) forJob(i int) func(string, ...any) {
+	return func(format string, args ...any) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if i == o.next {
+			logf(o.logf, format, args...)
+			return
+		}
+		o.pending[i] = append(o.pending[i], fmt.Sprintf(format, args...))
+	}
+}
+
+func (o *orderedLog) finish(i int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.done[i] = true
+	for o.next < len(o.done) && o.done[o.next] {
+		o.next++
+		if o.next < len(o.done) {
+			o.flush(o.next)
+		}
+	}
+}
+
+// flushAll emits what is still buffered, e.g. lines of jobs that finished
+// after a failed one.
+func (o *orderedLog) flushAll() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := o.next; i < len(o.pending); i++ {
+		o.flush(i)
+	}
+	o.next = len(o.done)
+}
+
+func (o *orderedLog) flush(i int) {
+	for _, line := range o.pending[i] {
+		logf(o.logf, "%s", line)
+	}
+	o.pending[i] = nil
+}
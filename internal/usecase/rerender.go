@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/domain/framing"
+	"github.com/forPelevin/hlcut/internal/domain/subtitles"
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+// RerenderResult reports which clips of an edited manifest had to be rendered
+// again.
+type RerenderResult struct {
+	Manifest   types.Manifest
+	Rerendered []string
+}
+
+// Rerender applies hand edits of a run's manifest. Each clip is compared with
+// its render checkpoint: clips whose start/end, burn_subtitles, subtitle
+// files, framing (aspect, reframe) or tighten flag changed, or whose file is
+// missing, are rendered again; metadata-only edits (title, caption, tags) are
+// kept without touching the video. The transcript is taken from the run
+// checkpoint or the transcript cache, never from a new ASR pass unless both
+// are missing.
+func (u Usecase) Rerender(ctx context.Context, in Input, m types.Manifest) (RerenderResult, error) {
+	cp := checkpoints{dir: in.CheckpointDir}
+	if in.InputPath == "" {
+		in.InputPath = m.Input
+	}
+
+	type pending struct {
+		idx     int
+		reason  string
+		frame   types.Frame
+		formats []string
+	}
+	var todo []pending
+	needTranscript := false
+	for i, c := range m.Clips {
+		if err := validateManifestClip(c); err != nil {
+			return RerenderResult{}, err
+		}
+		frame, err := manifestFrame(c)
+		if err != nil {
+			return RerenderResult{}, err
+		}
+		formats, err := subtitleFormats(c)
+		if err != nil {
+			return RerenderResult{}, err
+		}
+		var done clipCheckpoint
+		reason := ""
+		switch {
+		case !loadCheckpoint(in, cp, "clip-"+c.ID, &done):
+			reason = "no render record"
+		case !sameSeconds(done.Clip.StartSec, c.StartSec) || !sameSeconds(done.Clip.EndSec, c.EndSec):
+			reason = "timing changed"
+		case done.Clip.BurnSubtitles != c.BurnSubtitles:
+			reason = "subtitles changed"
+		case !sameFrame(done.Clip, frame):
+			reason = "framing changed"
+		case !slices.Equal(mustSubtitleFormats(done.Clip), formats):
+			reason = "subtitle files changed"
+		case done.Clip.Tighten != c.Tighten:
+			reason = "tightening changed"
+		case !fileExists(filepath.Join(in.OutDir, filepath.FromSlash(clipFile(c.ID, in.Format)))):
+			reason = "file missing"
+		}
+		if reason == "" {
+			continue
+		}
+		todo = append(todo, pending{idx: i, reason: reason, frame: frame, formats: formats})
+		needTranscript = needTranscript || c.BurnSubtitles || len(formats) > 0 || c.Tighten
+	}
+
+	// Only the clips change; the run metadata is kept as it is.
+	res := RerenderResult{Manifest: m}
+	res.Manifest.Clips = append([]types.ManifestClip(nil), m.Clips...)
+	if len(todo) == 0 {
+		logf(in.Logf, "no clip timing, subtitle or framing changes; manifest metadata updated only")
+		return res, u.syncClipMetadata(cp, res.Manifest)
+	}
+
+	var tr types.Transcript
+	if needTranscript {
+		var err error
+		tr, err = u.transcribe(ctx, in, cp)
+		if err != nil {
+			return RerenderResult{}, err
+		}
+	}
+
+	started := time.Now()
+	jobs := make([]renderJob, len(todo))
+	for n, p := range todo {
+		c := m.Clips[p.idx]
+		jobs[n] = renderJob{
+			id: c.ID,
+			cs: types.ClipSpec{
+				Start:    types.Seconds(c.StartSec),
+				End:      types.Seconds(c.EndSec),
+				Title:    c.Title,
+				Caption:  c.Caption,
+				Tags:     c.Tags,
+				Keywords: c.Keywords,
+				Model:    c.Model,
+			},
+			burnSubtitles:   c.BurnSubtitles,
+			subtitleFormats: p.formats,
+			frame:           p.frame,
+			tighten:         c.Tighten,
+			reason:          p.reason,
+		}
+	}
+	clips, err := u.renderJobs(ctx, in, cp, tr, jobs)
+	if err != nil {
+		return RerenderResult{}, err
+	}
+	for n, p := range todo {
+		c, mc := m.Clips[p.idx], clips[n]
+		// Scores and text are not recomputed; keep whatever the manifest had.
+		mc.InfoScore = c.InfoScore
+		mc.HookScore = c.HookScore
+		mc.Text = c.Text
+		res.Manifest.Clips[p.idx] = mc
+		res.Rerendered = append(res.Rerendered, c.ID)
+	}
+	logf(in.Logf, "rerendered %d/%d clips in %s", len(todo), len(m.Clips), shortDuration(time.Since(started)))
+	return res, u.syncClipMetadata(cp, res.Manifest)
+}
+
+// syncClipMetadata stores metadata-only edits in the clip checkpoints, so the
+// checkpoints keep describing what is on disk and `resume` reports the edited
+// manifest.
+func (u Usecase) syncClipMetadata(cp checkpoints, m types.Manifest) error {
+	for _, c := range m.Clips {
+		var done clipCheckpoint
+		ok, err := cp.load("clip-"+c.ID, &done)
+		if err != nil || !ok {
+			continue
+		}
+		done.Clip = c
+		if err := cp.save("clip-"+c.ID, done); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateManifestClip(c types.ManifestClip) error {
+	if c.ID == "" {
+		return fmt.Errorf("manifest clip without id")
+	}
+	if filepath.Base(c.ID) != c.ID || c.ID == "." || c.ID == ".." {
+		return fmt.Errorf("clip %s: invalid id", c.ID)
+	}
+	if c.StartSec < 0 || c.EndSec <= c.StartSec {
+		return fmt.Errorf("clip %s: invalid range %.3fs -> %.3fs", c.ID, c.StartSec, c.EndSec)
+	}
+	return nil
+}
+
+// manifestFrame resolves the output frame of a manifest clip. Width and height
+// only carry the resolution, so editing just the aspect re-derives them.
+func manifestFrame(c types.ManifestClip) (types.Frame, error) {
+	f, err := framing.Resolve(c.Aspect, c.Reframe, min(c.Width, c.Height))
+	if err != nil {
+		return types.Frame{}, fmt.Errorf("clip %s: %w", c.ID, err)
+	}
+	return f, nil
+}
+
+// subtitleFormats returns the formats of the clip's subtitle files.
+func subtitleFormats(c types.ManifestClip) ([]string, error) {
+	names := make([]string, 0, len(c.SubtitleFiles))
+	for _, f := range c.SubtitleFiles {
+		names = append(names, f.Format)
+	}
+	formats, err := subtitles.ParseFormats(names)
+	if err != nil {
+		return nil, fmt.Errorf("clip %s: %w", c.ID, err)
+	}
+	return formats, nil
+}
+
+// mustSubtitleFormats reads formats from a render checkpoint, which only ever
+// holds formats written by hlcut itself.
+func mustSubtitleFormats(c types.ManifestClip) []string {
+	formats, _ := subtitleFormats(c)
+	return formats
+}
+
+func sameFrame(c types.ManifestClip, f types.Frame) bool {
+	return c.Aspect == f.Aspect && c.Reframe == f.Mode && c.Width == f.Width && c.Height == f.Height
+}
+
+func sameSeconds(a, b float64) bool {
+	return math.Abs(a-b) < 0.001
+}
@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/forPelevin/hlcut/internal/types"
+)
+
+func TestRerender_OnlyChangedClips(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	for _, d := range []string{"clips", "subtitles"} {
+		if err := os.MkdirAll(filepath.Join(outDir, d), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", d, err)
+		}
+	}
+
+	var asrCalls int
+	video := &fakeVideoTool{}
+	uc := New(Deps{
+		Video: video,
+		ASR:   fakeASR{tr: testTranscript(), calls: &asrCalls},
+		LLM: fakeLLM{clips: []types.ClipSpec{
+			{Start: 0, End: 20 * time.Second, Title: "a"},
+			{Start: 30 * time.Second, End: 50 * time.Second, Title: "b"},
+		}},
+	})
+	in := Input{
+		InputPath:     filepath.Join(tmp, "in.mp4"),
+		ClipsN:        2,
+		CacheDir:      filepath.Join(tmp, "cache"),
+		OutDir:        outDir,
+		CheckpointDir: filepath.Join(outDir, "checkpoints"),
+	}
+	res, err := uc.Run(context.Background(), in)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	edited := res.Manifest
+	edited.Clips = append([]types.ManifestClip(nil), res.Manifest.Clips...)
+	edited.Clips[0].Title = "renamed"
+	edited.Clips[1].StartSec = 31
+	edited.Clips[1].BurnSubtitles = true
+
+	rr, err := uc.Rerender(context.Background(), in, edited)
+	if err != nil {
+		t.Fatalf("rerender: %v", err)
+	}
+	if len(rr.Rerendered) != 1 || rr.Rerendered[0] != "002" {
+		t.Fatalf("expected only clip 002 to be rerendered, got %v", rr.Rerendered)
+	}
+	if asrCalls != 1 {
+		t.Fatalf("expected transcript to come from checkpoint, got %d asr calls", asrCalls)
+	}
+	if len(video.renderStarts) != 3 || video.renderStarts[2] != 31*time.Second {
+		t.Fatalf("unexpected renders: %v", video.renderStarts)
+	}
+	if video.renderBurnASS[2] == "" {
+		t.Fatalf("expected subtitles to be burned after enabling them")
+	}
+	got := rr.Manifest.Clips
+	if got[0].Title != "renamed" || got[1].Subtitles == "" || got[1].StartSec != 31 {
+		t.Fatalf("unexpected rewritten manifest: %+v", got)
+	}
+
+	// Applying the same manifest again is a no-op.
+	rr, err = uc.Rerender(context.Background(), in, rr.Manifest)
+	if err != nil {
+		t.Fatalf("second rerender: %v", err)
+	}
+	if len(rr.Rerendered) != 0 {
+		t.Fatalf("expected nothing to rerender, got %v", rr.Rerendered)
+	}
+}
+
+func TestRerender_KeepsManifestMetadata(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips: %v", err)
+	}
+	uc := New(Deps{
+		Video: &fakeVideoTool{},
+		ASR:   fakeASR{tr: testTranscript()},
+		LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 20 * time.Second}}},
+	})
+	in := Input{
+		InputPath:     filepath.Join(tmp, "in.mp4"),
+		ClipsN:        1,
+		CacheDir:      filepath.Join(tmp, "cache"),
+		OutDir:        outDir,
+		CheckpointDir: filepath.Join(outDir, "checkpoints"),
+	}
+	res, err := uc.Run(context.Background(), in)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	edited := res.Manifest
+	edited.Language, edited.Translated, edited.WhisperModel = "es", true, "large-v3"
+	edited.Clips = append([]types.ManifestClip(nil), res.Manifest.Clips...)
+	edited.Clips[0].StartSec = 1
+	rr, err := uc.Rerender(context.Background(), in, edited)
+	if err != nil {
+		t.Fatalf("rerender: %v", err)
+	}
+	if len(rr.Rerendered) != 1 {
+		t.Fatalf("expected the retimed clip to be rerendered, got %v", rr.Rerendered)
+	}
+	if got := rr.Manifest; got.Input != edited.Input || got.Language != "es" || !got.Translated || got.WhisperModel != "large-v3" {
+		t.Fatalf("expected manifest metadata to survive a rerender, got %+v", got)
+	}
+}
+
+func TestRerender_RejectsInvalidRange(t *testing.T) {
+	t.Parallel()
+
+	uc := New(Deps{Video: &fakeVideoTool{}})
+	_, err := uc.Rerender(context.Background(), Input{OutDir: t.TempDir()}, types.Manifest{
+		Input: "in.mp4",
+		Clips: []types.ManifestClip{{ID: "001", StartSec: 10, EndSec: 5}},
+	})
+	if err == nil {
+		t.Fatalf("expected error for end before start")
+	}
+}
+
+func TestRerender_AspectEditReframes(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips: %v", err)
+	}
+	video := &fakeVideoTool{}
+	uc := New(Deps{
+		Video: video,
+		ASR:   fakeASR{tr: testTranscript()},
+		LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 20 * time.Second}}},
+	})
+	in := Input{
+		InputPath:     filepath.Join(tmp, "in.mp4"),
+		ClipsN:        1,
+		Frame:         types.Frame{Aspect: "9:16", Mode: "blur-pad", Width: 1080, Height: 1920},
+		CacheDir:      filepath.Join(tmp, "cache"),
+		OutDir:        outDir,
+		CheckpointDir: filepath.Join(outDir, "checkpoints"),
+	}
+	res, err := uc.Run(context.Background(), in)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if c := res.Manifest.Clips[0]; c.Aspect != "9:16" || c.Width != 1080 || c.Height != 1920 {
+		t.Fatalf("expected frame in manifest, got %+v", c)
+	}
+
+	edited := res.Manifest
+	edited.Clips = append([]types.ManifestClip(nil), res.Manifest.Clips...)
+	edited.Clips[0].Aspect = "1:1"
+	rr, err := uc.Rerender(context.Background(), in, edited)
+	if err != nil {
+		t.Fatalf("rerender: %v", err)
+	}
+	if len(rr.Rerendered) != 1 {
+		t.Fatalf("expected the reframed clip to be rerendered, got %v", rr.Rerendered)
+	}
+	want := types.Frame{Aspect: "1:1", Mode: "blur-pad", Width: 1080, Height: 1080}
+	if got := video.renderFrames[1]; got != want {
+		t.Fatalf("rendered frame %+v, want %+v", got, want)
+	}
+	if c := rr.Manifest.Clips[0]; c.Width != 1080 || c.Height != 1080 {
+		t.Fatalf("expected re-derived geometry in manifest, got %+v", c)
+	}
+
+	edited = rr.Manifest
+	edited.Clips = append([]types.ManifestClip(nil), rr.Manifest.Clips...)
+	edited.Clips[0].Reframe = "stretch"
+	if _, err := uc.Rerender(context.Background(), in, edited); err == nil {
+		t.Fatalf("expected error for unknown reframe mode")
+	}
+}
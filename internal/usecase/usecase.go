@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"time"
 
 	"github.com/forPelevin/hlcut/internal/domain/highlights"
 	"github.com/forPelevin/hlcut/internal/domain/subtitles"
+	"github.com/forPelevin/hlcut/internal/domain/tighten"
 	"github.com/forPelevin/hlcut/internal/ports"
 	"github.com/forPelevin/hlcut/internal/types"
 )
@@ -25,37 +27,219 @@ type Usecase struct{ d Deps }
 func New(d Deps) Usecase { return Usecase{d: d} }
 
 type Input struct {
-	InputMP4      string
-	ClipsN        int
+	InputPath string
+	ClipsN    int
+	// Durations bounds candidate and selected clip lengths; the zero value
+	// means highlights.DefaultDurations.
+	Durations     types.ClipDurations
 	BurnSubtitles bool
-	CacheDir      string
-	OutDir        string
-	Logf          func(format string, args ...any)
+	// Frame is the output geometry of rendered clips; the zero value keeps
+	// the source geometry.
+	Frame types.Frame
+	// SubtitleFormats lists the subtitle files written per clip (ass, srt,
+	// vtt), with or without burning; burning always writes the ASS file.
+	SubtitleFormats []string
+	// Captions styles the subtitles (preset or style file); the frame size
+	// and clip title are filled in per clip.
+	Captions subtitles.Options
+	// FontsDir is where ffmpeg looks up the fonts of burned subtitles.
+	FontsDir string
+	// Tighten cuts filler words and long pauses out of every clip; pauses
+	// up to TightenPause are kept (0 means tighten.DefaultMaxPause).
+	Tighten      bool
+	TightenPause time.Duration
+	// Audio is the loudness normalization and fades applied to every clip.
+	Audio types.AudioOptions
+	// AudioTrack is the input's audio stream to transcribe and render
+	// (ffmpeg's "0:a:N").
+	AudioTrack int
+	// Format is the output format of the clips (mp4, webm, mov); empty
+	// means mp4. It is also the file extension.
+	Format string
+	// FastCut stream-copies clips instead of encoding them
+	// (types.FastCutCopy or types.FastCutSmart). Clips that burn subtitles,
+	// reframe, tighten or process audio are encoded regardless.
+	FastCut string
+	// MaxVideoKbps caps the video bitrate of encoded clips (0 = uncapped).
+	// A clip larger than MaxFileSizeMB (0 = no limit) is logged.
+	MaxVideoKbps  int
+	MaxFileSizeMB int
+	// StereoAudio extracts both channels for whisper's stereo diarization
+	// instead of a mono mix.
+	StereoAudio bool
+	// WhisperModel names the transcription model, recorded in the manifest.
+	WhisperModel string
+	// Source is the probed input, recorded in the manifest; may be nil.
+	Source *types.InputInfo
+	// Audiogram is set when the input is audio-only: clips are rendered as
+	// a waveform over a cover image or solid color, sized by Frame.
+	Audiogram *types.Audiogram
+	// Jobs bounds how many clips render concurrently; <= 1 renders one by one.
+	Jobs     int
+	CacheDir string
+	OutDir   string
+	Logf     func(format string, args ...any)
+
+	// TranscriptCache is where the parsed transcript for this input is
+	// persisted and reused from. Empty disables transcript caching.
+	TranscriptCache string
+	// RefreshTranscript re-runs stages 1-2 even when a cached transcript exists.
+	RefreshTranscript bool
+
+	// CheckpointDir receives one checkpoint per completed stage (and per
+	// rendered clip). Existing checkpoints are picked up, so running again with
+	// the same directory resumes from the first incomplete stage. Empty
+	// disables checkpointing.
+	CheckpointDir string
 }
 
 type Result struct {
 	Manifest types.Manifest
 }
 
+const (
+	checkpointAudio      = "audio"
+	checkpointTranscript = "transcript"
+	checkpointCandidates = "candidates"
+	checkpointSelection  = "selection"
+)
+
 func (u Usecase) Run(ctx context.Context, in Input) (Result, error) {
 	// Stages are ordered and fail-fast on purpose: each stage consumes artifacts
 	// from the previous one, so continuing after an error would only produce
-	// misleading partial output.
-	wav := filepath.Join(in.CacheDir, "audio.wav")
+	// misleading partial output. Completed stages are checkpointed instead, so
+	// a failed run can be resumed rather than restarted.
+	cp := checkpoints{dir: in.CheckpointDir}
 
-	logf(in.Logf, "stage 1/5: extracting audio")
-	stageStart := time.Now()
-	if err := u.d.Video.ExtractAudioMono16k(ctx, in.InputMP4, wav); err != nil {
+	tr, err := u.transcribe(ctx, in, cp)
+	if err != nil {
 		return Result{}, err
 	}
-	logf(in.Logf, "stage 1/5 done in %s", shortDuration(time.Since(stageStart)))
 
-	logf(in.Logf, "stage 2/5: transcribing audio")
-	stageStart = time.Now()
-	tr, err := u.d.ASR.Transcribe(ctx, wav, in.CacheDir)
+	cands, err := u.candidates(in, cp, tr)
+	if err != nil {
+		return Result{}, err
+	}
+
+	clipSpecs, err := u.selectClips(ctx, in, cp, tr, cands)
 	if err != nil {
 		return Result{}, err
 	}
+
+	m, err := u.render(ctx, in, cp, tr, clipSpecs)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Manifest: m}, nil
+}
+
+// Transcribe runs stages 1-2 only (audio extraction and ASR), honoring the
+// transcript cache and checkpoints configured in the input.
+func (u Usecase) Transcribe(ctx context.Context, in Input) (types.Transcript, error) {
+	return u.transcribe(ctx, in, checkpoints{dir: in.CheckpointDir})
+}
+
+// Select runs stage 4 only: picks up to in.ClipsN clips from the candidates.
+// The result is sorted by timeline position.
+func (u Usecase) Select(
+	ctx context.Context,
+	in Input,
+	tr types.Transcript,
+	cands []types.Candidate,
+) ([]types.ClipSpec, error) {
+	return u.selectClips(ctx, in, checkpoints{dir: in.CheckpointDir}, tr, cands)
+}
+
+// Render runs stage 5 only for an externally provided selection (e.g. a
+// hand-edited selection.json). The selection and transcript are checkpointed
+// first, so an interrupted render can be resumed like a full run.
+func (u Usecase) Render(
+	ctx context.Context,
+	in Input,
+	tr types.Transcript,
+	clipSpecs []types.ClipSpec,
+) (types.Manifest, error) {
+	cp := checkpoints{dir: in.CheckpointDir}
+	clipSpecs = append([]types.ClipSpec(nil), clipSpecs...)
+	sortClipSpecs(clipSpecs)
+	if err := cp.save(checkpointSelection, clipSpecs); err != nil {
+		return types.Manifest{}, err
+	}
+	// An empty transcript is checkpointed too, so resuming does not
+	// transcribe what the render was asked to go without.
+	if err := cp.save(checkpointTranscript, tr); err != nil {
+		return types.Manifest{}, err
+	}
+	return u.render(ctx, in, cp, tr, clipSpecs)
+}
+
+func (u Usecase) transcribe(ctx context.Context, in Input, cp checkpoints) (types.Transcript, error) {
+	var tr types.Transcript
+	if loadCheckpoint(in, cp, checkpointTranscript, &tr) {
+		logf(in.Logf, "stage 1/5: extracting audio (skipped, checkpoint)")
+		logf(in.Logf, "stage 2/5: transcribing audio (skipped, checkpoint)")
+		return tr, nil
+	}
+
+	if in.TranscriptCache != "" && !in.RefreshTranscript {
+		tr, ok, err := loadTranscriptCache(in.TranscriptCache)
+		if err != nil {
+			logf(in.Logf, "cache: ignoring unreadable transcript cache: %v", err)
+		}
+		if ok {
+			logf(in.Logf, "stage 1/5: extracting audio (skipped, transcript cached)")
+			logf(in.Logf, "stage 2/5: transcribing audio (skipped, transcript cached)")
+			logf(
+				in.Logf,
+				"cache: transcript reused (%d segments, %d words) from %s",
+				len(tr.Segments),
+				countWords(tr),
+				in.TranscriptCache,
+			)
+			return tr, cp.save(checkpointTranscript, tr)
+		}
+	}
+
+	// An imported transcript does not need the audio.
+	src, imported := u.d.ASR.(ports.TranscriptSource)
+	wav := filepath.Join(in.CacheDir, "audio.wav")
+
+	var audio audioCheckpoint
+	if imported {
+		wav = ""
+		logf(in.Logf, "stage 1/5: extracting audio (skipped, transcript imported)")
+	} else if loadCheckpoint(in, cp, checkpointAudio, &audio) && fileExists(audio.WAV) {
+		wav = audio.WAV
+		logf(in.Logf, "stage 1/5: extracting audio (skipped, checkpoint)")
+	} else {
+		logf(in.Logf, "stage 1/5: extracting audio")
+		stageStart := time.Now()
+		channels := 1
+		if in.StereoAudio {
+			channels = 2
+		}
+		if err := u.d.Video.ExtractAudio16k(ctx, in.InputPath, wav, in.AudioTrack, channels); err != nil {
+			return types.Transcript{}, err
+		}
+		if err := cp.save(checkpointAudio, audioCheckpoint{WAV: wav}); err != nil {
+			return types.Transcript{}, err
+		}
+		logf(in.Logf, "stage 1/5 done in %s", shortDuration(time.Since(stageStart)))
+	}
+
+	if imported {
+		logf(in.Logf, "stage 2/5: importing transcript %s", src.Source())
+	} else {
+		logf(in.Logf, "stage 2/5: transcribing audio")
+	}
+	stageStart := time.Now()
+	tr, err := u.d.ASR.Transcribe(ctx, wav, in.CacheDir)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+	if err := cp.save(checkpointTranscript, tr); err != nil {
+		return types.Transcript{}, err
+	}
 	logf(
 		in.Logf,
 		"stage 2/5 done in %s (%d segments, %d words)",
@@ -63,97 +247,366 @@ func (u Usecase) Run(ctx context.Context, in Input) (Result, error) {
 		len(tr.Segments),
 		countWords(tr),
 	)
+	if tr.Language != "" {
+		if tr.Translated {
+			logf(in.Logf, "transcript language: %s, translated to English", tr.Language)
+		} else {
+			logf(in.Logf, "transcript language: %s", tr.Language)
+		}
+	}
+
+	if in.TranscriptCache != "" {
+		// A failed cache write only costs a re-transcription next time.
+		if err := storeTranscriptCache(in.TranscriptCache, tr); err != nil {
+			logf(in.Logf, "cache: transcript not saved: %v", err)
+		}
+	}
+	return tr, nil
+}
+
+func (u Usecase) candidates(in Input, cp checkpoints, tr types.Transcript) ([]types.Candidate, error) {
+	var cands []types.Candidate
+	if loadCheckpoint(in, cp, checkpointCandidates, &cands) {
+		logf(in.Logf, "stage 3/5: generating candidate windows (skipped, checkpoint)")
+		return cands, nil
+	}
 
 	logf(in.Logf, "stage 3/5: generating candidate windows")
-	stageStart = time.Now()
+	stageStart := time.Now()
 	// Candidate generation is intentionally broad; final selection constraints
 	// (quality, non-overlap, count) are enforced in the LLM refinement stage.
-	cands := highlights.BuildCandidates(tr)
+	cands = highlights.BuildCandidates(tr, clipDurations(in))
+	if err := cp.save(checkpointCandidates, cands); err != nil {
+		return nil, err
+	}
 	logf(in.Logf, "stage 3/5 done in %s (%d candidates)", shortDuration(time.Since(stageStart)), len(cands))
+	return cands, nil
+}
+
+// clipDurations returns the input's clip length bounds, defaulting when unset.
+func clipDurations(in Input) types.ClipDurations {
+	if in.Durations == (types.ClipDurations{}) {
+		return highlights.DefaultDurations()
+	}
+	return in.Durations
+}
+
+func (u Usecase) selectClips(
+	ctx context.Context,
+	in Input,
+	cp checkpoints,
+	tr types.Transcript,
+	cands []types.Candidate,
+) ([]types.ClipSpec, error) {
+	var clipSpecs []types.ClipSpec
+	if loadCheckpoint(in, cp, checkpointSelection, &clipSpecs) {
+		logf(in.Logf, "stage 4/5: refining clips with llm (skipped, checkpoint)")
+		return clipSpecs, nil
+	}
 
 	logf(in.Logf, "stage 4/5: refining clips with llm")
-	stageStart = time.Now()
-	clipSpecs, err := u.d.LLM.Refine(ctx, tr, cands, in.ClipsN)
+	stageStart := time.Now()
+	durs := clipDurations(in)
+	clipSpecs, err := u.d.LLM.Refine(ctx, tr, cands, in.ClipsN, durs)
 	if err != nil {
-		return Result{}, err
+		return nil, err
 	}
 	logf(in.Logf, "stage 4/5 done in %s (%d selected)", shortDuration(time.Since(stageStart)), len(clipSpecs))
 	if len(clipSpecs) == 0 {
-		minClip, maxClip := highlights.DurationBounds()
 		logf(
 			in.Logf,
 			"no highlights found (%s to %s, distinct non-overlapping windows); writing empty manifest",
-			formatTimestamp(minClip),
-			formatTimestamp(maxClip),
+			formatTimestamp(durs.Min),
+			formatTimestamp(durs.Max),
 		)
 	}
-	sort.Slice(clipSpecs, func(i, j int) bool {
-		if clipSpecs[i].Start == clipSpecs[j].Start {
-			return clipSpecs[i].End < clipSpecs[j].End
-		}
-		return clipSpecs[i].Start < clipSpecs[j].Start
-	})
+	sortClipSpecs(clipSpecs)
+	// The sorted selection is checkpointed so clip IDs stay stable on resume
+	// even if a later LLM call would have picked differently.
+	if err := cp.save(checkpointSelection, clipSpecs); err != nil {
+		return nil, err
+	}
+	return clipSpecs, nil
+}
 
-	if in.BurnSubtitles {
+func (u Usecase) render(
+	ctx context.Context,
+	in Input,
+	cp checkpoints,
+	tr types.Transcript,
+	clipSpecs []types.ClipSpec,
+) (types.Manifest, error) {
+	if in.BurnSubtitles || len(in.SubtitleFormats) > 0 {
 		logf(in.Logf, "stage 5/5: rendering clips and subtitles")
 	} else {
 		logf(in.Logf, "stage 5/5: rendering clips")
 	}
-	stageStart = time.Now()
-	m := types.Manifest{Input: in.InputMP4}
+	if in.FastCut != "" && !fastCuts(in, renderJob{burnSubtitles: in.BurnSubtitles, frame: in.Frame, tighten: in.Tighten}) {
+		logf(in.Logf, "fast cut: off, clips are filtered (subtitles, framing, tightening or audio) and must be encoded")
+	}
+	stageStart := time.Now()
+	jobs := make([]renderJob, len(clipSpecs))
 	for i, cs := range clipSpecs {
-		id := fmt.Sprintf("%03d", i+1)
-		clipPath := filepath.Join(in.OutDir, "clips", id+".mp4")
-		assPath := ""
-		subtitlesPath := ""
+		jobs[i] = renderJob{
+			id:              fmt.Sprintf("%03d", i+1),
+			cs:              cs,
+			burnSubtitles:   in.BurnSubtitles,
+			subtitleFormats: in.SubtitleFormats,
+			frame:           in.Frame,
+			tighten:         in.Tighten,
+			reuse:           true,
+		}
+	}
+	clips, err := u.renderJobs(ctx, in, cp, tr, jobs)
+	if err != nil {
+		return types.Manifest{}, err
+	}
+	m := types.Manifest{
+		Input:      in.InputPath,
+		Source:     in.Source,
+		Language:   tr.Language,
+		Translated: tr.Translated,
+		Clips:      clips,
+
+		WhisperModel: in.WhisperModel,
+	}
+	logf(in.Logf, "stage 5/5 done in %s", shortDuration(time.Since(stageStart)))
+
+	return m, nil
+}
+
+// renderClip renders one clip and its subtitle files, and checkpoints the
+// resulting manifest entry.
+func (u Usecase) renderClip(
+	ctx context.Context,
+	in Input,
+	cp checkpoints,
+	tr types.Transcript,
+	j renderJob,
+) (types.ManifestClip, error) {
+	if in.Audiogram != nil && !j.frame.Reframes() {
+		// Audiograms have no source geometry to keep.
+		j.frame = in.Frame
+	}
+	id, cs, frame := j.id, j.cs, j.frame
+	clipPath := filepath.Join(in.OutDir, filepath.FromSlash(clipFile(id, in.Format)))
+	// A tightened clip is the kept ranges joined; its subtitles are timed on
+	// that edited timeline.
+	var keep []types.Range
+	start := cs.Start
+	fast := fastCuts(in, j)
+	if fast && in.FastCut == types.FastCutCopy {
+		// A stream copy starts at a keyframe; subtitles follow the snapped start.
+		k, err := u.d.Video.KeyframeBefore(ctx, in.InputPath, cs.Start)
+		if err != nil {
+			return types.ManifestClip{}, err
+		}
+		start = k
+	}
+	subTr, subStart, subEnd := tr, start, cs.End
+	if j.tighten {
+		keep = tighten.KeepRanges(tr, cs.Start, cs.End, in.TightenPause)
+		subTr, subStart, subEnd = tighten.Retime(tr, keep), 0, tighten.Duration(keep)
+	}
+	// Subtitle files are rendered before the video so ffmpeg can burn the
+	// exact ASS file shipped with this clip.
+	files, err := writeSubtitleFiles(in.OutDir, subTr, subStart, subEnd, in.Captions, j)
+	if err != nil {
+		return types.ManifestClip{}, err
+	}
+	assPath := ""
+	subtitlesPath := ""
+	if j.burnSubtitles {
+		subtitlesPath = subtitleFile(id, subtitles.FormatASS)
+		assPath = filepath.Join(in.OutDir, filepath.FromSlash(subtitlesPath))
+	}
+
+	// render
+	opts := types.RenderOptions{
+		BurnASS:    assPath,
+		FontsDir:   in.FontsDir,
+		Frame:      frame,
+		Keep:       keep,
+		Audio:      in.Audio,
+		AudioTrack: in.AudioTrack,
+		Format:     in.Format,
+
+		MaxVideoKbps: in.MaxVideoKbps,
+	}
+	if fast {
+		opts.FastCut = in.FastCut
+	}
+	var rendered types.RenderResult
+	if in.Audiogram != nil {
+		rendered, err = u.d.Video.RenderAudiogram(ctx, in.InputPath, cs.Start, cs.End, clipPath, *in.Audiogram, opts)
+	} else {
+		rendered, err = u.d.Video.RenderClip(ctx, in.InputPath, start, cs.End, clipPath, opts)
+	}
+	if err != nil {
+		return types.ManifestClip{}, err
+	}
+	if in.MaxFileSizeMB > 0 {
+		if fi, err := os.Stat(clipPath); err == nil && fi.Size() > int64(in.MaxFileSizeMB)*1_000_000 {
+			logf(in.Logf, "clip %s is %.1f MB, over the %d MB limit", id, float64(fi.Size())/1e6, in.MaxFileSizeMB)
+		}
+	}
+	if rendered.LoudnessLUFS != 0 {
+		logf(in.Logf, "clip %s loudness: %.1f -> %.1f LUFS", id, rendered.SourceLoudnessLUFS, rendered.LoudnessLUFS)
+	}
+
+	// Manifest shape is kept stable for downstream tools even though candidate
+	// text/scores are not wired through from LLM output yet.
+	mc := types.ManifestClip{
+		ID:            id,
+		StartSec:      cs.Start.Seconds(),
+		EndSec:        cs.End.Seconds(),
+		InfoScore:     0,
+		HookScore:     0,
+		Text:          "",
+		File:          clipFile(id, in.Format),
+		Subtitles:     subtitlesPath,
+		BurnSubtitles: j.burnSubtitles,
+		SubtitleFiles: files,
+		Title:         cs.Title,
+		Caption:       cs.Caption,
+		Tags:          cs.Tags,
+		Keywords:      cs.Keywords,
+		Model:         cs.Model,
+		Aspect:        frame.Aspect,
+		Reframe:       frame.Mode,
+		Width:         frame.Width,
+		Height:        frame.Height,
+
+		SourceLoudnessLUFS: rendered.SourceLoudnessLUFS,
+		LoudnessLUFS:       rendered.LoudnessLUFS,
+		Audiogram:          in.Audiogram != nil,
+	}
+	// The manifest records the cut that happened: a smart cut the source
+	// does not allow is encoded instead.
+	switch {
+	case rendered.CutMode != "":
+		mc.Cut = &types.CutInfo{Mode: rendered.CutMode, StartSec: start.Seconds(), EndSec: cs.End.Seconds()}
+		if start != cs.Start {
+			logf(in.Logf, "clip %s snapped to keyframe: %.3fs -> %.3fs", id, cs.Start.Seconds(), start.Seconds())
+		}
+	case fast:
+		logf(in.Logf, "clip %s cannot be cut with --fast-cut %s; encoded", id, in.FastCut)
+	}
+	if j.tighten {
 		logf(
 			in.Logf,
-			"rendering clip %d/%d (%s) [%s -> %s]",
-			i+1,
-			len(clipSpecs),
-			id,
-			formatTimestamp(cs.Start),
-			formatTimestamp(cs.End),
+			"clip %s tightened: %d cuts, %s -> %s",
+			id, len(keep)-1, shortDuration(cs.End-cs.Start), shortDuration(tighten.Duration(keep)),
 		)
+		mc.Tighten = true
+		mc.Cuts = len(keep) - 1
+		mc.TightenedSec = tighten.Duration(keep).Seconds()
+	}
+	if err := u.saveClipCheckpoint(ctx, cp, clipPath, mc); err != nil {
+		return types.ManifestClip{}, err
+	}
+	return mc, nil
+}
+
+// fastCuts reports whether the job's clip can be cut without encoding:
+// nothing filters its video or audio.
+func fastCuts(in Input, j renderJob) bool {
+	return in.FastCut != "" && in.Audiogram == nil && !j.burnSubtitles && !j.frame.Reframes() && !j.tighten &&
+		in.Audio == (types.AudioOptions{})
+}
 
-		if in.BurnSubtitles {
-			// ASS is rendered as a side artifact before video rendering so ffmpeg can
-			// burn the exact subtitle file used for this clip.
-			assPath = filepath.Join(in.OutDir, "subtitles", id+".ass")
-			ass, err := subtitles.RenderTikTokASS(tr, cs.Start, cs.End)
-			if err != nil {
-				return Result{}, err
-			}
-			if err := writeFile(assPath, []byte(ass)); err != nil {
-				return Result{}, err
-			}
-			subtitlesPath = filepath.ToSlash(filepath.Join("subtitles", id+".ass"))
+// writeSubtitleFiles writes one subtitle file per requested format, plus the
+// ASS file when burning, laid out for the clip's output frame. start and end
+// select the clip's part of tr.
+func writeSubtitleFiles(
+	outDir string,
+	tr types.Transcript,
+	start, end time.Duration,
+	captions subtitles.Options,
+	j renderJob,
+) ([]types.SubtitleFile, error) {
+	formats := j.subtitleFormats
+	if j.burnSubtitles && !slices.Contains(formats, subtitles.FormatASS) {
+		formats = append([]string{subtitles.FormatASS}, formats...)
+	}
+	opts := captions
+	opts.Width, opts.Height = j.frame.Width, j.frame.Height
+	opts.Title = j.cs.Title
+	opts.Keywords = j.cs.Keywords
+	var files []types.SubtitleFile
+	for _, format := range formats {
+		text, err := subtitles.Render(format, tr, start, end, opts)
+		if err != nil {
+			return nil, err
 		}
+		rel := subtitleFile(j.id, format)
+		if err := writeFile(filepath.Join(outDir, filepath.FromSlash(rel)), []byte(text)); err != nil {
+			return nil, err
+		}
+		files = append(files, types.SubtitleFile{Format: format, File: rel})
+	}
+	return files, nil
+}
 
-		// render
-		if err := u.d.Video.RenderClip(ctx, in.InputMP4, cs.Start, cs.End, clipPath, assPath); err != nil {
-			return Result{}, err
+// clipFile is the clip's path relative to the run directory.
+func clipFile(id, format string) string {
+	if format == "" {
+		format = "mp4"
+	}
+	return "clips/" + id + "." + format
+}
+
+func subtitleFile(id, format string) string {
+	return "subtitles/" + id + "." + format
+}
+
+func (u Usecase) saveClipCheckpoint(ctx context.Context, cp checkpoints, clipPath string, mc types.ManifestClip) error {
+	if cp.dir == "" {
+		return nil
+	}
+	// A probe failure right after a successful render is not fatal; resume then
+	// only requires the file to probe, without a duration floor.
+	d, _ := u.d.Video.ProbeDuration(ctx, clipPath)
+	return cp.save("clip-"+mc.ID, clipCheckpoint{Clip: mc, DurationSec: d.Seconds()})
+}
+
+// clipIsComplete accepts a previously rendered clip only when it still exists
+// and ffprobe can read it back with (close to) the duration recorded at render
+// time, so partially written files from an interrupted run are re-rendered.
+func (u Usecase) clipIsComplete(ctx context.Context, clipPath string, done clipCheckpoint) bool {
+	if !fileExists(clipPath) {
+		return false
+	}
+	d, err := u.d.Video.ProbeDuration(ctx, clipPath)
+	if err != nil {
+		return false
+	}
+	const tolerance = 500 * time.Millisecond
+	return d+tolerance >= types.Seconds(done.DurationSec)
+}
+
+// sortClipSpecs orders clips along the timeline so clip IDs follow playback order.
+func sortClipSpecs(clipSpecs []types.ClipSpec) {
+	sort.Slice(clipSpecs, func(i, j int) bool {
+		if clipSpecs[i].Start == clipSpecs[j].Start {
+			return clipSpecs[i].End < clipSpecs[j].End
 		}
+		return clipSpecs[i].Start < clipSpecs[j].Start
+	})
+}
 
-		// Manifest shape is kept stable for downstream tools even though candidate
-		// text/scores are not wired through from LLM output yet.
-		m.Clips = append(m.Clips, types.ManifestClip{
-			ID:        id,
-			StartSec:  cs.Start.Seconds(),
-			EndSec:    cs.End.Seconds(),
-			InfoScore: 0,
-			HookScore: 0,
-			Text:      "",
-			File:      filepath.ToSlash(filepath.Join("clips", id+".mp4")),
-			Subtitles: subtitlesPath,
-			Title:     cs.Title,
-			Caption:   cs.Caption,
-			Tags:      cs.Tags,
-		})
+func loadCheckpoint(in Input, cp checkpoints, name string, v any) bool {
+	ok, err := cp.load(name, v)
+	if err != nil {
+		logf(in.Logf, "checkpoint %s unreadable, redoing stage: %v", name, err)
+		return false
 	}
-	logf(in.Logf, "stage 5/5 done in %s", shortDuration(time.Since(stageStart)))
+	return ok
+}
 
-	return Result{Manifest: m}, nil
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
 }
 
 func writeFile(path string, b []byte) error {
@@ -2,9 +2,15 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -54,7 +60,7 @@ func TestRun_BurnSubtitlesToggle(t *testing.T) {
 			})
 
 			res, err := uc.Run(context.Background(), Input{
-				InputMP4:      filepath.Join(tmp, "in.mp4"),
+				InputPath:     filepath.Join(tmp, "in.mp4"),
 				ClipsN:        1,
 				BurnSubtitles: tc.burnSubtitles,
 				CacheDir:      filepath.Join(tmp, "cache"),
@@ -106,25 +112,87 @@ func TestRun_BurnSubtitlesToggle(t *testing.T) {
 }
 
 type fakeVideoTool struct {
+	mu            sync.Mutex
 	renderBurnASS []string
 	renderStarts  []time.Duration
+	renderFrames  []types.Frame
+	renderKeeps   [][]types.Range
+	audiograms    []types.Audiogram
+	fastCuts      []string
+	extractCalls  int
+	// keyframe is where KeyframeBefore snaps to, when before the cut point.
+	keyframe time.Duration
+	// encodeSmartCuts makes smart cuts fall back to encoding, like ffmpeg
+	// does for sources without a usable keyframe.
+	encodeSmartCuts bool
+	// failRenderAt makes the n-th RenderClip call (1-based) fail.
+	failRenderAt int
+	// renderFn, when set, runs before a render is recorded; an error fails it.
+	renderFn func(ctx context.Context, start time.Duration) error
 }
 
-func (f *fakeVideoTool) ExtractAudioMono16k(_ context.Context, _, _ string) error {
+func (f *fakeVideoTool) ExtractAudio16k(_ context.Context, _, _ string, _, _ int) error {
+	f.extractCalls++
 	return nil
 }
 
 func (f *fakeVideoTool) RenderClip(
-	_ context.Context,
+	ctx context.Context,
 	_ string,
 	start time.Duration,
 	_ time.Duration,
-	_ string,
-	burnASS string,
-) error {
-	f.renderBurnASS = append(f.renderBurnASS, burnASS)
+	outPath string,
+	opts types.RenderOptions,
+) (types.RenderResult, error) {
+	if f.renderFn != nil {
+		if err := f.renderFn(ctx, start); err != nil {
+			return types.RenderResult{}, err
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failRenderAt > 0 && len(f.renderStarts)+1 == f.failRenderAt {
+		f.failRenderAt = 0
+		return types.RenderResult{}, errors.New("render failed")
+	}
+	f.renderBurnASS = append(f.renderBurnASS, opts.BurnASS)
+	f.renderFrames = append(f.renderFrames, opts.Frame)
+	f.renderKeeps = append(f.renderKeeps, opts.Keep)
+	f.fastCuts = append(f.fastCuts, opts.FastCut)
 	f.renderStarts = append(f.renderStarts, start)
-	return nil
+	// Leave a file behind like ffmpeg would, so resume can find it.
+	_ = os.WriteFile(outPath, []byte("clip"), 0o644)
+	var res types.RenderResult
+	if opts.Audio.LoudnessLUFS != 0 {
+		// Pretend a quiet source normalized right onto the target.
+		res = types.RenderResult{SourceLoudnessLUFS: -23.5, LoudnessLUFS: opts.Audio.LoudnessLUFS}
+	}
+	if opts.FastCut != types.FastCutSmart || !f.encodeSmartCuts {
+		res.CutMode = opts.FastCut
+	}
+	return res, nil
+}
+
+func (f *fakeVideoTool) RenderAudiogram(
+	ctx context.Context,
+	in string,
+	start, end time.Duration,
+	outPath string,
+	ag types.Audiogram,
+	opts types.RenderOptions,
+) (types.RenderResult, error) {
+	f.mu.Lock()
+	f.audiograms = append(f.audiograms, ag)
+	f.mu.Unlock()
+	return f.RenderClip(ctx, in, start, end, outPath, opts)
+}
+
+func (f *fakeVideoTool) Probe(_ context.Context, _ string) (types.MediaInfo, error) {
+	return types.MediaInfo{Streams: []types.MediaStream{{Type: "video"}, {Index: 1, Type: "audio"}}}, nil
+}
+
+func (f *fakeVideoTool) KeyframeBefore(_ context.Context, _ string, t time.Duration) (time.Duration, error) {
+	return min(f.keyframe, t), nil
 }
 
 func (f *fakeVideoTool) ProbeDuration(_ context.Context, _ string) (time.Duration, error) {
@@ -132,15 +200,20 @@ func (f *fakeVideoTool) ProbeDuration(_ context.Context, _ string) (time.Duratio
 }
 
 type fakeASR struct {
-	tr types.Transcript
+	tr    types.Transcript
+	calls *int
 }
 
 func (f fakeASR) Transcribe(_ context.Context, _, _ string) (types.Transcript, error) {
+	if f.calls != nil {
+		*f.calls++
+	}
 	return f.tr, nil
 }
 
 type fakeLLM struct {
 	clips []types.ClipSpec
+	calls *int
 }
 
 func (f fakeLLM) Refine(
@@ -148,7 +221,11 @@ func (f fakeLLM) Refine(
 	_ types.Transcript,
 	_ []types.Candidate,
 	_ int,
+	_ types.ClipDurations,
 ) ([]types.ClipSpec, error) {
+	if f.calls != nil {
+		*f.calls++
+	}
 	return f.clips, nil
 }
 
@@ -199,10 +276,10 @@ func TestRun_SortsClipsByTimeline(t *testing.T) {
 	})
 
 	res, err := uc.Run(context.Background(), Input{
-		InputMP4: filepath.Join(tmp, "in.mp4"),
-		ClipsN:   2,
-		CacheDir: filepath.Join(tmp, "cache"),
-		OutDir:   outDir,
+		InputPath: filepath.Join(tmp, "in.mp4"),
+		ClipsN:    2,
+		CacheDir:  filepath.Join(tmp, "cache"),
+		OutDir:    outDir,
 	})
 	if err != nil {
 		t.Fatalf("run: %v", err)
@@ -224,3 +301,710 @@ func TestRun_SortsClipsByTimeline(t *testing.T) {
 		t.Fatalf("expected render order to follow timeline, got %s then %s", video.renderStarts[0], video.renderStarts[1])
 	}
 }
+
+func TestRun_ReusesCachedTranscript(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	cachePath := filepath.Join(tmp, "cache", "transcript.json")
+
+	var calls int
+	uc := New(Deps{
+		Video: &fakeVideoTool{},
+		ASR:   fakeASR{tr: testTranscript(), calls: &calls},
+		LLM:   fakeLLM{},
+	})
+
+	runOnce := func(refresh bool) {
+		t.Helper()
+		_, err := uc.Run(context.Background(), Input{
+			InputPath:         filepath.Join(tmp, "in.mp4"),
+			ClipsN:            1,
+			CacheDir:          filepath.Join(tmp, "cache"),
+			OutDir:            filepath.Join(tmp, "out"),
+			TranscriptCache:   cachePath,
+			RefreshTranscript: refresh,
+		})
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+
+	runOnce(false)
+	if calls != 1 {
+		t.Fatalf("expected first run to transcribe, got %d calls", calls)
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("expected transcript cache to be written: %v", err)
+	}
+
+	runOnce(false)
+	if calls != 1 {
+		t.Fatalf("expected cached transcript to be reused, got %d calls", calls)
+	}
+
+	runOnce(true)
+	if calls != 2 {
+		t.Fatalf("expected refresh to re-transcribe, got %d calls", calls)
+	}
+}
+
+func TestRun_IgnoresCorruptTranscriptCache(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	cachePath := filepath.Join(tmp, "transcript.json")
+	if err := os.WriteFile(cachePath, []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("write cache fixture: %v", err)
+	}
+
+	var calls int
+	uc := New(Deps{
+		Video: &fakeVideoTool{},
+		ASR:   fakeASR{tr: testTranscript(), calls: &calls},
+		LLM:   fakeLLM{},
+	})
+	_, err := uc.Run(context.Background(), Input{
+		InputPath:       filepath.Join(tmp, "in.mp4"),
+		ClipsN:          1,
+		CacheDir:        tmp,
+		OutDir:          filepath.Join(tmp, "out"),
+		TranscriptCache: cachePath,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected corrupt cache to trigger transcription, got %d calls", calls)
+	}
+	tr, ok, err := loadTranscriptCache(cachePath)
+	if err != nil || !ok {
+		t.Fatalf("expected cache to be rewritten, ok=%t err=%v", ok, err)
+	}
+	if len(tr.Segments) != 1 {
+		t.Fatalf("unexpected cached transcript: %+v", tr)
+	}
+}
+
+func TestRun_ResumesFromCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips dir: %v", err)
+	}
+
+	var asrCalls, llmCalls int
+	video := &fakeVideoTool{failRenderAt: 2}
+	uc := New(Deps{
+		Video: video,
+		ASR:   fakeASR{tr: testTranscript(), calls: &asrCalls},
+		LLM: fakeLLM{calls: &llmCalls, clips: []types.ClipSpec{
+			{Start: 0, End: 20 * time.Second, Title: "a"},
+			{Start: 30 * time.Second, End: 50 * time.Second, Title: "b"},
+			{Start: 60 * time.Second, End: 80 * time.Second, Title: "c"},
+		}},
+	})
+	in := Input{
+		InputPath:     filepath.Join(tmp, "in.mp4"),
+		ClipsN:        3,
+		CacheDir:      filepath.Join(tmp, "cache"),
+		OutDir:        outDir,
+		CheckpointDir: filepath.Join(outDir, "checkpoints"),
+	}
+
+	if _, err := uc.Run(context.Background(), in); err == nil {
+		t.Fatalf("expected first run to fail on clip 2")
+	}
+	if len(video.renderStarts) != 1 {
+		t.Fatalf("expected 1 clip rendered before failure, got %d", len(video.renderStarts))
+	}
+
+	res, err := uc.Run(context.Background(), in)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if asrCalls != 1 || llmCalls != 1 || video.extractCalls != 1 {
+		t.Fatalf("expected completed stages to be skipped, got asr=%d llm=%d extract=%d", asrCalls, llmCalls, video.extractCalls)
+	}
+	if len(video.renderStarts) != 3 {
+		t.Fatalf("expected only the 2 missing clips to be rendered on resume, got %d renders total", len(video.renderStarts))
+	}
+	if video.renderStarts[1] != 30*time.Second || video.renderStarts[2] != 60*time.Second {
+		t.Fatalf("unexpected resume render order: %v", video.renderStarts)
+	}
+	if len(res.Manifest.Clips) != 3 || res.Manifest.Clips[0].Title != "a" {
+		t.Fatalf("expected full manifest after resume, got %+v", res.Manifest.Clips)
+	}
+}
+
+func TestRun_RerendersClipWithMissingOutput(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips dir: %v", err)
+	}
+	video := &fakeVideoTool{}
+	uc := New(Deps{
+		Video: video,
+		ASR:   fakeASR{tr: testTranscript()},
+		LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 20 * time.Second}}},
+	})
+	in := Input{
+		InputPath:     filepath.Join(tmp, "in.mp4"),
+		ClipsN:        1,
+		CacheDir:      filepath.Join(tmp, "cache"),
+		OutDir:        outDir,
+		CheckpointDir: filepath.Join(outDir, "checkpoints"),
+	}
+	if _, err := uc.Run(context.Background(), in); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if err := os.Remove(filepath.Join(outDir, "clips", "001.mp4")); err != nil {
+		t.Fatalf("remove clip: %v", err)
+	}
+	if _, err := uc.Run(context.Background(), in); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(video.renderStarts) != 2 {
+		t.Fatalf("expected missing clip to be re-rendered, got %d renders", len(video.renderStarts))
+	}
+}
+
+func TestRender_SortsExternalSelectionAndCheckpointsIt(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips dir: %v", err)
+	}
+	video := &fakeVideoTool{}
+	uc := New(Deps{Video: video})
+	in := Input{
+		InputPath:     filepath.Join(tmp, "in.mp4"),
+		OutDir:        outDir,
+		CheckpointDir: filepath.Join(outDir, "checkpoints"),
+	}
+
+	m, err := uc.Render(context.Background(), in, types.Transcript{}, []types.ClipSpec{
+		{Start: time.Minute, End: time.Minute + 20*time.Second, Title: "late"},
+		{Start: 0, End: 20 * time.Second, Title: "early"},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if len(m.Clips) != 2 || m.Clips[0].Title != "early" || m.Clips[0].ID != "001" {
+		t.Fatalf("expected timeline-sorted manifest, got %+v", m.Clips)
+	}
+
+	var saved []types.ClipSpec
+	ok, err := checkpoints{dir: in.CheckpointDir}.load(checkpointSelection, &saved)
+	if err != nil || !ok || len(saved) != 2 {
+		t.Fatalf("expected selection checkpoint, ok=%t err=%v saved=%v", ok, err, saved)
+	}
+	if !SelectionCheckpointed(in.CheckpointDir) {
+		t.Fatalf("expected the selection to be reported as checkpointed")
+	}
+
+	// Resuming runs neither whisper nor the LLM, even without a transcript.
+	var asrCalls, llmCalls int
+	uc = New(Deps{Video: video, ASR: fakeASR{calls: &asrCalls}, LLM: fakeLLM{calls: &llmCalls}})
+	if _, err := uc.Run(context.Background(), in); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if asrCalls != 0 || llmCalls != 0 {
+		t.Fatalf("expected checkpoints to be reused, got %d asr and %d llm calls", asrCalls, llmCalls)
+	}
+}
+
+func TestRun_ParallelRenderKeepsOrder(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips dir: %v", err)
+	}
+	// Earlier clips take longer, so renders finish in reverse order.
+	video := &fakeVideoTool{renderFn: func(_ context.Context, start time.Duration) error {
+		time.Sleep(time.Duration(4-start/(30*time.Second)) * 10 * time.Millisecond)
+		return nil
+	}}
+	var clips []types.ClipSpec
+	for i := range 4 {
+		st := time.Duration(i) * 30 * time.Second
+		clips = append(clips, types.ClipSpec{Start: st, End: st + 20*time.Second, Title: string(rune('a' + i))})
+	}
+	uc := New(Deps{Video: video, ASR: fakeASR{tr: testTranscript()}, LLM: fakeLLM{clips: clips}})
+
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+	res, err := uc.Run(context.Background(), Input{
+		InputPath: filepath.Join(tmp, "in.mp4"),
+		ClipsN:    4,
+		Jobs:      4,
+		CacheDir:  filepath.Join(tmp, "cache"),
+		OutDir:    outDir,
+		Logf: func(format string, args ...any) {
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, fmt.Sprintf(format, args...))
+		},
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	for i, c := range res.Manifest.Clips {
+		if c.ID != fmt.Sprintf("%03d", i+1) || c.Title != clips[i].Title {
+			t.Fatalf("manifest out of order: %+v", res.Manifest.Clips)
+		}
+	}
+
+	var clipLines []string
+	for _, l := range lines {
+		if head, _, ok := strings.Cut(l, " ["); ok && strings.HasPrefix(l, "rendering clip ") {
+			clipLines = append(clipLines, head)
+		}
+		if head, _, ok := strings.Cut(l, " in "); ok && strings.HasPrefix(l, "clip ") {
+			clipLines = append(clipLines, head)
+		}
+	}
+	want := []string{
+		"rendering clip 1/4 (001)", "clip 001 rendered",
+		"rendering clip 2/4 (002)", "clip 002 rendered",
+		"rendering clip 3/4 (003)", "clip 003 rendered",
+		"rendering clip 4/4 (004)", "clip 004 rendered",
+	}
+	if strings.Join(clipLines, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected per-clip log lines in clip order, got %q", clipLines)
+	}
+}
+
+func TestRun_ParallelRenderFailureCancelsSiblings(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips dir: %v", err)
+	}
+	var started sync.WaitGroup
+	started.Add(2)
+	video := &fakeVideoTool{renderFn: func(ctx context.Context, start time.Duration) error {
+		if start >= 60*time.Second {
+			t.Errorf("clip at %s started after a failure", start)
+			return nil
+		}
+		started.Done()
+		started.Wait()
+		if start == 30*time.Second {
+			return errors.New("encoder crashed")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	uc := New(Deps{Video: video, ASR: fakeASR{tr: testTranscript()}, LLM: fakeLLM{clips: []types.ClipSpec{
+		{Start: 0, End: 20 * time.Second},
+		{Start: 30 * time.Second, End: 50 * time.Second},
+		{Start: 60 * time.Second, End: 80 * time.Second},
+	}}})
+
+	_, err := uc.Run(context.Background(), Input{
+		InputPath: filepath.Join(tmp, "in.mp4"),
+		ClipsN:    3,
+		Jobs:      2,
+		CacheDir:  filepath.Join(tmp, "cache"),
+		OutDir:    outDir,
+	})
+	if err == nil || !strings.Contains(err.Error(), "clip 002: encoder crashed") {
+		t.Fatalf("expected the failing clip's error, got %v", err)
+	}
+}
+
+func TestRun_WritesSubtitleSidecars(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		burn      bool
+		formats   []string
+		wantFiles []string
+	}{
+		{name: "soft only", formats: []string{"srt", "vtt"}, wantFiles: []string{"subtitles/001.srt", "subtitles/001.vtt"}},
+		{name: "burn adds ass", burn: true, formats: []string{"vtt"}, wantFiles: []string{"subtitles/001.ass", "subtitles/001.vtt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmp := t.TempDir()
+			outDir := filepath.Join(tmp, "out")
+			for _, d := range []string{"clips", "subtitles"} {
+				if err := os.MkdirAll(filepath.Join(outDir, d), 0o755); err != nil {
+					t.Fatalf("mkdir %s: %v", d, err)
+				}
+			}
+			video := &fakeVideoTool{}
+			uc := New(Deps{
+				Video: video,
+				ASR:   fakeASR{tr: testTranscript()},
+				LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 5 * time.Second}}},
+			})
+			res, err := uc.Run(context.Background(), Input{
+				InputPath:       filepath.Join(tmp, "in.mp4"),
+				ClipsN:          1,
+				BurnSubtitles:   tt.burn,
+				SubtitleFormats: tt.formats,
+				CacheDir:        filepath.Join(tmp, "cache"),
+				OutDir:          outDir,
+			})
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+
+			var got []string
+			for _, f := range res.Manifest.Clips[0].SubtitleFiles {
+				got = append(got, f.File)
+				b, err := os.ReadFile(filepath.Join(outDir, f.File))
+				if err != nil || !strings.Contains(string(b), "world") {
+					t.Fatalf("expected %s with the clip text, err=%v:\n%s", f.File, err, b)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Fatalf("subtitle files %v, want %v", got, tt.wantFiles)
+			}
+			if burned := video.renderBurnASS[0] != ""; burned != tt.burn {
+				t.Fatalf("burn-in %t, want %t", burned, tt.burn)
+			}
+		})
+	}
+}
+
+func TestRun_TightenCutsFillersAndRetimesSubtitles(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	for _, d := range []string{"clips", "subtitles"} {
+		if err := os.MkdirAll(filepath.Join(outDir, d), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", d, err)
+		}
+	}
+	tr := types.Transcript{Segments: []types.Segment{{Start: 0, End: 6, Text: "hello um world", Words: []types.Word{
+		{Start: 0.0, End: 0.5, Word: "hello"},
+		{Start: 0.6, End: 1.0, Word: "um"},
+		{Start: 3.0, End: 3.5, Word: "world"},
+	}}}}
+	video := &fakeVideoTool{}
+	uc := New(Deps{
+		Video: video,
+		ASR:   fakeASR{tr: tr},
+		LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 4 * time.Second}}},
+	})
+	res, err := uc.Run(context.Background(), Input{
+		InputPath:       filepath.Join(tmp, "in.mp4"),
+		ClipsN:          1,
+		SubtitleFormats: []string{"srt"},
+		Tighten:         true,
+		CacheDir:        filepath.Join(tmp, "cache"),
+		OutDir:          outDir,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	want := []types.Range{{Start: 0, End: 580 * time.Millisecond}, {Start: 2920 * time.Millisecond, End: 4 * time.Second}}
+	if keep := video.renderKeeps[0]; len(keep) != 2 || keep[0] != want[0] || keep[1] != want[1] {
+		t.Fatalf("keep ranges %v, want %v", keep, want)
+	}
+	mc := res.Manifest.Clips[0]
+	if !mc.Tighten || mc.Cuts != 1 || math.Abs(mc.TightenedSec-1.66) > 0.001 || mc.StartSec != 0 || mc.EndSec != 4 {
+		t.Fatalf("unexpected manifest clip: %+v", mc)
+	}
+	b, err := os.ReadFile(filepath.Join(outDir, "subtitles", "001.srt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1\n00:00:00,000 --> 00:00:01,160\nhello world\n"; !strings.HasPrefix(string(b), want) {
+		t.Fatalf("expected retimed cue without the filler, got:\n%s", b)
+	}
+}
+
+func TestRun_RecordsNormalizedLoudness(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips: %v", err)
+	}
+	uc := New(Deps{
+		Video: &fakeVideoTool{},
+		ASR:   fakeASR{tr: testTranscript()},
+		LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 5 * time.Second}}},
+	})
+	res, err := uc.Run(context.Background(), Input{
+		InputPath: filepath.Join(tmp, "in.mp4"),
+		ClipsN:    1,
+		Audio:     types.AudioOptions{LoudnessLUFS: -14, Fade: 50 * time.Millisecond},
+		CacheDir:  filepath.Join(tmp, "cache"),
+		OutDir:    outDir,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if mc := res.Manifest.Clips[0]; mc.SourceLoudnessLUFS != -23.5 || mc.LoudnessLUFS != -14 {
+		t.Fatalf("expected measured loudness in the manifest, got %+v", mc)
+	}
+}
+
+func TestRun_RendersAudiogramsForAudioInputs(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	for _, d := range []string{"clips", "subtitles"} {
+		if err := os.MkdirAll(filepath.Join(outDir, d), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", d, err)
+		}
+	}
+	video := &fakeVideoTool{}
+	uc := New(Deps{
+		Video: video,
+		ASR:   fakeASR{tr: testTranscript()},
+		LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 5 * time.Second}}},
+	})
+	ag := types.Audiogram{Background: "#101820", Title: "Episode 12"}
+	frame := types.Frame{Aspect: "1:1", Width: 1080, Height: 1080}
+	res, err := uc.Run(context.Background(), Input{
+		InputPath:     filepath.Join(tmp, "in.mp3"),
+		ClipsN:        1,
+		BurnSubtitles: true,
+		Frame:         frame,
+		Audiogram:     &ag,
+		CacheDir:      filepath.Join(tmp, "cache"),
+		OutDir:        outDir,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(video.audiograms) != 1 || video.audiograms[0] != ag {
+		t.Fatalf("expected one audiogram render with %+v, got %+v", ag, video.audiograms)
+	}
+	if video.renderFrames[0] != frame || video.renderBurnASS[0] == "" {
+		t.Fatalf("expected the frame and burned captions, got %+v %q", video.renderFrames[0], video.renderBurnASS[0])
+	}
+	if mc := res.Manifest.Clips[0]; !mc.Audiogram || mc.Width != 1080 {
+		t.Fatalf("unexpected manifest clip: %+v", mc)
+	}
+}
+
+func TestRun_WritesClipsInOutputFormat(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips: %v", err)
+	}
+	uc := New(Deps{
+		Video: &fakeVideoTool{},
+		ASR:   fakeASR{tr: testTranscript()},
+		LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 5 * time.Second}}},
+	})
+	res, err := uc.Run(context.Background(), Input{
+		InputPath: filepath.Join(tmp, "in.mkv"),
+		ClipsN:    1,
+		Format:    "webm",
+		CacheDir:  filepath.Join(tmp, "cache"),
+		OutDir:    outDir,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if f := res.Manifest.Clips[0].File; f != "clips/001.webm" {
+		t.Fatalf("expected a webm clip, got %q", f)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "clips", "001.webm")); err != nil {
+		t.Fatalf("expected the rendered file: %v", err)
+	}
+}
+
+func TestRun_FastCutSnapsToKeyframe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		mode        string
+		burn        bool
+		encodeSmart bool
+		wantStart   time.Duration
+		wantCut     *types.CutInfo
+		wantSRT     string
+	}{
+		{
+			name:      "copy snaps to the keyframe",
+			mode:      types.FastCutCopy,
+			wantStart: 500 * time.Millisecond,
+			wantCut:   &types.CutInfo{Mode: types.FastCutCopy, StartSec: 0.5, EndSec: 5},
+			wantSRT:   "--> 00:00:00,900",
+		},
+		{
+			name:      "burned subtitles are encoded",
+			mode:      types.FastCutCopy,
+			burn:      true,
+			wantStart: time.Second,
+		},
+		{
+			name:      "smart cut keeps the start",
+			mode:      types.FastCutSmart,
+			wantStart: time.Second,
+			wantCut:   &types.CutInfo{Mode: types.FastCutSmart, StartSec: 1, EndSec: 5},
+		},
+		{
+			name:        "smart cut falling back to encoding records no cut",
+			mode:        types.FastCutSmart,
+			encodeSmart: true,
+			wantStart:   time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmp := t.TempDir()
+			outDir := filepath.Join(tmp, "out")
+			for _, dir := range []string{"clips", "subtitles"} {
+				if err := os.MkdirAll(filepath.Join(outDir, dir), 0o755); err != nil {
+					t.Fatalf("mkdir %s: %v", dir, err)
+				}
+			}
+			video := &fakeVideoTool{keyframe: 500 * time.Millisecond, encodeSmartCuts: tt.encodeSmart}
+			uc := New(Deps{
+				Video: video,
+				ASR:   fakeASR{tr: testTranscript()},
+				LLM:   fakeLLM{clips: []types.ClipSpec{{Start: time.Second, End: 5 * time.Second}}},
+			})
+			res, err := uc.Run(context.Background(), Input{
+				InputPath:       filepath.Join(tmp, "in.mp4"),
+				ClipsN:          1,
+				BurnSubtitles:   tt.burn,
+				SubtitleFormats: []string{"srt"},
+				FastCut:         tt.mode,
+				CacheDir:        filepath.Join(tmp, "cache"),
+				OutDir:          outDir,
+			})
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if video.renderStarts[0] != tt.wantStart {
+				t.Fatalf("expected the clip to start at %s, got %s", tt.wantStart, video.renderStarts[0])
+			}
+			mc := res.Manifest.Clips[0]
+			if !reflect.DeepEqual(mc.Cut, tt.wantCut) {
+				t.Fatalf("expected cut %+v, got %+v", tt.wantCut, mc.Cut)
+			}
+			if mc.StartSec != 1 {
+				t.Fatalf("expected the selected start in the manifest, got %v", mc.StartSec)
+			}
+			if tt.burn && video.fastCuts[0] != "" {
+				t.Fatalf("expected an encoded clip, got fast cut %q", video.fastCuts[0])
+			}
+			if tt.wantSRT == "" {
+				return
+			}
+			b, err := os.ReadFile(filepath.Join(outDir, "subtitles", "001.srt"))
+			if err != nil {
+				t.Fatalf("read srt: %v", err)
+			}
+			if !strings.Contains(string(b), tt.wantSRT) {
+				t.Fatalf("expected subtitles timed from the snapped start (%q), got:\n%s", tt.wantSRT, b)
+			}
+		})
+	}
+}
+
+func TestRun_RecordsTranscriptLanguage(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips: %v", err)
+	}
+	tr := testTranscript()
+	tr.Language, tr.Translated = "es", true
+	var logs []string
+	uc := New(Deps{
+		Video: &fakeVideoTool{},
+		ASR:   fakeASR{tr: tr},
+		LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 5 * time.Second}}},
+	})
+	res, err := uc.Run(context.Background(), Input{
+		InputPath: filepath.Join(tmp, "in.mp4"),
+		ClipsN:    1,
+		CacheDir:  filepath.Join(tmp, "cache"),
+		OutDir:    outDir,
+		Logf:      func(format string, args ...any) { logs = append(logs, fmt.Sprintf(format, args...)) },
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if res.Manifest.Language != "es" || !res.Manifest.Translated {
+		t.Fatalf("expected language es, translated, got %q, %t", res.Manifest.Language, res.Manifest.Translated)
+	}
+	if !slices.Contains(logs, "transcript language: es, translated to English") {
+		t.Fatalf("expected the language logged, got %q", logs)
+	}
+}
+
+// fakeTranscriptSource is an imported transcript.
+type fakeTranscriptSource struct {
+	fakeASR
+	wav *string
+}
+
+func (f fakeTranscriptSource) Transcribe(ctx context.Context, wav, cacheDir string) (types.Transcript, error) {
+	*f.wav = wav
+	return f.fakeASR.Transcribe(ctx, wav, cacheDir)
+}
+
+func (f fakeTranscriptSource) Source() string { return "captions.srt" }
+
+func TestRun_ImportedTranscriptSkipsAudio(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outDir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, "clips"), 0o755); err != nil {
+		t.Fatalf("mkdir clips: %v", err)
+	}
+	video := &fakeVideoTool{}
+	wav := "unset"
+	uc := New(Deps{
+		Video: video,
+		ASR:   fakeTranscriptSource{fakeASR: fakeASR{tr: testTranscript()}, wav: &wav},
+		LLM:   fakeLLM{clips: []types.ClipSpec{{Start: 0, End: 5 * time.Second}}},
+	})
+	res, err := uc.Run(context.Background(), Input{
+		InputPath: filepath.Join(tmp, "in.mp4"),
+		ClipsN:    1,
+		CacheDir:  filepath.Join(tmp, "cache"),
+		OutDir:    outDir,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if video.extractCalls != 0 || wav != "" {
+		t.Fatalf("expected no audio extraction, got %d calls and wav %q", video.extractCalls, wav)
+	}
+	if len(res.Manifest.Clips) != 1 {
+		t.Fatalf("expected 1 clip, got %d", len(res.Manifest.Clips))
+	}
+}
@@ -6,10 +6,20 @@ BIN_DIR="$CACHE_DIR/bin"
 MODEL_DIR="$CACHE_DIR/models"
 ROOT_DIR="$(pwd -P)"
 WHISPER_REF="${WHISPER_REF:-764482c3175d9c3bc6089c1ec84df7d1b9537d83}"
-MODEL_URL="https://huggingface.co/ggerganov/whisper.cpp/resolve/main/ggml-base.bin"
-MODEL_SHA256="${MODEL_SHA256:-60ed5bc3dd14eea856493d334349b405782ddcaf0028d4b5df4088345fba2efe}"
+# MODEL_NAME is a name from `hlcut models list`. Its checksum comes from the
+# Go model registry (the same pins `hlcut models verify` uses); MODEL_SHA256
+# overrides it, e.g. for a model the registry does not pin.
+MODEL_NAME="${MODEL_NAME:-base}"
+MODEL_REGISTRY="$ROOT_DIR/internal/ports/adapters/whispercpp/models.go"
+MODEL_URL="https://huggingface.co/ggerganov/whisper.cpp/resolve/main/ggml-$MODEL_NAME.bin"
+if ! grep -q "{Name: \"$MODEL_NAME\"[,}]" "$MODEL_REGISTRY"; then
+  echo "Unknown whisper model $MODEL_NAME (see $MODEL_REGISTRY)" >&2
+  exit 1
+fi
+REGISTRY_SHA256="$(sed -n "s/.*{Name: \"$MODEL_NAME\", SHA256: \"\([0-9a-f]\{64\}\)\"}.*/\1/p" "$MODEL_REGISTRY")"
+MODEL_SHA256="${MODEL_SHA256:-$REGISTRY_SHA256}"
 SETUP_STATE_FILE="$CACHE_DIR/setup.fingerprint"
-MODEL_PATH="$MODEL_DIR/ggml-base.bin"
+MODEL_PATH="$MODEL_DIR/ggml-$MODEL_NAME.bin"
 
 mkdir -p "$BIN_DIR" "$MODEL_DIR"
 
@@ -48,6 +58,10 @@ verify_sha256() {
   local expected="$2"
   local actual
   actual="$(sha256_file "$file")"
+  if [ -z "$expected" ]; then
+    echo "[setup] no pinned SHA256 for $file; got $actual (set MODEL_SHA256 to verify)" >&2
+    return 0
+  fi
   if [ "$actual" != "$expected" ]; then
     echo "SHA256 mismatch for $file" >&2
     echo "  expected: $expected" >&2
@@ -152,7 +166,7 @@ fi
 popd >/dev/null
 
 if [ ! -f "$MODEL_PATH" ]; then
-  echo "[setup] downloading whisper base model..."
+  echo "[setup] downloading whisper $MODEL_NAME model..."
   TMP_MODEL_PATH="${MODEL_PATH}.tmp"
   curl -L --fail \
     -o "$TMP_MODEL_PATH" \